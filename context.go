@@ -16,7 +16,11 @@
 package exql
 
 import (
+	"context"
+	"sync"
+
 	"github.com/vedadiyan/exql/lang"
+	"github.com/vedadiyan/exql/lib"
 	"github.com/vedadiyan/exql/lib/crypt"
 	"github.com/vedadiyan/exql/lib/http"
 	"github.com/vedadiyan/exql/lib/ip"
@@ -29,67 +33,271 @@ import (
 	"github.com/vedadiyan/exql/lib/util"
 )
 
+// DefaultContext is a Context backed by maps. A child context, created with
+// NewChildContext, falls back to its parent for the names it does not hold
+// itself, and writes to a child never reach its parent.
+//
+// A DefaultContext may be read by any number of concurrent evaluations, but
+// SetVariable and SetFunction must not be called while it is in use. Give
+// each evaluation its own child context instead, or use a SyncContext.
 type DefaultContext struct {
-	values map[string]lang.Value
-	funcs  map[string]lang.Function
+	parent         lang.Context
+	values         map[string]lang.Value
+	funcs          map[string]lang.Function
+	contextFuncs   map[string]lang.ContextFunction
+	resolver       Resolver
+	operandResults bool
+}
+
+// SyncContext is a DefaultContext that is safe for concurrent use, so
+// variables and functions may be set while other goroutines evaluate
+// expressions against it. Each lookup takes a read lock.
+type SyncContext struct {
+	mu  sync.RWMutex
+	ctx *DefaultContext
 }
 
 type DefaultContextOption func(*DefaultContext)
 
+// builtIns is the context holding the built-in library, built on first use
+// and shared by every context that uses the library.
+var builtIns = sync.OnceValue(func() *DefaultContext {
+	return &DefaultContext{values: Exports()}
+})
+
+// WithFunctions adds funcs to the context. The map is copied, so later
+// changes to it are not seen.
 func WithFunctions(funcs map[string]lang.Function) DefaultContextOption {
 	return func(dc *DefaultContext) {
-		dc.funcs = funcs
+		for name, function := range funcs {
+			dc.SetFunction(name, function)
+		}
+	}
+}
+
+// WithContextFunctions adds functions that receive the context.Context of
+// the evaluation. The map is copied, so later changes to it are not seen.
+func WithContextFunctions(funcs map[string]lang.ContextFunction) DefaultContextOption {
+	return func(dc *DefaultContext) {
+		for name, function := range funcs {
+			dc.SetContextFunction(name, function)
+		}
 	}
 }
 
+// WithBuiltInLibrary makes the built-in namespaces available. A context
+// without a parent uses the shared BuiltIns context as its parent, so this
+// costs nothing per context; any other context gets the namespaces copied
+// in.
 func WithBuiltInLibrary() DefaultContextOption {
 	return func(dc *DefaultContext) {
-		dc.values = Exports()
+		if dc.parent == nil {
+			dc.parent = builtIns()
+			return
+		}
+		for name, value := range builtIns().values {
+			dc.SetVariable(name, value)
+		}
 	}
 }
 
-func NewDefaultContext(opts ...DefaultContextOption) *DefaultContext {
-	out := new(DefaultContext)
+// WithValues binds Go values as variables, converted with ValueOf. Structs
+// are not copied, so their fields are read when an expression accesses them.
+func WithValues(values map[string]any) DefaultContextOption {
+	return func(dc *DefaultContext) {
+		for name, value := range values {
+			dc.SetVariable(name, ValueOf(value))
+		}
+	}
+}
 
-	for _, opt := range opts {
-		opt(out)
+// WithResolver loads the variables the context and its parents do not hold
+// with resolver, when an expression first reads them. Loaded paths are
+// remembered until the evaluation ends, so every evaluation loads fresh
+// data and nothing is kept once it returns.
+func WithResolver(resolver Resolver) DefaultContextOption {
+	return func(dc *DefaultContext) {
+		dc.resolver = resolver
 	}
+}
 
-	if out.values == nil {
-		out.values = make(map[string]lang.Value)
+// WithOperandResults makes `and` and `or` return the operand that decided
+// the result instead of a boolean, e.g. `name or 'anonymous'`.
+func WithOperandResults() DefaultContextOption {
+	return func(dc *DefaultContext) {
+		dc.operandResults = true
 	}
+}
+
+func NewDefaultContext(opts ...DefaultContextOption) *DefaultContext {
+	return NewChildContext(nil, opts...)
+}
+
+// NewChildContext returns an empty context that resolves the variables and
+// functions it does not hold from parent, which may be nil. Variables and
+// functions set on the child shadow those of the parent without changing it,
+// so a base context can be shared by many concurrent evaluations as long as
+// nothing writes to the base itself. The maps of the child are only
+// allocated when something is set on it.
+func NewChildContext(parent lang.Context, opts ...DefaultContextOption) *DefaultContext {
+	out := &DefaultContext{parent: parent}
 
-	if out.funcs == nil {
-		out.funcs = make(map[string]lang.Function)
+	for _, opt := range opts {
+		opt(out)
 	}
 	return out
 }
 
+// BuiltIns returns the shared, read-only context holding the built-in
+// namespaces, for use as the parent of per-evaluation contexts:
+//
+//	ctx := exql.NewChildContext(exql.BuiltIns())
+//	ctx.SetVariable("user", user)
+//
+// The namespaces are shared too and must not be modified.
+func BuiltIns() lang.Context {
+	return builtIns()
+}
+
+// Parent returns the context c falls back to, or nil.
+func (c *DefaultContext) Parent() lang.Context {
+	return c.parent
+}
+
 func (c *DefaultContext) SetVariable(name string, value lang.Value) {
+	if c.values == nil {
+		c.values = make(map[string]lang.Value)
+	}
 	c.values[name] = value
 }
 func (c *DefaultContext) SetFunction(name string, function lang.Function) {
+	if c.funcs == nil {
+		c.funcs = make(map[string]lang.Function)
+	}
 	c.funcs[name] = function
+	delete(c.contextFuncs, name)
+}
+
+// SetContextFunction sets a function that receives the context.Context of
+// the evaluation calling it, or context.Background() outside EvalContext.
+func (c *DefaultContext) SetContextFunction(name string, function lang.ContextFunction) {
+	c.SetFunction(name, func(args []lang.Value) (lang.Value, error) {
+		return function(context.Background(), args)
+	})
+	if c.contextFuncs == nil {
+		c.contextFuncs = make(map[string]lang.ContextFunction)
+	}
+	c.contextFuncs[name] = function
 }
 
 func (c *DefaultContext) GetVariable(name string) lang.Value {
-	return c.values[name]
+	if value, ok := c.values[name]; ok {
+		return value
+	}
+	var value lang.Value
+	if c.parent != nil {
+		value = c.parent.GetVariable(name)
+	}
+	if value == nil && c.resolver != nil {
+		// Every read gets its own memo. lang.EvaluateContext keeps the
+		// first one for the rest of the evaluation
+		return &lazy{resolution: &resolution{resolver: c.resolver}, path: []string{name}}
+	}
+	return value
 }
 func (c *DefaultContext) GetFunction(name string) lang.Function {
-	return c.funcs[name]
+	if function, ok := c.funcs[name]; ok || c.parent == nil {
+		return function
+	}
+	return c.parent.GetFunction(name)
+}
+
+func (c *DefaultContext) GetContextFunction(name string) lang.ContextFunction {
+	if _, ok := c.funcs[name]; ok {
+		return c.contextFuncs[name]
+	}
+	if parent, ok := c.parent.(lang.FunctionContext); ok {
+		return parent.GetContextFunction(name)
+	}
+	return nil
+}
+
+// OperandResults reports whether c or any of its parents was created with
+// WithOperandResults.
+func (c *DefaultContext) OperandResults() bool {
+	if c.operandResults {
+		return true
+	}
+	parent, ok := c.parent.(lang.OperandResultContext)
+	return ok && parent.OperandResults()
+}
+
+// NewSyncContext returns a SyncContext configured with opts.
+func NewSyncContext(opts ...DefaultContextOption) *SyncContext {
+	return &SyncContext{ctx: NewDefaultContext(opts...)}
+}
+
+func (c *SyncContext) SetVariable(name string, value lang.Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx.SetVariable(name, value)
+}
+func (c *SyncContext) SetFunction(name string, function lang.Function) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx.SetFunction(name, function)
+}
+
+func (c *SyncContext) GetVariable(name string) lang.Value {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ctx.GetVariable(name)
+}
+func (c *SyncContext) GetFunction(name string) lang.Function {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ctx.GetFunction(name)
+}
+
+func (c *SyncContext) SetContextFunction(name string, function lang.ContextFunction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx.SetContextFunction(name, function)
+}
+
+func (c *SyncContext) GetContextFunction(name string) lang.ContextFunction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ctx.GetContextFunction(name)
+}
+
+func (c *SyncContext) OperandResults() bool {
+	return c.ctx.OperandResults()
 }
 
 func Exports() map[string]lang.Value {
-	return map[string]lang.Value{
-		"crypt":  NewDefaultContext(WithFunctions(crypt.Export())),
-		"http":   NewDefaultContext(WithFunctions(http.Export())),
-		"ip":     NewDefaultContext(WithFunctions(ip.Export())),
-		"json":   NewDefaultContext(WithFunctions(json.Export())),
-		"list":   NewDefaultContext(WithFunctions(list.Export())),
-		"map":    NewDefaultContext(WithFunctions(maps.Export())),
-		"string": NewDefaultContext(WithFunctions(str.Export())),
-		"time":   NewDefaultContext(WithFunctions(time.Export())),
-		"url":    NewDefaultContext(WithFunctions(url.Export())),
-		"util":   NewDefaultContext(WithFunctions(util.Export())),
+	out := make(map[string]lang.Value)
+	for name, descriptors := range descriptors() {
+		out[name] = NewDefaultContext(
+			WithFunctions(lib.Export(descriptors)),
+			WithContextFunctions(lib.ExportContext(descriptors)),
+		)
+	}
+	return out
+}
+
+// descriptors returns the descriptors of every built-in namespace.
+func descriptors() map[string][]*lib.Descriptor {
+	return map[string][]*lib.Descriptor{
+		"crypt":  crypt.Descriptors(),
+		"http":   http.Descriptors(),
+		"ip":     ip.Descriptors(),
+		"json":   json.Descriptors(),
+		"list":   list.Descriptors(),
+		"map":    maps.Descriptors(),
+		"string": str.Descriptors(),
+		"time":   time.Descriptors(),
+		"url":    url.Descriptors(),
+		"util":   util.Descriptors(),
 	}
 }
@@ -16,9 +16,14 @@
 package exql
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/vedadiyan/exql/lang"
+	"github.com/vedadiyan/exql/lib"
+	"github.com/vedadiyan/exql/lib/math"
+	types "github.com/vedadiyan/exql/lib/type"
 )
 
 func TestContextOptions(t *testing.T) {
@@ -97,4 +102,219 @@ func TestContextOptions(t *testing.T) {
 			t.Error("built-in library should not be nil")
 		}
 	})
+
+	t.Run("context with operand results", func(t *testing.T) {
+		ctx := NewDefaultContext(WithOperandResults())
+		ctx.SetVariable("name", lang.StringValue(""))
+
+		result, err := Eval("name or 'anonymous'", ctx)
+		if err != nil {
+			t.Fatalf("error with operand results: %v", err)
+		}
+
+		if !valueEqual(result, lang.StringValue("anonymous")) {
+			t.Errorf("expected 'anonymous', got %v", result)
+		}
+	})
+
+	t.Run("short circuit guards field access", func(t *testing.T) {
+		ctx := NewDefaultContext()
+		calls := 0
+		ctx.SetFunction("touch", func(args []lang.Value) (lang.Value, error) {
+			calls++
+			return lang.BoolValue(true), nil
+		})
+
+		result, err := Eval("false and touch()", ctx)
+		if err != nil {
+			t.Fatalf("error with short circuit: %v", err)
+		}
+
+		if !valueEqual(result, lang.BoolValue(false)) {
+			t.Errorf("expected false, got %v", result)
+		}
+		if calls != 0 {
+			t.Errorf("expected right operand to be skipped, got %d calls", calls)
+		}
+	})
+}
+
+func TestChildContext(t *testing.T) {
+	constant := func(value lang.Value) lang.Function {
+		return func(args []lang.Value) (lang.Value, error) {
+			return value, nil
+		}
+	}
+
+	base := NewDefaultContext(WithBuiltInLibrary(), WithOperandResults())
+	base.SetVariable("tenant", lang.StringValue("acme"))
+	base.SetVariable("limit", lang.NumberValue(10))
+	base.SetFunction("greet", constant(lang.StringValue("hello")))
+
+	child := NewChildContext(base)
+	child.SetVariable("limit", lang.NumberValue(20))
+	child.SetVariable("tenant", nil)
+	child.SetVariable("user", lang.StringValue("john"))
+	child.SetFunction("greet", constant(lang.StringValue("hi")))
+
+	tests := []struct {
+		name     string
+		ctx      *DefaultContext
+		expr     string
+		expected lang.Value
+	}{
+		{"child variable", child, "user", lang.StringValue("john")},
+		{"override", child, "limit", lang.NumberValue(20)},
+		{"null override", child, "tenant", nil},
+		{"override function", child, "greet()", lang.StringValue("hi")},
+		{"inherited library", child, "string.upper(user)", lang.StringValue("JOHN")},
+		{"inherited operand results", child, "tenant or 'none'", lang.StringValue("none")},
+		{"parent untouched", base, "[tenant, limit, user, greet()]", lang.ListValue{lang.StringValue("acme"), lang.NumberValue(10), nil, lang.StringValue("hello")}},
+		{"grandchild", NewChildContext(child, WithValues(map[string]any{"user": "jane"})), "`${user} ${limit}`", lang.StringValue("jane 20")},
+		{"shared built-ins", NewChildContext(BuiltIns()), "list.length([1, 2])", lang.NumberValue(2)},
+		{"own functions first", NewChildContext(base, WithFunctions(map[string]lang.Function{"greet": constant(lang.StringValue("hey"))})), "greet()", lang.StringValue("hey")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Eval(tt.expr, tt.ctx)
+			if err != nil {
+				t.Fatalf("evaluation error: %v", err)
+			}
+			if !valueEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("built-ins are shared", func(t *testing.T) {
+		if NewDefaultContext(WithBuiltInLibrary()).Parent() != BuiltIns() {
+			t.Error("expected the shared built-in context as parent")
+		}
+		if BuiltIns().GetVariable("string") != BuiltIns().GetVariable("string") {
+			t.Error("expected the built-in namespaces to be shared")
+		}
+		// A context with a parent of its own gets the namespaces copied in
+		ctx := NewChildContext(base, WithBuiltInLibrary())
+		if ctx.Parent() != base || ctx.values["string"] != BuiltIns().GetVariable("string") {
+			t.Error("expected the built-in namespaces to be copied in")
+		}
+	})
+
+	t.Run("functions are copied", func(t *testing.T) {
+		funcs := map[string]lang.Function{}
+		ctx := NewDefaultContext(WithFunctions(funcs))
+		ctx.SetFunction("added", constant(nil))
+		if len(funcs) != 0 {
+			t.Errorf("expected the option map to be left alone, got %v", funcs)
+		}
+	})
+
+	t.Run("allocations", func(t *testing.T) {
+		parent := BuiltIns()
+		var ctx *DefaultContext
+		if allocs := testing.AllocsPerRun(100, func() { ctx = NewChildContext(parent) }); allocs != 1 {
+			t.Errorf("expected 1 allocation per child context, got %v", allocs)
+		}
+		_ = ctx
+	})
+}
+
+func TestSyncContext(t *testing.T) {
+	ctx := NewSyncContext(WithBuiltInLibrary(), WithOperandResults())
+	ctx.SetVariable("name", lang.StringValue(""))
+	ctx.SetFunction("double", func(args []lang.Value) (lang.Value, error) {
+		return lang.NumberValue(toNumber(args[0]) * 2), nil
+	})
+
+	result, err := Eval("string.upper(name or 'anonymous') + double(2)", ctx)
+	if err != nil {
+		t.Fatalf("evaluation error: %v", err)
+	}
+	if !valueEqual(result, lang.StringValue("ANONYMOUS4")) {
+		t.Errorf("expected 'ANONYMOUS4', got %v", result)
+	}
+}
+
+// TestConcurrentEvaluation evaluates shared trees, programs and contexts
+// from many goroutines. It is meant to be run with -race.
+func TestConcurrentEvaluation(t *testing.T) {
+	const (
+		goroutines = 16
+		iterations = 200
+	)
+	expr := "list.length(list.shuffle(items)) + string.len(util.randomString(4)) + list.length(list.filter(items, i => i > limit)) + counter * 0"
+	ast, err := Parse(expr)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	program, err := Compile(expr, NewEnv(DeclareBuiltInLibrary(), DeclareVariables("items", "limit", "counter")))
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+
+	base := NewDefaultContext(WithBuiltInLibrary())
+	base.SetVariable("items", lang.ListValue{lang.NumberValue(1), lang.NumberValue(2), lang.NumberValue(3)})
+	shared := NewSyncContext(WithBuiltInLibrary())
+	shared.SetVariable("items", base.GetVariable("items"))
+	shared.SetVariable("limit", lang.NumberValue(1))
+	expected := lang.NumberValue(3 + 4 + 2)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines)
+	check := func(result lang.Value, err error) bool {
+		if err == nil && !valueEqual(result, expected) {
+			err = fmt.Errorf("expected %v, got %v", expected, result)
+		}
+		if err != nil {
+			errs <- err
+			return false
+		}
+		return true
+	}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				child := NewChildContext(base)
+				child.SetVariable("limit", lang.NumberValue(1))
+				child.SetVariable("counter", lang.NumberValue(i))
+				if !check(ast.Evaluate(child)) {
+					return
+				}
+				// Parsing is safe for concurrent use too
+				if !check(Eval(expr, child)) {
+					return
+				}
+
+				// Writers and readers share one context
+				shared.SetVariable("counter", lang.NumberValue(g*iterations+i))
+				if !check(ast.Evaluate(shared)) {
+					return
+				}
+
+				vars := map[string]lang.Value{"items": base.GetVariable("items"), "limit": lang.NumberValue(1), "counter": lang.NumberValue(i)}
+				if !check(program.Evaluate(vars)) {
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDescriptors(t *testing.T) {
+	// math and type are not built-in namespaces, but are checked as well
+	namespaces := descriptors()
+	namespaces["math"] = math.Descriptors()
+	namespaces["type"] = types.Descriptors()
+	for namespace, descriptors := range namespaces {
+		if err := lib.CheckDescriptors(descriptors); err != nil {
+			t.Errorf("%s: %v", namespace, err)
+		}
+	}
 }
@@ -16,8 +16,12 @@
 package lang
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type (
@@ -28,70 +32,279 @@ type (
 	ListValue   []Value
 	MapValue    map[string]Value
 	EachValue   Value
-	ExprNode    interface {
+	RangeValue  struct {
+		Begin, End, Step Value
+	}
+	FunctionValue func(args []Value) (Value, error)
+	// ExprNode is a parsed expression. Evaluation never modifies a node, so
+	// one tree may be evaluated from many goroutines at once.
+	ExprNode interface {
 		Evaluate(ctx Context) (Value, error)
 	}
+	// Context resolves the variables and functions of an expression.
+	// Evaluation only reads from it, so a context that is not written to
+	// while it is in use may be shared by concurrent evaluations.
 	Context interface {
 		GetVariable(name string) Value
 		GetFunction(name string) Function
 	}
-	Function     func(args []Value) (Value, error)
+	// OperandResultContext is implemented by contexts that want `and` and
+	// `or` to return the operand that decided the result (as in JavaScript
+	// or Python) instead of a BoolValue.
+	OperandResultContext interface {
+		Context
+		OperandResults() bool
+	}
+	// Function is a function callable from expressions. Functions may be
+	// called from many goroutines at once and must not modify their
+	// arguments, which can be shared with the context or other evaluations.
+	Function func(args []Value) (Value, error)
+	// Object is a value whose fields are resolved when they are accessed,
+	// such as a Go struct bound with exql.ValueOf. Field returns nil for
+	// fields it does not have.
+	Object interface {
+		Field(name string) Value
+	}
+	// Lazy is an Object that is only loaded when an expression uses it,
+	// such as a variable bound by an exql resolver. Accessing a field of a
+	// lazy variable or field gives another Lazy for that field without
+	// loading anything; any other use loads the value with Resolve, which
+	// must not return a Lazy. An evaluation, whether started by
+	// EvaluateContext or by calling Evaluate directly, keeps the first Lazy
+	// a context returns for a variable until it ends, so a context may
+	// return a new one, with its own cache, on every read.
+	Lazy interface {
+		Object
+		Resolve() (Value, error)
+	}
+	// MapObject is an Object that can list all of its fields, such as a Go
+	// struct bound with exql.ValueOf. It is used as a map wherever a whole
+	// map is needed: it can be spread into a map literal and passed to map
+	// parameters of library functions. Map returns a new map every time.
+	MapObject interface {
+		Object
+		Map() MapValue
+	}
+	// Span is the byte range [Start, End) of a node in the source
+	// expression. Every node built by the parser records its span.
+	Span struct {
+		Start, End int
+	}
+	// Operator, variable and function call nodes also record Pos, the byte
+	// offset of the operator or name in the source expression, so that
+	// tooling can report where a problem was found.
 	BinaryOpNode struct {
 		Left, Right ExprNode
 		Operator    string
+		Pos         int
+		Span        Span
 	}
 	UnaryOpNode struct {
 		Operand  ExprNode
 		Operator string
+		Pos      int
+		Span     Span
 	}
 	LiteralNode struct {
 		Value Value
+		Span  Span
 	}
 	VariableNode struct {
 		Name string
+		Pos  int
+		Span Span
 	}
 	FieldAccessNode struct {
-		Object ExprNode
-		Field  string
+		Object   ExprNode
+		Field    string
+		Optional bool
+		Span     Span
 	}
 	IndexAccessNode struct {
-		Object ExprNode
-		Index  ExprNode
+		Object   ExprNode
+		Index    ExprNode
+		Optional bool
+		Span     Span
 	}
 	FunctionCallNode struct {
 		Namespace ExprNode
 		Name      string
 		Args      []ExprNode
+		Optional  bool
+		Pos       int
+		Span      Span
 	}
 	ListNode struct {
 		Elements []ExprNode
+		Span     Span
+	}
+	TemplateNode struct {
+		Parts []ExprNode
+		Span  Span
+	}
+	MapNode struct {
+		Entries []MapEntry
+		Span    Span
+	}
+	MapEntry struct {
+		Key    ExprNode
+		Value  ExprNode
+		Spread bool
+	}
+	EachNode struct {
+		Span Span
 	}
-	EachNode  struct{}
 	RangeNode struct {
-		Begin Value
-		End   Value
+		Begin ExprNode
+		End   ExprNode
+		Step  ExprNode
+		Span  Span
+	}
+	ConditionalNode struct {
+		Condition ExprNode
+		Then      ExprNode
+		Else      ExprNode
+		Span      Span
+	}
+	CaseNode struct {
+		Subject ExprNode
+		Whens   []WhenClause
+		Else    ExprNode
+		Span    Span
+	}
+	WhenClause struct {
+		Condition ExprNode
+		Result    ExprNode
+	}
+	LambdaNode struct {
+		Params []string
+		Body   ExprNode
+		Span   Span
+	}
+	// BadNode stands in for the part of a partially parsed expression that
+	// could not be parsed.
+	BadNode struct {
+		Span Span
+	}
+	// InSetNode is `Operand in List` or, when Negate is set, `Operand not
+	// in List` for a constant list. Optimize builds it so that membership is
+	// a hash lookup instead of a scan of the list.
+	InSetNode struct {
+		Operand ExprNode
+		List    ListValue
+		Negate  bool
+		Pos     int
+		Span    Span
+		set     map[string]bool
+	}
+	scope struct {
+		parent     Context
+		values     map[string]Value
+		evaluation *evaluation
 	}
 )
 
+// SpanOf returns the source span of node, or the zero Span for nodes that
+// were not built by the parser.
+func SpanOf(node ExprNode) Span {
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		return n.Span
+	case *UnaryOpNode:
+		return n.Span
+	case *LiteralNode:
+		return n.Span
+	case *VariableNode:
+		return n.Span
+	case *FieldAccessNode:
+		return n.Span
+	case *IndexAccessNode:
+		return n.Span
+	case *FunctionCallNode:
+		return n.Span
+	case *ListNode:
+		return n.Span
+	case *TemplateNode:
+		return n.Span
+	case *MapNode:
+		return n.Span
+	case *EachNode:
+		return n.Span
+	case *RangeNode:
+		return n.Span
+	case *ConditionalNode:
+		return n.Span
+	case *CaseNode:
+		return n.Span
+	case *LambdaNode:
+		return n.Span
+	case *BadNode:
+		return n.Span
+	case *InSetNode:
+		return n.Span
+	default:
+		return Span{}
+	}
+}
+
 func (n *BinaryOpNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
 	left, err := n.Left.Evaluate(ctx)
 	if err != nil {
 		return nil, err
 	}
+
+	switch n.Operator {
+	case "and", "or":
+		return n.evaluateLogical(ctx, left)
+	case "??":
+		if left != nil {
+			return left, nil
+		}
+		return n.Right.Evaluate(ctx)
+	}
+
 	right, err := n.Right.Evaluate(ctx)
 	if err != nil {
 		return nil, err
 	}
+	value, err := n.apply(left, right, e.bounds())
+	if err != nil {
+		return nil, err
+	}
+	if err := e.size(value, n.Span); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
 
+// apply applies an operator that evaluates both of its operands. Strings
+// and lists are built within limits.
+func (n *BinaryOpNode) apply(left, right Value, limits Limits) (Value, error) {
 	switch n.Operator {
-	case "and":
-		return BoolValue(ToBool(left) && ToBool(right)), nil
-	case "or":
-		return BoolValue(ToBool(left) || ToBool(right)), nil
 	case "=", "==":
 		return BoolValue(equal(left, right)), nil
 	case "!=":
 		return BoolValue(!equal(left, right)), nil
+	case "in", "not in":
+		if right == nil {
+			return nil, nil
+		}
+		return BoolValue(contains(right, left) == (n.Operator == "in")), nil
+	}
+
+	// Ordering and arithmetic are unknown when either side is null
+	if left == nil || right == nil {
+		switch n.Operator {
+		case "<", "<=", ">", ">=", "+", "-", "*", "/", "%", "//", "**":
+			return nil, nil
+		}
+	}
+
+	switch n.Operator {
 	case "<":
 		return BoolValue(compare(left, right) < 0), nil
 	case "<=":
@@ -100,65 +313,247 @@ func (n *BinaryOpNode) Evaluate(ctx Context) (Value, error) {
 		return BoolValue(compare(left, right) > 0), nil
 	case ">=":
 		return BoolValue(compare(left, right) >= 0), nil
-	case "in":
-		return BoolValue(contains(right, left)), nil
-	case "not in":
-		return BoolValue(!contains(right, left)), nil
 	case "+":
-		return NumberValue(ToNumber(left) + ToNumber(right)), nil
+		value, err := add(left, right, limits)
+		if err != nil {
+			return nil, evalError(n.Span, err)
+		}
+		return value, nil
 	case "-":
 		return NumberValue(ToNumber(left) - ToNumber(right)), nil
 	case "*":
 		return NumberValue(ToNumber(left) * ToNumber(right)), nil
 	case "/":
 		return NumberValue(ToNumber(left) / ToNumber(right)), nil
+	case "%":
+		if ToNumber(right) == 0 {
+			return nil, evalErrorf(n.Span, "expectation failed: modulo by zero")
+		}
+		return NumberValue(math.Mod(ToNumber(left), ToNumber(right))), nil
+	case "//":
+		if ToNumber(right) == 0 {
+			return nil, evalErrorf(n.Span, "expectation failed: integer division by zero")
+		}
+		return NumberValue(math.Floor(ToNumber(left) / ToNumber(right))), nil
+	case "**":
+		return NumberValue(math.Pow(ToNumber(left), ToNumber(right))), nil
 	}
-	return nil, fmt.Errorf("expectation failed: %s not supported", n.Operator)
+	return nil, evalErrorf(n.Span, "expectation failed: %s not supported", n.Operator)
+}
+
+// evaluateLogical implements `and` / `or` with three-valued logic: null is
+// unknown, so `null and false` is false, `null or true` is true and any other
+// combination involving null is null. With operand results the JavaScript
+// semantics apply instead and null is simply falsy.
+func (n *BinaryOpNode) evaluateLogical(ctx Context, left Value) (Value, error) {
+	operands := false
+	if ctx, ok := ctx.(OperandResultContext); ok {
+		operands = ctx.OperandResults()
+	}
+
+	decisive := n.Operator == "or"
+	if operands {
+		if ToBool(left) == decisive {
+			return left, nil
+		}
+		return n.Right.Evaluate(ctx)
+	}
+
+	if left != nil && ToBool(left) == decisive {
+		return BoolValue(decisive), nil
+	}
+	right, err := n.Right.Evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return logical(decisive, left, right), nil
+}
+
+// logical combines both operands of `and` (decisive false) or `or`
+// (decisive true) when the left one did not decide the result.
+func logical(decisive bool, left, right Value) Value {
+	if right != nil && ToBool(right) == decisive {
+		return BoolValue(decisive)
+	}
+	if left == nil || right == nil {
+		return nil
+	}
+	return BoolValue(!decisive)
 }
 
 func (n *UnaryOpNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
 	operand, err := n.Operand.Evaluate(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return n.apply(operand)
+}
+
+func (n *UnaryOpNode) apply(operand Value) (Value, error) {
+	switch n.Operator {
+	case "is null":
+		return BoolValue(operand == nil), nil
+	case "is not null":
+		return BoolValue(operand != nil), nil
+	}
+	if operand == nil {
+		switch n.Operator {
+		case "not", "-":
+			return nil, nil
+		}
+	}
 	switch n.Operator {
 	case "not":
 		return BoolValue(!ToBool(operand)), nil
 	case "-":
 		return NumberValue(-ToNumber(operand)), nil
 	}
-	return nil, fmt.Errorf("expectation failed: %s not supported", n.Operator)
+	return nil, evalErrorf(n.Span, "expectation failed: %s not supported", n.Operator)
 }
 
 func (n *LiteralNode) Evaluate(ctx Context) (Value, error) {
+	e := current(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	// Literals folded by Optimize may be larger than the limits
+	if err := e.size(n.Value, n.Span); err != nil {
+		return nil, err
+	}
 	return n.Value, nil
 }
 
 func (n *VariableNode) Evaluate(ctx Context) (Value, error) {
-	return ctx.GetVariable(n.Name), nil
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	return resolve(ctx.GetVariable(n.Name), n.Span)
 }
 
 func (n *FieldAccessNode) Evaluate(ctx Context) (Value, error) {
-	obj, err := n.Object.Evaluate(ctx)
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	value, _, err := n.access(ctx, false)
 	if err != nil {
 		return nil, err
 	}
-	return n.evaluate(obj), nil
+	return resolve(value, n.Span)
+}
+
+// access evaluates n as a link of an access chain such as `a?.b[0].c`,
+// without resolving a Lazy result, and reports whether an optional access
+// in the chain found null, in which case the rest of the chain is skipped
+// and evaluates to null. strict is set if a later link of the chain fails
+// on null; only then is a Lazy object of an optional access resolved to
+// tell whether it is null, since fields of null are null anyway.
+func (n *FieldAccessNode) access(ctx Context, strict bool) (Value, bool, error) {
+	obj, short, err := chain(n.Object, ctx, n.Optional && strict, strict && !n.Optional)
+	if err != nil || short {
+		return nil, short, err
+	}
+	if obj == nil && n.Optional {
+		return nil, true, nil
+	}
+	return Field(obj, n.Field), false, nil
+}
+
+// chain evaluates node as the object of a link of an access chain, see
+// FieldAccessNode.access. A variable or field that is Lazy is only
+// resolved if resolved is set, so that accessing its fields only loads
+// those fields.
+func chain(node ExprNode, ctx Context, resolved, strict bool) (Value, bool, error) {
+	switch n := node.(type) {
+	case *VariableNode:
+		{
+			if !resolved {
+				return ctx.GetVariable(n.Name), false, nil
+			}
+			value, err := n.Evaluate(ctx)
+			return value, false, err
+		}
+	case *FieldAccessNode:
+		{
+			value, short, err := n.access(ctx, strict)
+			if err != nil || short || !resolved {
+				return value, short, err
+			}
+			value, err = resolve(value, n.Span)
+			return value, false, err
+		}
+	case *IndexAccessNode:
+		{
+			return n.access(ctx)
+		}
+	case *FunctionCallNode:
+		{
+			return n.access(ctx)
+		}
+	default:
+		{
+			value, err := node.Evaluate(ctx)
+			return value, false, err
+		}
+	}
+}
+
+// resolve loads value if it is Lazy, attributing errors to the node
+// spanning span.
+func resolve(value Value, span Span) (Value, error) {
+	lazy, ok := value.(Lazy)
+	if !ok {
+		return value, nil
+	}
+	value, err := lazy.Resolve()
+	if err != nil {
+		return nil, evalError(span, err)
+	}
+	return value, nil
 }
 
-func (n *FieldAccessNode) evaluate(obj Value) Value {
+// ToMap returns value as a map if it is a MapValue or a MapObject.
+func ToMap(value Value) (MapValue, bool) {
+	switch value := value.(type) {
+	case MapValue:
+		{
+			return value, true
+		}
+	case MapObject:
+		{
+			return value.Map(), true
+		}
+	default:
+		{
+			return nil, false
+		}
+	}
+}
+
+// Field returns the named field of a map or Object, or of each element of a
+// list, as the expression `obj.name` does. It returns nil for anything else.
+func Field(obj Value, name string) Value {
 	switch obj := obj.(type) {
 	case ListValue:
 		{
-			values := make(ListValue, 0)
-			for _, i := range obj {
-				values = append(values, n.evaluate(i))
+			values := make(ListValue, len(obj))
+			for i, item := range obj {
+				values[i] = Field(item, name)
 			}
 			return values
 		}
 	case MapValue:
 		{
-			return obj[n.Field]
+			return obj[name]
+		}
+	case Object:
+		{
+			return obj.Field(name)
 		}
 	default:
 		{
@@ -168,26 +563,48 @@ func (n *FieldAccessNode) evaluate(obj Value) Value {
 }
 
 func (n *IndexAccessNode) Evaluate(ctx Context) (Value, error) {
-	obj, err := n.Object.Evaluate(ctx)
-	if err != nil {
-		return nil, err
+	_, ctx = begin(ctx)
+	value, _, err := n.access(ctx)
+	return value, err
+}
+
+// access evaluates n as a link of an access chain, see
+// FieldAccessNode.access.
+func (n *IndexAccessNode) access(ctx Context) (Value, bool, error) {
+	if err := current(ctx).step(n.Span); err != nil {
+		return nil, false, err
+	}
+	obj, short, err := chain(n.Object, ctx, true, !n.Optional)
+	if err != nil || short {
+		return nil, short, err
+	}
+	if obj == nil && n.Optional {
+		return nil, true, nil
 	}
 
 	index, err := n.Index.Evaluate(ctx)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
+	value, err := n.apply(obj, index)
+	return value, false, err
+}
 
+func (n *IndexAccessNode) apply(obj, index Value) (Value, error) {
 	switch obj := obj.(type) {
 	case MapValue:
 		{
 			if strIndex, ok := index.(StringValue); ok {
-				expr := new(FieldAccessNode)
-				expr.Field = string(strIndex)
-				expr.Object = n.Object
-				return expr.Evaluate(ctx)
+				return obj[string(strIndex)], nil
+			}
+			return nil, evalErrorf(n.Span, "expectation failed: %T not supported", index)
+		}
+	case Object:
+		{
+			if strIndex, ok := index.(StringValue); ok {
+				return obj.Field(string(strIndex)), nil
 			}
-			return nil, fmt.Errorf("expectation failed: %T not supported", index)
+			return nil, evalErrorf(n.Span, "expectation failed: %T not supported", index)
 		}
 	case ListValue:
 		{
@@ -198,18 +615,23 @@ func (n *IndexAccessNode) Evaluate(ctx Context) (Value, error) {
 					if idx >= 0 && idx < len(obj) {
 						return obj[idx], nil
 					}
-					return nil, fmt.Errorf("expectation failed: index %d is out of range", idx)
+					return nil, evalErrorf(n.Span, "expectation failed: index %d is out of range", idx)
 				}
 			case StringValue:
 				{
-					expr := new(FieldAccessNode)
-					expr.Field = string(index)
-					expr.Object = n.Object
-					return expr.Evaluate(ctx)
+					return Field(obj, string(index)), nil
 				}
 			case BoolValue:
 				{
-					return nil, fmt.Errorf("expectation failed: %T not supported", index)
+					return nil, evalErrorf(n.Span, "expectation failed: %T not supported", index)
+				}
+			case RangeValue:
+				{
+					value, err := sliceList(obj, index)
+					if err != nil {
+						return nil, evalError(n.Span, err)
+					}
+					return value, nil
 				}
 			case EachValue:
 				{
@@ -217,31 +639,69 @@ func (n *IndexAccessNode) Evaluate(ctx Context) (Value, error) {
 				}
 			default:
 				{
-					return nil, fmt.Errorf("expectation failed: %T not supported", index)
+					return nil, evalErrorf(n.Span, "expectation failed: %T not supported", index)
 				}
 			}
 		}
+	case StringValue:
+		{
+			if index, ok := index.(RangeValue); ok {
+				value, err := sliceString(obj, index)
+				if err != nil {
+					return nil, evalError(n.Span, err)
+				}
+				return value, nil
+			}
+			return nil, evalErrorf(n.Span, "expectation failed: %T not supported", obj)
+		}
 	default:
 		{
-			return nil, fmt.Errorf("expectation failed: %T not supported", obj)
+			return nil, evalErrorf(n.Span, "expectation failed: %T not supported", obj)
 		}
 	}
 }
 
 func (n *FunctionCallNode) Evaluate(ctx Context) (Value, error) {
+	_, ctx = begin(ctx)
+	value, _, err := n.access(ctx)
+	return value, err
+}
+
+// access evaluates n as a link of an access chain, see
+// FieldAccessNode.access. Calls without a namespace start a chain.
+func (n *FunctionCallNode) access(ctx Context) (Value, bool, error) {
+	e := current(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, false, err
+	}
 	namespace := ctx
 	if n.Namespace != nil {
-		value, err := n.Namespace.Evaluate(ctx)
-		if err != nil {
-			return nil, err
+		value, short, err := chain(n.Namespace, ctx, true, !n.Optional)
+		if err != nil || short {
+			return nil, short, err
 		}
-		n, ok := value.(Context)
+		if value == nil && n.Optional {
+			return nil, true, nil
+		}
+		inner, ok := value.(Context)
 		if !ok {
-			return nil, fmt.Errorf("unexpected identifier %v", value)
+			return nil, false, evalErrorf(n.Span, "unexpected identifier %v", value)
+		}
+		namespace = inner
+	}
+	value, err := n.call(ctx, namespace, e)
+	return value, false, err
+}
+
+// call calls the function n names in namespace with the arguments of n
+// evaluated in ctx.
+func (n *FunctionCallNode) call(ctx, namespace Context, e *evaluation) (Value, error) {
+	fn := function(namespace, n.Name, e)
+	if fn == nil && n.Namespace == nil {
+		if value, ok := ctx.GetVariable(n.Name).(FunctionValue); ok {
+			fn = Function(value)
 		}
-		namespace = n
 	}
-	fn := namespace.GetFunction(n.Name)
 	if fn == nil {
 		return BoolValue(false), nil
 	}
@@ -255,10 +715,39 @@ func (n *FunctionCallNode) Evaluate(ctx Context) (Value, error) {
 		args[i] = val
 	}
 
-	return fn(args)
+	if err := e.cancelled(n.Span); err != nil {
+		return nil, err
+	}
+	value, err := fn(args)
+	if err != nil {
+		return nil, n.callError(err)
+	}
+	if err := e.size(value, n.Span); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// callError attributes an error returned by the called function. Errors
+// that surfaced from a lambda the function called keep the lambda's span
+// and gain this call in their stack.
+func (n *FunctionCallNode) callError(err error) error {
+	name := n.Name
+	if namespace, ok := n.Namespace.(*VariableNode); ok {
+		name = namespace.Name + "." + n.Name
+	}
+	var inner *EvalError
+	if errors.As(err, &inner) {
+		return &EvalError{Span: inner.Span, Stack: append([]string{name}, inner.Stack...), Err: err}
+	}
+	return &EvalError{Span: n.Span, Stack: []string{name}, Err: err}
 }
 
 func (n *ListNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
 	elements := make([]Value, len(n.Elements))
 	for i, elem := range n.Elements {
 		val, err := elem.Evaluate(ctx)
@@ -267,15 +756,479 @@ func (n *ListNode) Evaluate(ctx Context) (Value, error) {
 		}
 		elements[i] = val
 	}
+	if err := e.size(ListValue(elements), n.Span); err != nil {
+		return nil, err
+	}
 	return ListValue(elements), nil
 }
 
+func (n *TemplateNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	for _, part := range n.Parts {
+		value, err := part.Evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTemplatePart(&sb, value, e.bounds()); err != nil {
+			return nil, evalError(n.Span, err)
+		}
+	}
+	value := StringValue(sb.String())
+	if err := e.size(value, n.Span); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// writeTemplatePart interpolates value into sb, failing as soon as sb
+// grows longer than the MaxStringLength of limits.
+func writeTemplatePart(sb *strings.Builder, value Value, limits Limits) error {
+	if value == nil {
+		value = StringValue("null")
+	}
+	if !writeString(sb, value, limits.MaxStringLength) {
+		return limits.tooLong()
+	}
+	return nil
+}
+
+func (n *MapNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	out := make(MapValue, len(n.Entries))
+	for _, entry := range n.Entries {
+		value, err := entry.Value.Evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Spread {
+			if err := n.spread(out, value); err != nil {
+				return nil, err
+			}
+			if err := e.size(out, n.Span); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key, err := entry.Key.Evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := n.set(out, key, value); err != nil {
+			return nil, err
+		}
+		if err := e.size(out, n.Span); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (n *MapNode) set(out MapValue, key, value Value) error {
+	switch key := key.(type) {
+	case StringValue:
+		{
+			out[string(key)] = value
+			return nil
+		}
+	case NumberValue, BoolValue:
+		{
+			out[toString(key)] = value
+			return nil
+		}
+	default:
+		{
+			return evalErrorf(n.Span, "expectation failed: %T not supported as map key", key)
+		}
+	}
+}
+
+func (n *MapNode) spread(out MapValue, value Value) error {
+	if value == nil {
+		return nil
+	}
+	m, ok := ToMap(value)
+	if !ok {
+		return evalErrorf(n.Span, "expectation failed: cannot spread %T into map", value)
+	}
+	for k, v := range m {
+		out[k] = v
+	}
+	return nil
+}
+
 func (n *EachNode) Evaluate(ctx Context) (Value, error) {
+	if err := current(ctx).step(n.Span); err != nil {
+		return nil, err
+	}
 	return EachValue(0), nil
 }
 
+func (n *BadNode) Evaluate(ctx Context) (Value, error) {
+	return nil, evalErrorf(n.Span, "expectation failed: invalid expression")
+}
+
+// NewInSetNode returns an InSetNode testing operand against list.
+func NewInSetNode(operand ExprNode, list ListValue, negate bool, pos int, span Span) *InSetNode {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[equalKey(item)] = true
+	}
+	return &InSetNode{Operand: operand, List: list, Negate: negate, Pos: pos, Span: span, set: set}
+}
+
+func (n *InSetNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	operand, err := n.Operand.Evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return n.apply(operand), nil
+}
+
+func (n *InSetNode) apply(operand Value) Value {
+	if n.set == nil {
+		return BoolValue(contains(n.List, operand) != n.Negate)
+	}
+	return BoolValue(n.set[equalKey(operand)] != n.Negate)
+}
+
 func (n *RangeNode) Evaluate(ctx Context) (Value, error) {
-	return EachValue(0), nil
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	begin, err := evaluateOptional(n.Begin, ctx)
+	if err != nil {
+		return nil, err
+	}
+	end, err := evaluateOptional(n.End, ctx)
+	if err != nil {
+		return nil, err
+	}
+	step, err := evaluateOptional(n.Step, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return RangeValue{Begin: begin, End: end, Step: step}, nil
+}
+
+func evaluateOptional(node ExprNode, ctx Context) (Value, error) {
+	if node == nil {
+		return nil, nil
+	}
+	return node.Evaluate(ctx)
+}
+
+func (n *ConditionalNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	condition, err := n.Condition.Evaluate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ToBool(condition) {
+		return n.Then.Evaluate(ctx)
+	}
+	return n.Else.Evaluate(ctx)
+}
+
+func (n *CaseNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	var subject Value
+	if n.Subject != nil {
+		value, err := n.Subject.Evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		subject = value
+	}
+	for _, when := range n.Whens {
+		condition, err := when.Condition.Evaluate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		matched := ToBool(condition)
+		if n.Subject != nil {
+			matched = equal(subject, condition)
+		}
+		if matched {
+			return when.Result.Evaluate(ctx)
+		}
+	}
+	return evaluateOptional(n.Else, ctx)
+}
+
+func (n *LambdaNode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	if err := e.step(n.Span); err != nil {
+		return nil, err
+	}
+	return FunctionValue(func(args []Value) (Value, error) {
+		if err := e.cancelled(n.Span); err != nil {
+			return nil, err
+		}
+		values := make(map[string]Value, len(n.Params))
+		for i, param := range n.Params {
+			if i < len(args) {
+				values[param] = args[i]
+			} else {
+				values[param] = nil
+			}
+		}
+		return n.Body.Evaluate(&scope{parent: ctx, values: values, evaluation: e})
+	}), nil
+}
+
+func (s *scope) GetVariable(name string) Value {
+	if value, ok := s.values[name]; ok {
+		return value
+	}
+	return s.parent.GetVariable(name)
+}
+
+func (s *scope) GetFunction(name string) Function {
+	return s.parent.GetFunction(name)
+}
+
+func (s *scope) OperandResults() bool {
+	if ctx, ok := s.parent.(OperandResultContext); ok {
+		return ctx.OperandResults()
+	}
+	return false
+}
+
+func sliceList(list ListValue, r RangeValue) (Value, error) {
+	indices, err := r.indices(len(list))
+	if err != nil {
+		return nil, err
+	}
+	out := make(ListValue, len(indices))
+	for i, idx := range indices {
+		out[i] = list[idx]
+	}
+	return out, nil
+}
+
+func sliceString(str StringValue, r RangeValue) (Value, error) {
+	runes := []rune(str)
+	indices, err := r.indices(len(runes))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]rune, len(indices))
+	for i, idx := range indices {
+		out[i] = runes[idx]
+	}
+	return StringValue(out), nil
+}
+
+// indices resolves the range against a sequence of the given length and
+// returns the selected positions in order. Bounds are half-open, negative
+// bounds count from the end and omitted bounds default to the start or end
+// of the sequence depending on the direction of the step.
+func (r RangeValue) indices(length int) ([]int, error) {
+	step := 1
+	if r.Step != nil {
+		value, err := rangeBound(r.Step, length, false)
+		if err != nil {
+			return nil, err
+		}
+		if value == 0 {
+			return nil, fmt.Errorf("expectation failed: slice step cannot be zero")
+		}
+		step = value
+	}
+
+	begin, end := 0, length
+	if step < 0 {
+		begin, end = length-1, -1
+	}
+	if r.Begin != nil {
+		value, err := rangeBound(r.Begin, length, true)
+		if err != nil {
+			return nil, err
+		}
+		begin = value
+		if step < 0 && begin == length {
+			begin = length - 1
+		}
+	}
+	if r.End != nil {
+		value, err := rangeBound(r.End, length, true)
+		if err != nil {
+			return nil, err
+		}
+		end = value
+	}
+
+	out := make([]int, 0)
+	if step > 0 {
+		for i := begin; i < end; i += step {
+			out = append(out, i)
+		}
+		return out, nil
+	}
+	for i := begin; i > end; i += step {
+		out = append(out, i)
+	}
+	return out, nil
+}
+
+func rangeBound(value Value, length int, normalize bool) (int, error) {
+	number, ok := value.(NumberValue)
+	if !ok {
+		return 0, fmt.Errorf("expectation failed: %T not supported as slice bound", value)
+	}
+	idx := int(number)
+	if !normalize {
+		return idx, nil
+	}
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx > length {
+		return 0, fmt.Errorf("expectation failed: slice bound %d is out of range for length %d", int(number), length)
+	}
+	return idx, nil
+}
+
+// add concatenates when either side is a string, appends when both sides
+// are lists and adds numerically otherwise. Strings and lists are checked
+// against limits before they are built.
+func add(left, right Value, limits Limits) (Value, error) {
+	l, leftString := left.(StringValue)
+	r, rightString := right.(StringValue)
+	if leftString && rightString {
+		if err := limits.CheckString(len(l) + len(r)); err != nil {
+			return nil, err
+		}
+		return l + r, nil
+	}
+	if leftString || rightString {
+		var sb strings.Builder
+		if !writeString(&sb, left, limits.MaxStringLength) || !writeString(&sb, right, limits.MaxStringLength) {
+			return nil, limits.tooLong()
+		}
+		return StringValue(sb.String()), nil
+	}
+	leftList, leftIsList := left.(ListValue)
+	rightList, rightIsList := right.(ListValue)
+	if leftIsList && rightIsList {
+		if err := limits.CheckCollection(len(leftList) + len(rightList)); err != nil {
+			return nil, err
+		}
+		out := make(ListValue, 0, len(leftList)+len(rightList))
+		out = append(out, leftList...)
+		return append(out, rightList...), nil
+	}
+	return NumberValue(ToNumber(left) + ToNumber(right)), nil
+}
+
+func toString(v Value) string {
+	var sb strings.Builder
+	writeString(&sb, v, 0)
+	return sb.String()
+}
+
+// writeString writes v to sb as a string, stopping as soon as sb holds more
+// than max bytes, so that a large list is not stringified whole only to
+// fail the limit. It reports whether sb is within max, which is unlimited
+// if zero.
+func writeString(sb *strings.Builder, v Value, max int) bool {
+	switch val := v.(type) {
+	case StringValue:
+		{
+			if max > 0 && sb.Len()+len(val) > max {
+				return false
+			}
+			sb.WriteString(string(val))
+		}
+	case NumberValue:
+		{
+			sb.WriteString(strconv.FormatFloat(float64(val), 'f', -1, 64))
+		}
+	case BoolValue:
+		{
+			sb.WriteString(strconv.FormatBool(bool(val)))
+		}
+	default:
+		{
+			return writeElement(sb, v, max)
+		}
+	}
+	return max <= 0 || sb.Len() <= max
+}
+
+// writeElement writes v to sb as fmt's %v verb does, stopping as soon as
+// sb holds more than max bytes.
+func writeElement(sb *strings.Builder, v Value, max int) bool {
+	switch val := v.(type) {
+	case nil:
+		{
+			sb.WriteString("<nil>")
+		}
+	case StringValue:
+		{
+			return writeString(sb, val, max)
+		}
+	case NumberValue:
+		{
+			sb.WriteString(strconv.FormatFloat(float64(val), 'g', -1, 64))
+		}
+	case ListValue:
+		{
+			sb.WriteByte('[')
+			for i, item := range val {
+				if i > 0 {
+					sb.WriteByte(' ')
+				}
+				if !writeElement(sb, item, max) {
+					return false
+				}
+			}
+			sb.WriteByte(']')
+		}
+	case MapValue:
+		{
+			keys := make([]string, 0, len(val))
+			for key := range val {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			sb.WriteString("map[")
+			for i, key := range keys {
+				if i > 0 {
+					sb.WriteByte(' ')
+				}
+				sb.WriteString(key)
+				sb.WriteByte(':')
+				if !writeElement(sb, val[key], max) {
+					return false
+				}
+			}
+			sb.WriteByte(']')
+		}
+	default:
+		{
+			sb.WriteString(fmt.Sprint(v))
+		}
+	}
+	return max <= 0 || sb.Len() <= max
 }
 
 func ToBool(v Value) bool {
@@ -309,7 +1262,47 @@ func ToNumber(v Value) float64 {
 }
 
 func equal(a, b Value) bool {
-	return fmt.Sprintf("%T %v", a, a) == fmt.Sprintf("%T %v", b, b)
+	switch a := a.(type) {
+	case StringValue:
+		if b, ok := b.(StringValue); ok {
+			return a == b
+		}
+	case NumberValue:
+		if b, ok := b.(NumberValue); ok {
+			return sameNumber(float64(a), float64(b))
+		}
+	case BoolValue:
+		if b, ok := b.(BoolValue); ok {
+			return a == b
+		}
+	}
+	return equalKey(a) == equalKey(b)
+}
+
+// sameNumber reports whether a and b have the same equalKey: NaN equals
+// itself and 0 does not equal -0.
+func sameNumber(a, b float64) bool {
+	if a != a {
+		return b != b
+	}
+	return a == b && math.Signbit(a) == math.Signbit(b)
+}
+
+// equalKey returns a key that two values share exactly when they are equal.
+// Values of different types are never equal.
+func equalKey(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case StringValue:
+		return "s:" + string(v)
+	case NumberValue:
+		return "n:" + strconv.FormatFloat(float64(v), 'g', -1, 64)
+	case BoolValue:
+		return "b:" + strconv.FormatBool(bool(v))
+	default:
+		return fmt.Sprintf("%T %v", v, v)
+	}
 }
 
 func compare(a, b Value) int {
@@ -16,7 +16,11 @@
 package lang
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -88,6 +92,20 @@ func TestBinaryOpNode(t *testing.T) {
 		{"subtraction", NumberValue(5), NumberValue(3), "-", NumberValue(2)},
 		{"multiplication", NumberValue(3), NumberValue(4), "*", NumberValue(12)},
 		{"division", NumberValue(10), NumberValue(2), "/", NumberValue(5)},
+		{"modulo", NumberValue(10), NumberValue(3), "%", NumberValue(1)},
+		{"modulo negative", NumberValue(-7), NumberValue(3), "%", NumberValue(-1)},
+		{"modulo fraction", NumberValue(5.5), NumberValue(2), "%", NumberValue(1.5)},
+		{"integer division", NumberValue(7), NumberValue(2), "//", NumberValue(3)},
+		{"integer division floors", NumberValue(-7), NumberValue(2), "//", NumberValue(-4)},
+		{"power", NumberValue(2), NumberValue(10), "**", NumberValue(1024)},
+		{"fractional power", NumberValue(9), NumberValue(0.5), "**", NumberValue(3)},
+
+		// Concatenation
+		{"string concatenation", StringValue("a"), StringValue("b"), "+", StringValue("ab")},
+		{"string and number", StringValue("id-"), NumberValue(42), "+", StringValue("id-42")},
+		{"number and string", NumberValue(1.5), StringValue("x"), "+", StringValue("1.5x")},
+		{"string and bool", StringValue("is "), BoolValue(true), "+", StringValue("is true")},
+		{"list append", ListValue{NumberValue(1)}, ListValue{NumberValue(2)}, "+", ListValue{NumberValue(1), NumberValue(2)}},
 
 		// In operations
 		{"in true", NumberValue(2), ListValue{NumberValue(1), NumberValue(2), NumberValue(3)}, "in", BoolValue(true)},
@@ -116,6 +134,74 @@ func TestBinaryOpNode(t *testing.T) {
 	}
 }
 
+func TestBinaryOpNodeNull(t *testing.T) {
+	ctx := NewMockContext()
+	list := ListValue{NumberValue(1), nil}
+
+	tests := []struct {
+		name     string
+		left     Value
+		right    Value
+		operator string
+		expected Value
+	}{
+		// Arithmetic propagates null
+		{"null plus number", nil, NumberValue(1), "+", nil},
+		{"number minus null", NumberValue(1), nil, "-", nil},
+		{"null times null", nil, nil, "*", nil},
+		{"number divided by null", NumberValue(1), nil, "/", nil},
+		{"null modulo number", nil, NumberValue(2), "%", nil},
+		{"null power", nil, NumberValue(2), "**", nil},
+		{"string plus null", StringValue("a"), nil, "+", nil},
+
+		// Ordering with null is unknown
+		{"null less than number", nil, NumberValue(1), "<", nil},
+		{"number greater than null", NumberValue(0), nil, ">", nil},
+		{"null greater than equal null", nil, nil, ">=", nil},
+
+		// Equality treats null as a value
+		{"null equals null", nil, nil, "==", BoolValue(true)},
+		{"null equals zero", nil, NumberValue(0), "==", BoolValue(false)},
+		{"null not equal string", nil, StringValue(""), "!=", BoolValue(true)},
+
+		// Three-valued logic
+		{"null and false", nil, BoolValue(false), "and", BoolValue(false)},
+		{"null and true", nil, BoolValue(true), "and", nil},
+		{"true and null", BoolValue(true), nil, "and", nil},
+		{"false and null", BoolValue(false), nil, "and", BoolValue(false)},
+		{"null or true", nil, BoolValue(true), "or", BoolValue(true)},
+		{"null or false", nil, BoolValue(false), "or", nil},
+		{"false or null", BoolValue(false), nil, "or", nil},
+		{"true or null", BoolValue(true), nil, "or", BoolValue(true)},
+		{"null and null", nil, nil, "and", nil},
+
+		// Membership
+		{"null in list with null", nil, list, "in", BoolValue(true)},
+		{"null in list without null", nil, ListValue{NumberValue(1)}, "in", BoolValue(false)},
+		{"value in null", NumberValue(1), nil, "in", nil},
+		{"value not in null", NumberValue(1), nil, "not in", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &BinaryOpNode{
+				Left:     &LiteralNode{Value: tt.left},
+				Right:    &LiteralNode{Value: tt.right},
+				Operator: tt.operator,
+			}
+
+			result, err := node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestBinaryOpNodeUnsupportedOperator(t *testing.T) {
 	ctx := NewMockContext()
 	node := &BinaryOpNode{
@@ -130,6 +216,117 @@ func TestBinaryOpNodeUnsupportedOperator(t *testing.T) {
 	}
 }
 
+type operandResultContext struct {
+	*MockContext
+}
+
+func (c operandResultContext) OperandResults() bool {
+	return true
+}
+
+func TestBinaryOpNodeShortCircuit(t *testing.T) {
+	ctx := NewMockContext()
+	calls := 0
+	ctx.SetFunction("touch", func(args []Value) (Value, error) {
+		calls++
+		return BoolValue(true), nil
+	})
+	touch := &FunctionCallNode{Name: "touch", Args: []ExprNode{}}
+
+	tests := []struct {
+		name          string
+		left          Value
+		operator      string
+		expected      Value
+		expectedCalls int
+	}{
+		{"and stops on false", BoolValue(false), "and", BoolValue(false), 0},
+		{"and continues on true", BoolValue(true), "and", BoolValue(true), 1},
+		{"or stops on true", BoolValue(true), "or", BoolValue(true), 0},
+		{"or continues on false", BoolValue(false), "or", BoolValue(true), 1},
+		{"and with nil is unknown", nil, "and", nil, 1},
+		{"or with nil continues", nil, "or", BoolValue(true), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			node := &BinaryOpNode{
+				Left:     &LiteralNode{Value: tt.left},
+				Right:    touch,
+				Operator: tt.operator,
+			}
+
+			result, err := node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+func TestBinaryOpNodeOperandResults(t *testing.T) {
+	ctx := operandResultContext{NewMockContext()}
+
+	tests := []struct {
+		name     string
+		left     Value
+		right    Value
+		operator string
+		expected Value
+	}{
+		{"or returns truthy left", StringValue("alice"), StringValue("anonymous"), "or", StringValue("alice")},
+		{"or returns right", StringValue(""), StringValue("anonymous"), "or", StringValue("anonymous")},
+		{"and returns falsy left", NumberValue(0), StringValue("x"), "and", NumberValue(0)},
+		{"and returns right", NumberValue(1), StringValue("x"), "and", StringValue("x")},
+		{"or returns nil right", nil, nil, "or", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &BinaryOpNode{
+				Left:     &LiteralNode{Value: tt.left},
+				Right:    &LiteralNode{Value: tt.right},
+				Operator: tt.operator,
+			}
+
+			result, err := node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBinaryOpNodeDivisionByZero(t *testing.T) {
+	ctx := NewMockContext()
+
+	for _, operator := range []string{"%", "//"} {
+		t.Run(operator, func(t *testing.T) {
+			node := &BinaryOpNode{
+				Left:     &LiteralNode{Value: NumberValue(1)},
+				Right:    &LiteralNode{Value: NumberValue(0)},
+				Operator: operator,
+			}
+
+			if _, err := node.Evaluate(ctx); err == nil {
+				t.Error("expected error for division by zero")
+			}
+		})
+	}
+}
+
 func TestUnaryOpNode(t *testing.T) {
 	ctx := NewMockContext()
 
@@ -145,6 +342,12 @@ func TestUnaryOpNode(t *testing.T) {
 		{"not falsy", NumberValue(0), "not", BoolValue(true)},
 		{"negative number", NumberValue(5), "-", NumberValue(-5)},
 		{"double negative", NumberValue(-3), "-", NumberValue(3)},
+		{"not null", nil, "not", nil},
+		{"negative null", nil, "-", nil},
+		{"null is null", nil, "is null", BoolValue(true)},
+		{"value is null", NumberValue(0), "is null", BoolValue(false)},
+		{"null is not null", nil, "is not null", BoolValue(false)},
+		{"value is not null", StringValue(""), "is not null", BoolValue(true)},
 	}
 
 	for _, tt := range tests {
@@ -243,6 +446,40 @@ func TestVariableNode(t *testing.T) {
 	}
 }
 
+// mockObject resolves its fields on access, as an Object.
+type mockObject map[string]Value
+
+func (o mockObject) Field(name string) Value {
+	return o[name]
+}
+
+// mockLazy is the value at path in data, loaded on Resolve. Every load is
+// recorded in loads.
+type mockLazy struct {
+	data  Value
+	path  []string
+	loads *[]string
+}
+
+func (l mockLazy) Field(name string) Value {
+	return mockLazy{data: l.data, path: append(l.path[:len(l.path):len(l.path)], name), loads: l.loads}
+}
+
+func (l mockLazy) Resolve() (Value, error) {
+	path := strings.Join(l.path, ".")
+	*l.loads = append(*l.loads, path)
+	value := l.data
+	for _, name := range l.path {
+		if fields, ok := value.(MapValue); ok {
+			if _, ok := fields[name]; !ok {
+				return nil, fmt.Errorf("%s not found", path)
+			}
+		}
+		value = Field(value, name)
+	}
+	return value, nil
+}
+
 func TestFieldAccessNode(t *testing.T) {
 	ctx := NewMockContext()
 
@@ -279,6 +516,18 @@ func TestFieldAccessNode(t *testing.T) {
 			"length",
 			nil,
 		},
+		{
+			"object field access",
+			mockObject{"name": StringValue("John")},
+			"name",
+			StringValue("John"),
+		},
+		{
+			"list of objects field access",
+			ListValue{mockObject{"name": StringValue("John")}, MapValue{"name": StringValue("Jane")}},
+			"name",
+			ListValue{StringValue("John"), StringValue("Jane")},
+		},
 	}
 
 	for _, tt := range tests {
@@ -300,6 +549,69 @@ func TestFieldAccessNode(t *testing.T) {
 	}
 }
 
+func TestLazyAccess(t *testing.T) {
+	data := MapValue{"profile": MapValue{
+		"name":    StringValue("John"),
+		"orders":  ListValue{MapValue{"id": NumberValue(1)}},
+		"manager": nil,
+	}}
+	tests := []struct {
+		input    string
+		expected Value
+		loads    []string
+	}{
+		{"profile.name", StringValue("John"), []string{"profile.name"}},
+		{"profile.orders.id", ListValue{NumberValue(1)}, []string{"profile.orders.id"}},
+		{"profile.orders[0].id", NumberValue(1), []string{"profile.orders"}},
+		{"profile['name']", StringValue("John"), []string{"profile"}},
+		{"profile.name + profile.name", StringValue("JohnJohn"), []string{"profile.name", "profile.name"}},
+		{"{...profile}.name", StringValue("John"), []string{"profile"}},
+		{"profile?.name ?? 'none'", StringValue("John"), []string{"profile.name"}},
+		{"profile?.orders[0].id", NumberValue(1), []string{"profile"}},
+		{"profile.manager?.orders[0].id", nil, []string{"profile.manager"}},
+		{"call(p => p.name, profile)", StringValue("John"), []string{"profile"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			for _, compiled := range []bool{false, true} {
+				var loads []string
+				ctx := vmContext()
+				ctx.SetVariable("profile", mockLazy{data: data, path: []string{"profile"}, loads: &loads})
+				var result Value
+				var err error
+				if node := mustParse(t, tt.input); compiled {
+					result, err = Compile(node).Evaluate(ctx)
+				} else {
+					result, err = node.Evaluate(ctx)
+				}
+				if err != nil {
+					t.Fatalf("evaluation error: %v", err)
+				}
+				if !reflect.DeepEqual(result, tt.expected) {
+					t.Errorf("expected %v, got %v", tt.expected, result)
+				}
+				if !reflect.DeepEqual(loads, tt.loads) {
+					t.Errorf("expected loads %v, got %v", tt.loads, loads)
+				}
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		var loads []string
+		ctx := NewMockContext()
+		ctx.SetVariable("profile", mockLazy{data: data, path: []string{"profile"}, loads: &loads})
+		node := mustParse(t, "1 + profile.missing")
+		for _, evaluate := range []func(Context) (Value, error){node.Evaluate, Compile(node).Evaluate} {
+			_, err := evaluate(ctx)
+			var evalErr *EvalError
+			if !errors.As(err, &evalErr) || evalErr.Span != (Span{Start: 4, End: 19}) || err.Error() != "profile.missing not found" {
+				t.Errorf("expected an error at 4-19, got %#v", err)
+			}
+		}
+	})
+}
+
 func TestIndexAccessNode(t *testing.T) {
 	ctx := NewMockContext()
 
@@ -348,6 +660,20 @@ func TestIndexAccessNode(t *testing.T) {
 			StringValue("value"),
 			false,
 		},
+		{
+			"object string index",
+			mockObject{"key": StringValue("value")},
+			StringValue("key"),
+			StringValue("value"),
+			false,
+		},
+		{
+			"object number index",
+			mockObject{"key": StringValue("value")},
+			NumberValue(0),
+			nil,
+			true,
+		},
 		{
 			"list each index",
 			ListValue{NumberValue(1), NumberValue(2), NumberValue(3)},
@@ -412,6 +738,126 @@ func TestIndexAccessNodeUnsupportedTypes(t *testing.T) {
 	}
 }
 
+func TestOptionalAccess(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetVariable("ns", ctx)
+	ctx.SetFunction("one", func(args []Value) (Value, error) {
+		return NumberValue(1), nil
+	})
+	null := &LiteralNode{Value: nil}
+
+	tests := []struct {
+		name      string
+		node      ExprNode
+		expected  Value
+		expectErr bool
+	}{
+		{"optional index on null", &IndexAccessNode{Object: null, Index: &LiteralNode{Value: NumberValue(0)}, Optional: true}, nil, false},
+		{"optional slice on null", &IndexAccessNode{Object: null, Index: &RangeNode{}, Optional: true}, nil, false},
+		{"index on null", &IndexAccessNode{Object: null, Index: &LiteralNode{Value: NumberValue(0)}}, nil, true},
+		{"optional index on list", &IndexAccessNode{Object: &LiteralNode{Value: ListValue{NumberValue(7)}}, Index: &LiteralNode{Value: NumberValue(0)}, Optional: true}, NumberValue(7), false},
+		{"optional field on null", &FieldAccessNode{Object: null, Field: "x", Optional: true}, nil, false},
+		{"optional call on null namespace", &FunctionCallNode{Namespace: null, Name: "one", Args: []ExprNode{}, Optional: true}, nil, false},
+		{"call on null namespace", &FunctionCallNode{Namespace: null, Name: "one", Args: []ExprNode{}}, nil, true},
+		{"optional call on namespace", &FunctionCallNode{Namespace: &VariableNode{Name: "ns"}, Name: "one", Args: []ExprNode{}, Optional: true}, NumberValue(1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.node.Evaluate(ctx)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	// An optional access that finds null skips the rest of its chain
+	ctx.SetVariable("payload", nil)
+	ctx.SetVariable("user", MapValue{"name": StringValue("John")})
+	chains := []struct {
+		input     string
+		expected  Value
+		expectErr bool
+	}{
+		{"payload?.items[0].id", nil, false},
+		{"payload?.[0].items.id[1]", nil, false},
+		{"payload?.ns.one()[0]", nil, false},
+		{"payload?.items[0] ?? 'none'", StringValue("none"), false},
+		{"user?.name[0:1]", StringValue("J"), false},
+		{"user?.items[0]", nil, true},
+		{"payload.items[0]", nil, true},
+	}
+	for _, tt := range chains {
+		t.Run(tt.input, func(t *testing.T) {
+			node := mustParse(t, tt.input)
+			for _, evaluate := range []func(Context) (Value, error){node.Evaluate, Compile(node).Evaluate} {
+				result, err := evaluate(ctx)
+				if tt.expectErr {
+					if err == nil {
+						t.Errorf("expected error but got %v", result)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !equal(result, tt.expected) {
+					t.Errorf("expected %v, got %v", tt.expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestCoalesceOperator(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetFunction("fail", func(args []Value) (Value, error) {
+		return nil, fmt.Errorf("right operand should not be evaluated")
+	})
+
+	tests := []struct {
+		name     string
+		left     Value
+		right    ExprNode
+		expected Value
+	}{
+		{"null left", nil, &LiteralNode{Value: StringValue("default")}, StringValue("default")},
+		{"falsy left is kept", BoolValue(false), &FunctionCallNode{Name: "fail", Args: []ExprNode{}}, BoolValue(false)},
+		{"zero left is kept", NumberValue(0), &FunctionCallNode{Name: "fail", Args: []ExprNode{}}, NumberValue(0)},
+		{"both null", nil, &LiteralNode{Value: nil}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &BinaryOpNode{
+				Left:     &LiteralNode{Value: tt.left},
+				Right:    tt.right,
+				Operator: "??",
+			}
+
+			result, err := node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestFunctionCallNode(t *testing.T) {
 	ctx := NewMockContext()
 
@@ -563,6 +1009,133 @@ func TestListNode(t *testing.T) {
 	}
 }
 
+func TestTemplateNode(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetVariable("user", MapValue{"id": NumberValue(7), "name": StringValue("john")})
+	literal := func(v Value) ExprNode { return &LiteralNode{Value: v} }
+
+	tests := []struct {
+		name     string
+		parts    []ExprNode
+		expected Value
+	}{
+		{"empty", []ExprNode{}, StringValue("")},
+		{"literal only", []ExprNode{literal(StringValue("hello"))}, StringValue("hello")},
+		{
+			"mixed parts",
+			[]ExprNode{
+				literal(StringValue("user:")),
+				&FieldAccessNode{Object: &VariableNode{Name: "user"}, Field: "id"},
+				literal(StringValue(":")),
+				&FieldAccessNode{Object: &VariableNode{Name: "user"}, Field: "name"},
+			},
+			StringValue("user:7:john"),
+		},
+		{"null part", []ExprNode{literal(StringValue("value=")), literal(nil)}, StringValue("value=null")},
+		{"bool part", []ExprNode{literal(BoolValue(false))}, StringValue("false")},
+		{"fraction part", []ExprNode{literal(NumberValue(0.25))}, StringValue("0.25")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &TemplateNode{Parts: tt.parts}
+			result, err := node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMapNode(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetVariable("base", MapValue{"a": NumberValue(1), "b": NumberValue(2)})
+	ctx.SetVariable("key", StringValue("dynamic"))
+	literal := func(v Value) ExprNode { return &LiteralNode{Value: v} }
+
+	tests := []struct {
+		name      string
+		entries   []MapEntry
+		expected  Value
+		expectErr bool
+	}{
+		{"empty map", []MapEntry{}, MapValue{}, false},
+		{
+			"literal keys",
+			[]MapEntry{
+				{Key: literal(StringValue("name")), Value: literal(StringValue("John"))},
+				{Key: literal(StringValue("age")), Value: literal(NumberValue(30))},
+			},
+			MapValue{"name": StringValue("John"), "age": NumberValue(30)},
+			false,
+		},
+		{
+			"computed key",
+			[]MapEntry{{Key: &VariableNode{Name: "key"}, Value: literal(BoolValue(true))}},
+			MapValue{"dynamic": BoolValue(true)},
+			false,
+		},
+		{
+			"number key",
+			[]MapEntry{{Key: literal(NumberValue(1)), Value: literal(StringValue("one"))}},
+			MapValue{"1": StringValue("one")},
+			false,
+		},
+		{
+			"spread with override",
+			[]MapEntry{
+				{Value: &VariableNode{Name: "base"}, Spread: true},
+				{Key: literal(StringValue("b")), Value: literal(NumberValue(3))},
+			},
+			MapValue{"a": NumberValue(1), "b": NumberValue(3)},
+			false,
+		},
+		{
+			"spread null",
+			[]MapEntry{{Value: literal(nil), Spread: true}},
+			MapValue{},
+			false,
+		},
+		{
+			"spread non-map",
+			[]MapEntry{{Value: literal(ListValue{}), Spread: true}},
+			nil,
+			true,
+		},
+		{
+			"null key",
+			[]MapEntry{{Key: literal(nil), Value: literal(NumberValue(1))}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &MapNode{Entries: tt.entries}
+			result, err := node.Evaluate(ctx)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestEachNode(t *testing.T) {
 	ctx := NewMockContext()
 	node := &EachNode{}
@@ -579,9 +1152,14 @@ func TestEachNode(t *testing.T) {
 
 func TestRangeNode(t *testing.T) {
 	ctx := NewMockContext()
+	ctx.SetVariable("i", NumberValue(2))
 	node := &RangeNode{
-		Begin: NumberValue(1),
-		End:   NumberValue(10),
+		Begin: &VariableNode{Name: "i"},
+		End: &BinaryOpNode{
+			Left:     &VariableNode{Name: "i"},
+			Right:    &LiteralNode{Value: NumberValue(3)},
+			Operator: "+",
+		},
 	}
 
 	result, err := node.Evaluate(ctx)
@@ -589,8 +1167,284 @@ func TestRangeNode(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if _, ok := result.(EachValue); !ok {
-		t.Errorf("expected EachValue, got %T", result)
+	r, ok := result.(RangeValue)
+	if !ok {
+		t.Fatalf("expected RangeValue, got %T", result)
+	}
+	if !equal(r.Begin, NumberValue(2)) || !equal(r.End, NumberValue(5)) || r.Step != nil {
+		t.Errorf("unexpected range %v", r)
+	}
+}
+
+func TestIndexAccessNodeSlice(t *testing.T) {
+	ctx := NewMockContext()
+	list := ListValue{NumberValue(0), NumberValue(1), NumberValue(2), NumberValue(3), NumberValue(4)}
+	num := func(v float64) ExprNode { return &LiteralNode{Value: NumberValue(v)} }
+
+	tests := []struct {
+		name      string
+		object    Value
+		index     *RangeNode
+		expected  Value
+		expectErr bool
+	}{
+		{"begin and end", list, &RangeNode{Begin: num(1), End: num(3)}, ListValue{NumberValue(1), NumberValue(2)}, false},
+		{"omitted begin", list, &RangeNode{End: num(2)}, ListValue{NumberValue(0), NumberValue(1)}, false},
+		{"omitted end", list, &RangeNode{Begin: num(3)}, ListValue{NumberValue(3), NumberValue(4)}, false},
+		{"full copy", list, &RangeNode{}, list, false},
+		{"negative begin", list, &RangeNode{Begin: num(-2)}, ListValue{NumberValue(3), NumberValue(4)}, false},
+		{"negative end", list, &RangeNode{End: num(-3)}, ListValue{NumberValue(0), NumberValue(1)}, false},
+		{"step", list, &RangeNode{Step: num(2)}, ListValue{NumberValue(0), NumberValue(2), NumberValue(4)}, false},
+		{"negative step", list, &RangeNode{Step: num(-1)}, ListValue{NumberValue(4), NumberValue(3), NumberValue(2), NumberValue(1), NumberValue(0)}, false},
+		{"empty when begin after end", list, &RangeNode{Begin: num(3), End: num(1)}, ListValue{}, false},
+		{"string", StringValue("héllo"), &RangeNode{Begin: num(1), End: num(3)}, StringValue("él"), false},
+		{"string reversed", StringValue("abc"), &RangeNode{Step: num(-1)}, StringValue("cba"), false},
+		{"begin out of range", list, &RangeNode{Begin: num(6)}, nil, true},
+		{"end out of range", list, &RangeNode{End: num(-6)}, nil, true},
+		{"zero step", list, &RangeNode{Step: num(0)}, nil, true},
+		{"non-number bound", list, &RangeNode{Begin: &LiteralNode{Value: StringValue("a")}}, nil, true},
+		{"map object", MapValue{}, &RangeNode{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &IndexAccessNode{
+				Object: &LiteralNode{Value: tt.object},
+				Index:  tt.index,
+			}
+
+			result, err := node.Evaluate(ctx)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestConditionalNode(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetFunction("fail", func(args []Value) (Value, error) {
+		return nil, fmt.Errorf("branch should not be evaluated")
+	})
+	fail := &FunctionCallNode{Name: "fail", Args: []ExprNode{}}
+
+	tests := []struct {
+		name      string
+		condition Value
+		then      ExprNode
+		els       ExprNode
+		expected  Value
+	}{
+		{"true condition", BoolValue(true), &LiteralNode{Value: StringValue("yes")}, fail, StringValue("yes")},
+		{"false condition", BoolValue(false), fail, &LiteralNode{Value: StringValue("no")}, StringValue("no")},
+		{"truthy condition", NumberValue(1), &LiteralNode{Value: StringValue("yes")}, fail, StringValue("yes")},
+		{"nil condition", nil, fail, &LiteralNode{Value: StringValue("no")}, StringValue("no")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &ConditionalNode{
+				Condition: &LiteralNode{Value: tt.condition},
+				Then:      tt.then,
+				Else:      tt.els,
+			}
+
+			result, err := node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCaseNode(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetFunction("fail", func(args []Value) (Value, error) {
+		return nil, fmt.Errorf("branch should not be evaluated")
+	})
+	fail := &FunctionCallNode{Name: "fail", Args: []ExprNode{}}
+	literal := func(v Value) ExprNode { return &LiteralNode{Value: v} }
+
+	tests := []struct {
+		name     string
+		node     *CaseNode
+		expected Value
+	}{
+		{
+			"searched case first match",
+			&CaseNode{Whens: []WhenClause{
+				{Condition: literal(BoolValue(false)), Result: fail},
+				{Condition: literal(BoolValue(true)), Result: literal(StringValue("second"))},
+				{Condition: fail, Result: fail},
+			}, Else: fail},
+			StringValue("second"),
+		},
+		{
+			"searched case else",
+			&CaseNode{Whens: []WhenClause{
+				{Condition: literal(BoolValue(false)), Result: fail},
+			}, Else: literal(StringValue("fallback"))},
+			StringValue("fallback"),
+		},
+		{
+			"searched case without else",
+			&CaseNode{Whens: []WhenClause{
+				{Condition: literal(BoolValue(false)), Result: fail},
+			}},
+			nil,
+		},
+		{
+			"simple case",
+			&CaseNode{Subject: literal(StringValue("b")), Whens: []WhenClause{
+				{Condition: literal(StringValue("a")), Result: fail},
+				{Condition: literal(StringValue("b")), Result: literal(NumberValue(2))},
+			}, Else: fail},
+			NumberValue(2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.node.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLambdaNode(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetVariable("offset", NumberValue(10))
+	ctx.SetVariable("a", StringValue("shadowed"))
+
+	node := &LambdaNode{
+		Params: []string{"a", "b"},
+		Body: &BinaryOpNode{
+			Left: &BinaryOpNode{
+				Left:     &VariableNode{Name: "a"},
+				Right:    &VariableNode{Name: "b"},
+				Operator: "+",
+			},
+			Right:    &VariableNode{Name: "offset"},
+			Operator: "+",
+		},
+	}
+
+	result, err := node.Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fn, ok := result.(FunctionValue)
+	if !ok {
+		t.Fatalf("expected FunctionValue, got %T", result)
+	}
+
+	tests := []struct {
+		name     string
+		args     []Value
+		expected Value
+	}{
+		{"all arguments", []Value{NumberValue(1), NumberValue(2)}, NumberValue(13)},
+		{"extra arguments ignored", []Value{NumberValue(1), NumberValue(2), NumberValue(3)}, NumberValue(13)},
+		{"missing arguments are nil", []Value{NumberValue(1)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !equal(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	if !equal(ctx.GetVariable("a"), StringValue("shadowed")) {
+		t.Error("lambda parameters must not leak into the enclosing context")
+	}
+}
+
+func TestFunctionCallNodeFunctionValue(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetVariable("double", FunctionValue(func(args []Value) (Value, error) {
+		return NumberValue(ToNumber(args[0]) * 2), nil
+	}))
+
+	node := &FunctionCallNode{
+		Name: "double",
+		Args: []ExprNode{&LiteralNode{Value: NumberValue(21)}},
+	}
+
+	result, err := node.Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !equal(result, NumberValue(42)) {
+		t.Errorf("expected 42, got %v", result)
+	}
+}
+
+// mockMapObject is a MapObject over a map.
+type mockMapObject MapValue
+
+func (o mockMapObject) Field(name string) Value { return o[name] }
+func (o mockMapObject) Map() MapValue {
+	out := make(MapValue, len(o))
+	for k, v := range o {
+		out[k] = v
+	}
+	return out
+}
+
+func TestToMap(t *testing.T) {
+	fields := MapValue{"a": NumberValue(1)}
+	for _, value := range []Value{fields, mockMapObject(fields)} {
+		if m, ok := ToMap(value); !ok || !reflect.DeepEqual(m, fields) {
+			t.Errorf("expected %v, got %v", fields, m)
+		}
+	}
+	for _, value := range []Value{nil, ListValue{}, mockLazy{}} {
+		if _, ok := ToMap(value); ok {
+			t.Errorf("expected %v not to be a map", value)
+		}
+	}
+	if TypeOf(mockMapObject(fields)) != MapType {
+		t.Errorf("expected a map object to be typed map")
+	}
+
+	node := mustParse(t, "{...object, b: 2}")
+	ctx := NewMockContext()
+	ctx.SetVariable("object", mockMapObject(fields))
+	for _, evaluate := range []func(Context) (Value, error){node.Evaluate, Compile(node).Evaluate} {
+		result, err := evaluate(ctx)
+		if err != nil || !reflect.DeepEqual(result, MapValue{"a": NumberValue(1), "b": NumberValue(2)}) {
+			t.Errorf("expected the object to be spread, got %v (%v)", result, err)
+		}
 	}
 }
 
@@ -666,6 +1520,9 @@ func TestEqual(t *testing.T) {
 		{"both nil", nil, nil, true},
 		{"nil and value", nil, NumberValue(0), false},
 		{"different types", NumberValue(42), StringValue("42"), false},
+		{"NaN", NumberValue(math.NaN()), NumberValue(math.NaN()), true},
+		{"negative zero", NumberValue(math.Copysign(0, -1)), NumberValue(0), false},
+		{"equal lists", ListValue{NumberValue(1)}, ListValue{NumberValue(1)}, true},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,572 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// Bytecode is an expression compiled for a stack based virtual machine.
+	// Evaluating it gives the same results and errors as evaluating the tree
+	// it was compiled from, without walking the tree. It is safe for
+	// concurrent use.
+	// Code only ever jumps forward, so every instruction runs at most once
+	// per run and each call has its own arguments. Runs are charged for all
+	// of their instructions up front, attributed to span.
+	Bytecode struct {
+		span      Span
+		arguments int
+		code      []instruction
+		constants []Value
+		names     []string
+		nodes     []ExprNode
+		lambdas   []*lambda
+		deferred  []deferred
+	}
+	// lambda is the compiled body of a LambdaNode.
+	lambda struct {
+		params int
+		span   Span
+		body   *Bytecode
+	}
+	// deferred is a node the compiler does not know, evaluated by walking
+	// it with the lambda parameters in scope at that point.
+	deferred struct {
+		node   ExprNode
+		scopes [][]string
+	}
+	instruction struct {
+		op      opcode
+		a, b, c int32
+	}
+	opcode uint8
+	// compiler compiles the body of the expression or of a lambda. scopes
+	// holds the parameters of the enclosing lambdas, innermost last.
+	compiler struct {
+		out    *Bytecode
+		scopes [][]string
+	}
+)
+
+const (
+	opConst         opcode = iota // push constants[a], checking its size for nodes[b-1] unless b is 0
+	opVar                         // push the variable names[a], read once per run, resolved for nodes[b-1] unless b is 0
+	opLocal                       // push parameter b of the lambda a levels up, resolved for nodes[c-1] unless c is 0
+	opPop                         // drop the top of the stack
+	opJump                        // continue at a
+	opJumpIfFalse                 // pop, and continue at a if falsy
+	opJumpIfNotNull               // continue at a if the top is not null, pop it otherwise
+	opJumpIfNull                  // continue at a if the top is null
+	opLogical                     // `and` (b = 0) or `or` (b = 1) decided by the left operand: continue at a
+	opLogicalEnd                  // combine both operands of `and` or `or`
+	opBinary                      // apply the BinaryOpNode nodes[a], with fast path c, to the top two values
+	opBinaryConst                 // as opBinary, with constants[b] as the right operand
+	opUnary                       // apply the UnaryOpNode nodes[a] to the top
+	opInSet                       // apply the InSetNode nodes[a] to the top
+	opField                       // replace the top with its field names[a], resolved for nodes[b-1] unless b is 0
+	opIndex                       // apply the IndexAccessNode nodes[a] to the top two values
+	opFunction                    // push the function of the FunctionCallNode nodes[b], or false and continue at a
+	opMethod                      // as opFunction, looking the function up in the namespace on top
+	opCall                        // call the function below the top a values with them as arguments, at offset c of the arguments
+	opList                        // replace the top a values with a list of them, built by the ListNode nodes[b]
+	opTemplate                    // replace the top a values with their concatenation, built by the TemplateNode nodes[b]
+	opMap                         // push an empty map of size a
+	opMapSet                      // pop a key and a value into the map below them
+	opMapSpread                   // pop a map and copy its entries into the map below it
+	opRange                       // replace the top three values with a range
+	opCaseMatch                   // pop a when value, and continue at a if it differs from the case subject on top; pop the subject otherwise
+	opLambda                      // push a function running lambdas[a]
+	opEval                        // push the result of evaluating deferred[a]
+)
+
+// Operators of opBinary with a fast path, in its c operand.
+const (
+	opcodeNone int32 = iota
+	opcodeEqual
+	opcodeNotEqual
+	opcodeAdd
+	opcodeSub
+	opcodeMul
+	opcodeDiv
+	opcodeLess
+	opcodeLessEqual
+	opcodeGreater
+	opcodeGreaterEqual
+)
+
+var fastOperators = map[string]int32{
+	"=":  opcodeEqual,
+	"==": opcodeEqual,
+	"!=": opcodeNotEqual,
+	"+":  opcodeAdd,
+	"-":  opcodeSub,
+	"*":  opcodeMul,
+	"/":  opcodeDiv,
+	"<":  opcodeLess,
+	"<=": opcodeLessEqual,
+	">":  opcodeGreater,
+	">=": opcodeGreaterEqual,
+}
+
+var opcodeNames = [...]string{
+	opConst:         "const",
+	opVar:           "var",
+	opLocal:         "local",
+	opPop:           "pop",
+	opJump:          "jump",
+	opJumpIfFalse:   "jump_if_false",
+	opJumpIfNotNull: "jump_if_not_null",
+	opJumpIfNull:    "jump_if_null",
+	opLogical:       "logical",
+	opLogicalEnd:    "logical_end",
+	opBinary:        "binary",
+	opBinaryConst:   "binary_const",
+	opUnary:         "unary",
+	opInSet:         "in_set",
+	opField:         "field",
+	opIndex:         "index",
+	opFunction:      "function",
+	opMethod:        "method",
+	opCall:          "call",
+	opList:          "list",
+	opTemplate:      "template",
+	opMap:           "map",
+	opMapSet:        "map_set",
+	opMapSpread:     "map_spread",
+	opRange:         "range",
+	opCaseMatch:     "case_match",
+	opLambda:        "lambda",
+	opEval:          "eval",
+}
+
+// Compile compiles node to Bytecode. Nodes from outside this package are
+// evaluated by walking them, as are nodes whose evaluation always fails.
+// Bytecode saves the most on arithmetic and lambdas. Expressions that only
+// build lists, maps and templates allocate as much either way, and run a
+// little slower as bytecode for its fixed setup; BenchmarkBytecode measures
+// both.
+func Compile(node ExprNode) *Bytecode {
+	c := &compiler{out: &Bytecode{span: SpanOf(node)}}
+	c.compile(node)
+	return c.out
+}
+
+// String disassembles the bytecode, one instruction per line, followed by
+// the bytecode of its lambdas.
+func (b *Bytecode) String() string {
+	var sb strings.Builder
+	b.disassemble(&sb, "")
+	return sb.String()
+}
+
+func (b *Bytecode) disassemble(sb *strings.Builder, indent string) {
+	for pc, in := range b.code {
+		fmt.Fprintf(sb, "%s%04d %s", indent, pc, opcodeNames[in.op])
+		switch in.op {
+		case opConst:
+			{
+				fmt.Fprintf(sb, " %s", formatValue(b.constants[in.a]))
+			}
+		case opVar, opField:
+			{
+				fmt.Fprintf(sb, " %s", b.names[in.a])
+				if in.b == 0 {
+					sb.WriteString(" ref")
+				}
+			}
+		case opLocal:
+			{
+				fmt.Fprintf(sb, " %d %d", in.a, in.b)
+				if in.c == 0 {
+					sb.WriteString(" ref")
+				}
+			}
+		case opJump, opJumpIfFalse, opJumpIfNotNull, opJumpIfNull, opCaseMatch:
+			{
+				fmt.Fprintf(sb, " %04d", in.a)
+			}
+		case opLogical:
+			{
+				fmt.Fprintf(sb, " %s %04d", logicalName(in.b), in.a)
+			}
+		case opLogicalEnd:
+			{
+				fmt.Fprintf(sb, " %s", logicalName(in.b))
+			}
+		case opBinary:
+			{
+				fmt.Fprintf(sb, " %s", b.nodes[in.a].(*BinaryOpNode).Operator)
+			}
+		case opBinaryConst:
+			{
+				fmt.Fprintf(sb, " %s %s", b.nodes[in.a].(*BinaryOpNode).Operator, formatValue(b.constants[in.b]))
+			}
+		case opUnary:
+			{
+				fmt.Fprintf(sb, " %s", b.nodes[in.a].(*UnaryOpNode).Operator)
+			}
+		case opFunction, opMethod:
+			{
+				fmt.Fprintf(sb, " %s %04d", b.nodes[in.b].(*FunctionCallNode).Name, in.a)
+			}
+		case opInSet, opIndex, opMapSet, opMapSpread:
+			{
+			}
+		case opEval:
+			{
+				fmt.Fprintf(sb, " %T", b.deferred[in.a].node)
+			}
+		default:
+			{
+				fmt.Fprintf(sb, " %d", in.a)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	for i, lambda := range b.lambdas {
+		fmt.Fprintf(sb, "%slambda %d (%d params):\n", indent, i, lambda.params)
+		lambda.body.disassemble(sb, indent+"    ")
+	}
+}
+
+func logicalName(decisive int32) string {
+	if decisive == 1 {
+		return "or"
+	}
+	return "and"
+}
+
+func formatValue(value Value) string {
+	if value == EachValue(0) {
+		return "?"
+	}
+	p := &printer{}
+	p.literal(value)
+	return p.sb.String()
+}
+
+func (c *compiler) emit(op opcode, a, b, c2 int32) int {
+	c.out.code = append(c.out.code, instruction{op: op, a: a, b: b, c: c2})
+	return len(c.out.code) - 1
+}
+
+// patch makes the jump at pc continue at the next instruction emitted.
+func (c *compiler) patch(pc int) {
+	c.out.code[pc].a = int32(len(c.out.code))
+}
+
+func (c *compiler) constant(value Value) {
+	c.out.constants = append(c.out.constants, value)
+	c.emit(opConst, int32(len(c.out.constants)-1), 0, 0)
+}
+
+// literal compiles a literal. Strings, lists and maps folded by Optimize
+// may be larger than the limits of the evaluation, so their size is checked
+// when they are pushed.
+func (c *compiler) literal(n *LiteralNode) {
+	switch n.Value.(type) {
+	case StringValue, ListValue, MapValue:
+		{
+			c.out.constants = append(c.out.constants, n.Value)
+			c.emit(opConst, int32(len(c.out.constants)-1), c.node(n)+1, 0)
+		}
+	default:
+		{
+			c.constant(n.Value)
+		}
+	}
+}
+
+func (c *compiler) name(name string) int32 {
+	for i, existing := range c.out.names {
+		if existing == name {
+			return int32(i)
+		}
+	}
+	c.out.names = append(c.out.names, name)
+	return int32(len(c.out.names) - 1)
+}
+
+func (c *compiler) node(node ExprNode) int32 {
+	c.out.nodes = append(c.out.nodes, node)
+	return int32(len(c.out.nodes) - 1)
+}
+
+// local resolves name to a parameter of an enclosing lambda, as the number
+// of lambdas up from the innermost one and the index of the parameter.
+// When a lambda declares a parameter twice, the last one wins.
+func (c *compiler) local(name string) (int32, int32, bool) {
+	for depth := 0; depth < len(c.scopes); depth++ {
+		params := c.scopes[len(c.scopes)-1-depth]
+		for i := len(params) - 1; i >= 0; i-- {
+			if params[i] == name {
+				return int32(depth), int32(i), true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// access compiles node as a link of an access chain, as
+// FieldAccessNode.access evaluates it, resolving a Lazy variable or field
+// if resolve is set. It returns the jumps of the optional accesses in the
+// chain, which skip the rest of the chain leaving null on the stack.
+func (c *compiler) access(node ExprNode, resolve, strict bool) []int {
+	switch n := node.(type) {
+	case *VariableNode:
+		{
+			node := int32(0)
+			if resolve {
+				node = c.node(n) + 1
+			}
+			if depth, index, ok := c.local(n.Name); ok {
+				c.emit(opLocal, depth, index, node)
+				return nil
+			}
+			c.emit(opVar, c.name(n.Name), node, 0)
+			return nil
+		}
+	case *FieldAccessNode:
+		{
+			var jumps []int
+			if n.Optional {
+				jumps = append(c.access(n.Object, strict, false), c.emit(opJumpIfNull, 0, 0, 0))
+			} else {
+				jumps = c.access(n.Object, false, strict)
+			}
+			node := int32(0)
+			if resolve {
+				node = c.node(n) + 1
+			}
+			c.emit(opField, c.name(n.Field), node, 0)
+			return jumps
+		}
+	case *IndexAccessNode:
+		{
+			jumps := c.access(n.Object, true, !n.Optional)
+			if n.Optional {
+				jumps = append(jumps, c.emit(opJumpIfNull, 0, 0, 0))
+			}
+			c.compile(n.Index)
+			c.emit(opIndex, c.node(n), 0, 0)
+			return jumps
+		}
+	case *FunctionCallNode:
+		{
+			var jumps []int
+			if n.Namespace != nil {
+				jumps = c.access(n.Namespace, true, !n.Optional)
+				if n.Optional {
+					jumps = append(jumps, c.emit(opJumpIfNull, 0, 0, 0))
+				}
+			}
+			c.call(n)
+			return jumps
+		}
+	default:
+		{
+			c.compile(node)
+			return nil
+		}
+	}
+}
+
+func (c *compiler) optional(node ExprNode) {
+	if node == nil {
+		c.constant(nil)
+		return
+	}
+	c.compile(node)
+}
+
+func (c *compiler) compile(node ExprNode) {
+	switch n := node.(type) {
+	case *LiteralNode:
+		{
+			c.literal(n)
+		}
+	case *EachNode:
+		{
+			c.constant(EachValue(0))
+		}
+	case *VariableNode, *FieldAccessNode, *IndexAccessNode, *FunctionCallNode:
+		{
+			for _, jump := range c.access(node, true, false) {
+				c.patch(jump)
+			}
+		}
+	case *BinaryOpNode:
+		{
+			c.binary(n)
+		}
+	case *UnaryOpNode:
+		{
+			c.compile(n.Operand)
+			c.emit(opUnary, c.node(n), 0, 0)
+		}
+	case *InSetNode:
+		{
+			c.compile(n.Operand)
+			c.emit(opInSet, c.node(n), 0, 0)
+		}
+	case *ListNode:
+		{
+			for _, element := range n.Elements {
+				c.compile(element)
+			}
+			c.emit(opList, int32(len(n.Elements)), c.node(n), 0)
+		}
+	case *TemplateNode:
+		{
+			for _, part := range n.Parts {
+				c.compile(part)
+			}
+			c.emit(opTemplate, int32(len(n.Parts)), c.node(n), 0)
+		}
+	case *MapNode:
+		{
+			c.emit(opMap, int32(len(n.Entries)), 0, 0)
+			index := c.node(n)
+			for _, entry := range n.Entries {
+				c.compile(entry.Value)
+				if entry.Spread {
+					c.emit(opMapSpread, index, 0, 0)
+					continue
+				}
+				c.compile(entry.Key)
+				c.emit(opMapSet, index, 0, 0)
+			}
+		}
+	case *RangeNode:
+		{
+			c.optional(n.Begin)
+			c.optional(n.End)
+			c.optional(n.Step)
+			c.emit(opRange, 0, 0, 0)
+		}
+	case *ConditionalNode:
+		{
+			c.compile(n.Condition)
+			otherwise := c.emit(opJumpIfFalse, 0, 0, 0)
+			c.compile(n.Then)
+			end := c.emit(opJump, 0, 0, 0)
+			c.patch(otherwise)
+			c.optional(n.Else)
+			c.patch(end)
+		}
+	case *CaseNode:
+		{
+			c.caseExpr(n)
+		}
+	case *LambdaNode:
+		{
+			scopes := make([][]string, len(c.scopes), len(c.scopes)+1)
+			copy(scopes, c.scopes)
+			body := &compiler{out: &Bytecode{span: SpanOf(n.Body)}, scopes: append(scopes, n.Params)}
+			body.compile(n.Body)
+			c.out.lambdas = append(c.out.lambdas, &lambda{params: len(n.Params), span: n.Span, body: body.out})
+			c.emit(opLambda, int32(len(c.out.lambdas)-1), 0, 0)
+		}
+	default:
+		{
+			c.out.deferred = append(c.out.deferred, deferred{node: node, scopes: c.scopes})
+			c.emit(opEval, int32(len(c.out.deferred)-1), 0, 0)
+		}
+	}
+}
+
+func (c *compiler) binary(n *BinaryOpNode) {
+	c.compile(n.Left)
+	switch n.Operator {
+	case "and", "or":
+		{
+			decisive := int32(0)
+			if n.Operator == "or" {
+				decisive = 1
+			}
+			end := c.emit(opLogical, 0, decisive, 0)
+			c.compile(n.Right)
+			c.emit(opLogicalEnd, 0, decisive, 0)
+			c.patch(end)
+		}
+	case "??":
+		{
+			end := c.emit(opJumpIfNotNull, 0, 0, 0)
+			c.compile(n.Right)
+			c.patch(end)
+		}
+	default:
+		{
+			if right, ok := n.Right.(*LiteralNode); ok {
+				c.out.constants = append(c.out.constants, right.Value)
+				c.emit(opBinaryConst, c.node(n), int32(len(c.out.constants)-1), fastOperators[n.Operator])
+				return
+			}
+			c.compile(n.Right)
+			c.emit(opBinary, c.node(n), 0, fastOperators[n.Operator])
+		}
+	}
+}
+
+// call compiles a function call whose namespace, if any, is already on the
+// stack. The function is looked up before its arguments are evaluated, and
+// they are not evaluated at all when it does not exist.
+func (c *compiler) call(n *FunctionCallNode) {
+	var lookup int
+	if n.Namespace == nil {
+		local := int32(0)
+		if depth, index, ok := c.local(n.Name); ok {
+			local = depth<<16 | index + 1
+		}
+		lookup = c.emit(opFunction, 0, c.node(n), local)
+	} else {
+		lookup = c.emit(opMethod, 0, c.node(n), 0)
+	}
+	for _, arg := range n.Args {
+		c.compile(arg)
+	}
+	c.emit(opCall, int32(len(n.Args)), c.out.code[lookup].b, int32(c.out.arguments))
+	c.out.arguments += len(n.Args)
+	c.patch(lookup)
+}
+
+func (c *compiler) caseExpr(n *CaseNode) {
+	var ends []int
+	if n.Subject != nil {
+		c.compile(n.Subject)
+	}
+	for _, when := range n.Whens {
+		c.compile(when.Condition)
+		var next int
+		if n.Subject != nil {
+			next = c.emit(opCaseMatch, 0, 0, 0)
+		} else {
+			next = c.emit(opJumpIfFalse, 0, 0, 0)
+		}
+		c.compile(when.Result)
+		ends = append(ends, c.emit(opJump, 0, 0, 0))
+		c.patch(next)
+	}
+	if n.Subject != nil {
+		c.emit(opPop, 0, 0, 0)
+	}
+	c.optional(n.Else)
+	for _, end := range ends {
+		c.patch(end)
+	}
+}
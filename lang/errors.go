@@ -0,0 +1,202 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// SyntaxError is returned when an expression cannot be parsed. Offset
+	// is the byte offset of the offending token, Line and Column are its
+	// 1-based location and Expected lists the tokens the parser would have
+	// accepted instead, when they are known.
+	SyntaxError struct {
+		Message  string
+		Token    string
+		Offset   int
+		Line     int
+		Column   int
+		Expected []string
+		source   string
+		err      error
+	}
+	// EvalError is returned when evaluation fails. Span is the source span
+	// of the innermost node that failed and Stack lists the functions that
+	// were being called at the time, outermost first.
+	EvalError struct {
+		Span  Span
+		Stack []string
+		Err   error
+	}
+)
+
+// tokenNames maps the parser's token names to how they read in an
+// expression.
+var tokenNames = map[string]string{
+	"$end":       "end of input",
+	"IDENTIFIER": "identifier",
+	"STRING":     "string",
+	"DSTRING":    "string",
+	"TEMPLATE":   "template",
+	"NUMBER":     "number",
+	"BOOLEAN":    "boolean",
+	"AND":        "'and'",
+	"OR":         "'or'",
+	"NOT":        "'not'",
+	"IN":         "'in'",
+	"IS":         "'is'",
+	"NULL":       "'null'",
+	"EQ":         "'=='",
+	"NE":         "'!='",
+	"LT":         "'<'",
+	"LE":         "'<='",
+	"GT":         "'>'",
+	"GE":         "'>='",
+	"LPAREN":     "'('",
+	"RPAREN":     "')'",
+	"LBRACKET":   "'['",
+	"RBRACKET":   "']'",
+	"LBRACE":     "'{'",
+	"RBRACE":     "'}'",
+	"DOT":        "'.'",
+	"COMMA":      "','",
+	"COLON":      "':'",
+	"QMARK":      "'?'",
+	"ARROW":      "'=>'",
+	"QDOT":       "'?.'",
+	"QLBRACKET":  "'?.['",
+	"COALESCE":   "'??'",
+	"IDIV":       "'//'",
+	"POW":        "'**'",
+	"ELLIPSIS":   "'...'",
+	"CASE":       "'case'",
+	"WHEN":       "'when'",
+	"THEN":       "'then'",
+	"ELSE":       "'else'",
+	"END":        "'end'",
+	"ILLEGAL":    "invalid token",
+	"EOI":        "end of input",
+}
+
+// newSyntaxError builds a SyntaxError for the token at source[start:end].
+// Verbose parser messages of the form "syntax error: unexpected X,
+// expecting Y or Z" are split into the message and the expected tokens.
+func newSyntaxError(source string, start, end int, message string) *SyntaxError {
+	start = min(max(start, 0), len(source))
+	end = min(max(end, start), len(source))
+
+	out := &SyntaxError{Message: message, Offset: start, source: source}
+	if rest, ok := strings.CutPrefix(message, "syntax error: unexpected "); ok {
+		unexpected, expected, _ := strings.Cut(rest, ", expecting ")
+		out.Message = "syntax error: unexpected " + tokenName(unexpected)
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(expected, " or ") {
+			// EOI is not listed, as the parser never lists the end of
+			// input that follows it
+			if name == "" || name == "EOI" || seen[tokenName(name)] {
+				continue
+			}
+			seen[tokenName(name)] = true
+			out.Expected = append(out.Expected, tokenName(name))
+		}
+	}
+
+	switch {
+	case start < end:
+		out.Token = source[start:end]
+	case start < len(source):
+		_, size := utf8.DecodeRuneInString(source[start:])
+		out.Token = source[start : start+size]
+	default:
+		out.Token = "<EOF>"
+	}
+
+	lineStart := strings.LastIndexByte(source[:start], '\n') + 1
+	out.Line = strings.Count(source[:start], "\n") + 1
+	out.Column = utf8.RuneCountInString(source[lineStart:start]) + 1
+	return out
+}
+
+func tokenName(name string) string {
+	if out, ok := tokenNames[name]; ok {
+		return out
+	}
+	return name
+}
+
+func (e *SyntaxError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Message)
+	if len(e.Expected) > 0 {
+		sb.WriteString(", expecting ")
+		sb.WriteString(strings.Join(e.Expected, " or "))
+	}
+	fmt.Fprintf(&sb, " near token '%s' at line %d, column %d (position %d)", e.Token, e.Line, e.Column, e.Offset)
+
+	// Show the offending line with a pointer under the token
+	lineStart := strings.LastIndexByte(e.source[:e.Offset], '\n') + 1
+	lineEnd := strings.IndexByte(e.source[e.Offset:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(e.source)
+	} else {
+		lineEnd += e.Offset
+	}
+	sb.WriteString("\n")
+	sb.WriteString(e.source[lineStart:lineEnd])
+	sb.WriteString("\n")
+	for _, ch := range e.source[lineStart:e.Offset] {
+		if ch == '\t' {
+			sb.WriteByte('\t')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	sb.WriteString("^")
+	return sb.String()
+}
+
+// Unwrap returns ErrLimitExceeded for expressions nested too deep to
+// parse, and nil otherwise.
+func (e *SyntaxError) Unwrap() error {
+	return e.err
+}
+
+func (e *EvalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
+
+// evalErrorf returns an EvalError for the node spanning span.
+func evalErrorf(span Span, format string, args ...any) error {
+	return &EvalError{Span: span, Err: fmt.Errorf(format, args...)}
+}
+
+// evalError attributes err to the node spanning span, unless it was
+// already attributed to a node further down.
+func evalError(span Span, err error) error {
+	var evalErr *EvalError
+	if errors.As(err, &evalErr) {
+		return err
+	}
+	return &EvalError{Span: span, Err: err}
+}
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		message  string
+		token    string
+		offset   int
+		line     int
+		column   int
+		expected []string
+	}{
+		{"unexpected operator", "3 + +", "syntax error: unexpected '+'", "+", 4, 1, 5, nil},
+		{"expected token", "(1, 2", "syntax error: unexpected ','", ",", 2, 1, 3, []string{"')'"}},
+		{"end of input", "a.", "syntax error: unexpected end of input", "<EOF>", 2, 1, 3, []string{"identifier"}},
+		{"missing end", "case when a then 1", "syntax error: unexpected end of input", "<EOF>", 18, 1, 19, []string{"'end'"}},
+		{"second line", "a +\n  b c", "syntax error: unexpected identifier", "c", 8, 2, 5, nil},
+		{"unknown character", "a # b", "unexpected character '#'", "#", 2, 1, 3, nil},
+		{"unclosed string", "x + 'abc", "unclosed string", "'abc", 4, 1, 5, nil},
+		{"invalid escape", `'a\qb'`, "invalid escape sequence", `\q`, 2, 1, 3, nil},
+		{"malformed number", "1 + 0x", "malformed number '0x'", "0x", 4, 1, 5, nil},
+		{"lambda parameter", "(a + 1) => a", "syntax error: lambda parameters must be identifiers", "a + 1", 1, 1, 2, nil},
+		{"template placeholder", "`a ${b c}`", "invalid template placeholder: syntax error: unexpected identifier", "c", 7, 1, 8, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpression(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected SyntaxError, got %v", err)
+			}
+			if syntaxErr.Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, syntaxErr.Message)
+			}
+			if syntaxErr.Token != tt.token {
+				t.Errorf("expected token %q, got %q", tt.token, syntaxErr.Token)
+			}
+			if syntaxErr.Offset != tt.offset || syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("expected offset %d at %d:%d, got %d at %d:%d", tt.offset, tt.line, tt.column, syntaxErr.Offset, syntaxErr.Line, syntaxErr.Column)
+			}
+			if !reflect.DeepEqual(syntaxErr.Expected, tt.expected) {
+				t.Errorf("expected tokens %v, got %v", tt.expected, syntaxErr.Expected)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	_, err := ParseExpression("a +\n\tb c")
+	expected := "syntax error: unexpected identifier near token 'c' at line 2, column 4 (position 7)\n\tb c\n\t  ^"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		messages []string
+		offsets  []int
+	}{
+		{"valid", "a + 1", nil, nil},
+		{"two operators", "1 + + 2 * (3 +)", []string{"syntax error: unexpected '+'", "syntax error: unexpected ')'"}, []int{4, 14}},
+		{"call and list", "f(1,,2) + [3 +]", []string{"syntax error: unexpected ','", "syntax error: unexpected ']'"}, []int{4, 14}},
+		{"lexer errors", "1 + 0x + 2 $ 3", []string{"malformed number '0x'", "unexpected character '$'"}, []int{4, 11}},
+		{"map entry", "{a: , b: 1}", []string{"syntax error: unexpected ','"}, []int{4}},
+		{"unclosed", "f([1, (2", []string{"syntax error: unexpected end of input"}, []int{8}},
+		{"trailing tokens", "a b ) c", []string{"syntax error: unexpected identifier"}, []int{2}},
+		{
+			"template placeholders",
+			"`${1 +} ${2 *}`",
+			[]string{"invalid template placeholder: syntax error: unexpected end of input", "invalid template placeholder: syntax error: unexpected end of input"},
+			[]int{6, 13},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, errs := ParsePartial(tt.input)
+			if ast == nil {
+				t.Fatal("expected partial AST")
+			}
+			var messages []string
+			var offsets []int
+			for _, err := range errs {
+				messages = append(messages, err.Message)
+				offsets = append(offsets, err.Offset)
+			}
+			if !reflect.DeepEqual(messages, tt.messages) {
+				t.Errorf("expected errors %q, got %q", tt.messages, messages)
+			}
+			if !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("expected offsets %v, got %v", tt.offsets, offsets)
+			}
+		})
+	}
+}
+
+func TestPartialRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"trailing tokens", "a + + b and c ) d", "a + <invalid> + b and c"},
+		{"map entry", "{a: 1, b}", "{a: 1, ...<invalid>}"},
+		{"missing comma", "[1, 2 3, 4]", "[1, 2, <invalid>, 4]"},
+		{"argument", "f(a b, c)", "f(a, <invalid>, c)"},
+		{"parentheses", "(a b) + c", "a + c"},
+		{"unclosed list", "[1, 2", "[1, 2, <invalid>]"},
+		{"unclosed call", "f(1,, 2) + g(", "f(1, <invalid>, 2) + g(<invalid>)"},
+		{"unclosed map", "{a: 1", "{a: 1, ...<invalid>}"},
+		{"unclosed brackets", "((a) + [1, {b: (2", "a + [1, {b: 2}]"},
+		{"unclosed parentheses", "(((", "<invalid>"},
+		{"nothing", "", "<invalid>"},
+		{"missing branch", "a ? b", "<invalid>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, errs := ParsePartial(tt.input)
+			if len(errs) == 0 {
+				t.Fatal("expected errors")
+			}
+			if ast == nil {
+				t.Fatal("expected partial AST")
+			}
+			if actual := Format(ast); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPartialAST(t *testing.T) {
+	input := "user.age > and user.name == 'john'"
+	ast, errs := ParsePartial(input)
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+
+	and, ok := ast.(*BinaryOpNode)
+	if !ok || and.Operator != "and" {
+		t.Fatalf("expected 'and' at the root, got %#v", ast)
+	}
+	if _, ok := and.Right.(*BinaryOpNode); !ok {
+		t.Errorf("expected the valid operand to be kept, got %#v", and.Right)
+	}
+	comparison := and.Left.(*BinaryOpNode)
+	bad, ok := comparison.Right.(*BadNode)
+	if !ok {
+		t.Fatalf("expected BadNode, got %#v", comparison.Right)
+	}
+	if actual := input[bad.Span.Start:bad.Span.End]; actual != "and" {
+		t.Errorf("expected BadNode to span 'and', got %q", actual)
+	}
+
+	_, err := ast.Evaluate(NewMockContext())
+	var evalErr *EvalError
+	if !errors.As(err, &evalErr) || evalErr.Span != bad.Span {
+		t.Errorf("expected evaluation to fail at the BadNode, got %v", err)
+	}
+
+	if _, err := ParseExpression(input); err == nil || err.Error() != errs[0].Error() {
+		t.Errorf("expected ParseExpression to return the first error, got %v", err)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"variable", "  abc ", "abc"},
+		{"literal", "'a b'", "'a b'"},
+		{"binary", "1 + a * 2", "1 + a * 2"},
+		{"unary", "not a", "not a"},
+		{"is null", "a is not null", "a is not null"},
+		{"parenthesized", "(a + b).c", "(a + b).c"},
+		{"field", "a.b.c", "a.b.c"},
+		{"index", "a[1]", "a[1]"},
+		{"slice", "a[1:2]", "a[1:2]"},
+		{"function call", "f(1, 2)", "f(1, 2)"},
+		{"namespace call", "ns.f()", "ns.f()"},
+		{"list", "[1, 2]", "[1, 2]"},
+		{"map", "{a: 1}", "{a: 1}"},
+		{"template", "`a${b}`", "`a${b}`"},
+		{"conditional", "a ? b : c", "a ? b : c"},
+		{"case", "case when a then b end", "case when a then b end"},
+		{"lambda", "x => x + 1", "x => x + 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			span := SpanOf(ast)
+			if actual := tt.input[span.Start:span.End]; actual != tt.expected {
+				t.Errorf("expected span of %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNestedSpans(t *testing.T) {
+	input := "list[1:] + f(x, `t${y * 2}`)"
+	ast, err := ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	binary := ast.(*BinaryOpNode)
+	slice := binary.Left.(*IndexAccessNode)
+	call := binary.Right.(*FunctionCallNode)
+	template := call.Args[1].(*TemplateNode)
+
+	tests := []struct {
+		node     ExprNode
+		expected string
+	}{
+		{slice, "list[1:]"},
+		{slice.Index, "1:"},
+		{call, "f(x, `t${y * 2}`)"},
+		{call.Args[0], "x"},
+		{template, "`t${y * 2}`"},
+		{template.Parts[0], "t"},
+		{template.Parts[1], "y * 2"},
+	}
+	for _, tt := range tests {
+		span := SpanOf(tt.node)
+		if actual := input[span.Start:span.End]; actual != tt.expected {
+			t.Errorf("expected span of %q, got %q", tt.expected, actual)
+		}
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	ctx := NewMockContext()
+	ctx.SetVariable("items", ListValue{NumberValue(1)})
+	ctx.SetFunction("fail", func(args []Value) (Value, error) {
+		return nil, fmt.Errorf("fail: always fails")
+	})
+	ctx.SetFunction("apply", func(args []Value) (Value, error) {
+		result, err := args[0].(FunctionValue)([]Value{NumberValue(1)})
+		if err != nil {
+			return nil, fmt.Errorf("apply: %w", err)
+		}
+		return result, nil
+	})
+	ctx.SetFunction("identity", func(args []Value) (Value, error) {
+		return args[0], nil
+	})
+	ns := NewMockContext()
+	ns.SetFunction("fail", ctx.GetFunction("fail"))
+	ctx.SetVariable("ns", ns)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		stack    []string
+		message  string
+	}{
+		{"operator", "1 + (2 % 0)", "2 % 0", nil, "modulo by zero"},
+		{"index", "identity(items[5])", "items[5]", nil, "index 5 is out of range"},
+		{"slice", "items[::0]", "items[::0]", nil, "slice step cannot be zero"},
+		{"function", "identity(fail(1))", "fail(1)", []string{"fail"}, "fail: always fails"},
+		{"namespace function", "ns.fail()", "ns.fail()", []string{"ns.fail"}, "fail: always fails"},
+		{"lambda", "apply(x => x // 0)", "x // 0", []string{"apply"}, "apply: expectation failed: integer division by zero"},
+		{"nested lambda", "apply(x => apply(y => fail(y)))", "fail(y)", []string{"apply", "apply", "fail"}, "always fails"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			_, err = ast.Evaluate(ctx)
+			var evalErr *EvalError
+			if !errors.As(err, &evalErr) {
+				t.Fatalf("expected EvalError, got %v", err)
+			}
+			if actual := tt.input[evalErr.Span.Start:evalErr.Span.End]; actual != tt.expected {
+				t.Errorf("expected span of %q, got %q", tt.expected, actual)
+			}
+			if !reflect.DeepEqual(evalErr.Stack, tt.stack) {
+				t.Errorf("expected stack %v, got %v", tt.stack, evalErr.Stack)
+			}
+			if !strings.Contains(evalErr.Error(), tt.message) {
+				t.Errorf("expected error to contain %q, got %q", tt.message, evalErr.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// ContextFunction is a Function that receives the context.Context of
+	// the evaluation calling it, so it can stop blocking or doing I/O once
+	// the evaluation is cancelled.
+	ContextFunction func(ctx context.Context, args []Value) (Value, error)
+	// FunctionContext is implemented by contexts that hold ContextFunctions.
+	// Calls look names up with GetContextFunction before GetFunction.
+	FunctionContext interface {
+		Context
+		GetContextFunction(name string) ContextFunction
+	}
+	// evaluation is the context of an evaluation started by EvaluateContext,
+	// or by calling Evaluate on a node or bytecode outside of one.
+	// steps counts the steps taken against limits.MaxSteps, by every
+	// goroutine the evaluation calls lambdas on. done is goctx.Done(),
+	// kept to check it without locking goctx on every node. lazies holds
+	// the Lazy variables read so far.
+	evaluation struct {
+		Context
+		goctx  context.Context
+		done   <-chan struct{}
+		limits Limits
+		steps  atomic.Int64
+		mu     sync.Mutex
+		lazies map[string]Value
+	}
+)
+
+// EvaluateContext evaluates node against ctx like node.Evaluate(ctx), but
+// passes goctx to the ContextFunctions it calls and stops with an EvalError
+// wrapping goctx.Err() once goctx is done. Cancellation is checked on every
+// step of the evaluation and before every function call and lambda
+// invocation, and the built-in functions that loop check it as they go.
+// The Limits goctx carries
+// are enforced too, failing the evaluation with an error wrapping
+// ErrLimitExceeded.
+func EvaluateContext(goctx context.Context, node ExprNode, ctx Context) (Value, error) {
+	limits := LimitsFrom(goctx)
+	if err := limits.CheckDepth(node); err != nil {
+		return nil, evalError(SpanOf(node), err)
+	}
+	if limits.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		goctx, cancel = context.WithTimeoutCause(goctx, limits.MaxDuration, fmt.Errorf("%w: evaluation took longer than %v", ErrLimitExceeded, limits.MaxDuration))
+		defer cancel()
+	}
+	e := &evaluation{Context: ctx, goctx: goctx, done: goctx.Done(), limits: limits}
+	if err := e.cancelled(SpanOf(node)); err != nil {
+		return nil, err
+	}
+	return node.Evaluate(e)
+}
+
+// GetVariable keeps the first Lazy the context returns for a variable for
+// the rest of the evaluation, so that whatever it loads is shared by every
+// read of the variable, and dropped with the evaluation.
+func (e *evaluation) GetVariable(name string) Value {
+	value := e.Context.GetVariable(name)
+	if _, ok := value.(Lazy); !ok {
+		return value
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if kept, ok := e.lazies[name]; ok {
+		return kept
+	}
+	if e.lazies == nil {
+		e.lazies = make(map[string]Value)
+	}
+	e.lazies[name] = value
+	return value
+}
+
+func (e *evaluation) GetContextFunction(name string) ContextFunction {
+	if ctx, ok := e.Context.(FunctionContext); ok {
+		return ctx.GetContextFunction(name)
+	}
+	return nil
+}
+
+func (e *evaluation) OperandResults() bool {
+	if ctx, ok := e.Context.(OperandResultContext); ok {
+		return ctx.OperandResults()
+	}
+	return false
+}
+
+func (s *scope) GetContextFunction(name string) ContextFunction {
+	if ctx, ok := s.parent.(FunctionContext); ok {
+		return ctx.GetContextFunction(name)
+	}
+	return nil
+}
+
+// current returns the evaluation ctx belongs to, or nil outside
+// EvaluateContext.
+func current(ctx Context) *evaluation {
+	switch c := ctx.(type) {
+	case *evaluation:
+		{
+			return c
+		}
+	case *scope:
+		{
+			return c.evaluation
+		}
+	default:
+		{
+			return nil
+		}
+	}
+}
+
+// begin returns the evaluation ctx belongs to and the context to evaluate
+// in. A node evaluated on its own with node.Evaluate(ctx) starts an
+// evaluation without limits, so that the Lazy variables it reads are shared
+// by every read of the variable for that call, as under EvaluateContext.
+func begin(ctx Context) (*evaluation, Context) {
+	if e := current(ctx); e != nil {
+		return e, ctx
+	}
+	e := &evaluation{Context: ctx, goctx: context.Background()}
+	return e, e
+}
+
+// cancelled returns the cause of the evaluation being done, attributed to
+// the node spanning span, once it is.
+func (e *evaluation) cancelled(span Span) error {
+	if e == nil || e.done == nil {
+		return nil
+	}
+	select {
+	case <-e.done:
+		{
+			return evalError(span, context.Cause(e.goctx))
+		}
+	default:
+		{
+			return nil
+		}
+	}
+}
+
+// step takes a step of the evaluation of the node spanning span, failing
+// once the evaluation is cancelled. Outside EvaluateContext it returns
+// without a call, as it runs for every node.
+func (e *evaluation) step(span Span) error {
+	if e == nil || e.done == nil && e.limits.MaxSteps <= 0 {
+		return nil
+	}
+	return e.charge(1, span)
+}
+
+// charge takes n steps of the evaluation at once, failing once the
+// evaluation is cancelled or has taken more than MaxSteps.
+func (e *evaluation) charge(n int, span Span) error {
+	if err := e.cancelled(span); err != nil {
+		return err
+	}
+	if e == nil || e.limits.MaxSteps <= 0 {
+		return nil
+	}
+	if e.steps.Add(int64(n)) > int64(e.limits.MaxSteps) {
+		return evalErrorf(span, "%w: evaluation took more than %d steps", ErrLimitExceeded, e.limits.MaxSteps)
+	}
+	return nil
+}
+
+// bounds returns the limits of e, or no limits outside EvaluateContext.
+func (e *evaluation) bounds() Limits {
+	if e == nil {
+		return Limits{}
+	}
+	return e.limits
+}
+
+// size checks the size of a value built by the node spanning span.
+func (e *evaluation) size(value Value, span Span) error {
+	if !e.sized() {
+		return nil
+	}
+	return e.checkSize(value, span)
+}
+
+// sized reports whether the evaluation limits the size of values, so that
+// callers can skip working out what to check when it does not.
+func (e *evaluation) sized() bool {
+	return e != nil && (e.limits.MaxStringLength > 0 || e.limits.MaxCollectionSize > 0)
+}
+
+func (e *evaluation) checkSize(value Value, span Span) error {
+	if err := e.limits.checkValue(value); err != nil {
+		return evalError(span, err)
+	}
+	return nil
+}
+
+// function looks name up in ctx, binding a ContextFunction to the
+// context.Context of e, or to the background context outside
+// EvaluateContext.
+func function(ctx Context, name string, e *evaluation) Function {
+	if fc, ok := ctx.(FunctionContext); ok {
+		if fn := fc.GetContextFunction(name); fn != nil {
+			goctx := context.Background()
+			if e != nil {
+				goctx = e.goctx
+			}
+			return func(args []Value) (Value, error) {
+				return fn(goctx, args)
+			}
+		}
+	}
+	return ctx.GetFunction(name)
+}
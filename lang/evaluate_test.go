@@ -0,0 +1,130 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type (
+	// functionContext is a MockContext that also holds ContextFunctions.
+	functionContext struct {
+		*MockContext
+		contextFunctions map[string]ContextFunction
+	}
+	contextKey struct{}
+)
+
+func (c functionContext) GetContextFunction(name string) ContextFunction {
+	return c.contextFunctions[name]
+}
+
+// evaluateContextTest returns a context whose `cancel()` cancels the
+// evaluation, whose `wait()` blocks until the evaluation is done and whose
+// `tag()` returns the contextKey value of the evaluation.
+func evaluateContextTest(cancel context.CancelFunc) functionContext {
+	ctx := functionContext{MockContext: vmContext(), contextFunctions: map[string]ContextFunction{
+		"cancel": func(ctx context.Context, args []Value) (Value, error) {
+			cancel()
+			return BoolValue(true), nil
+		},
+		"wait": func(ctx context.Context, args []Value) (Value, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		"tag": func(ctx context.Context, args []Value) (Value, error) {
+			tag, _ := ctx.Value(contextKey{}).(string)
+			return StringValue(tag), nil
+		},
+	}}
+	ctx.SetVariable("lib", functionContext{MockContext: NewMockContext(), contextFunctions: ctx.contextFunctions})
+	return ctx
+}
+
+func TestEvaluateContext(t *testing.T) {
+	evaluators := map[string]func(node ExprNode) ExprNode{
+		"tree":     func(node ExprNode) ExprNode { return node },
+		"bytecode": func(node ExprNode) ExprNode { return Compile(node) },
+	}
+	tests := []struct {
+		input    string
+		expected Value
+		err      error
+		span     Span
+	}{
+		{input: "tag()", expected: StringValue("request")},
+		{input: "lib.tag() + x", expected: StringValue("request2")},
+		{input: "map([1], i => tag())", expected: ListValue{StringValue("request")}},
+		{input: "upper(s)", expected: StringValue("HELLO")},
+		{input: "cancel() and upper(s)", err: context.Canceled, span: Span{Start: 13, End: 21}},
+		{input: "cancel() or x > 1", expected: BoolValue(true)},
+		{input: "map([1, 2], i => i > 1 or cancel())", err: context.Canceled, span: Span{Start: 12, End: 34}},
+		{input: "wait()", err: context.DeadlineExceeded, span: Span{Start: 0, End: 6}},
+	}
+	for name, evaluator := range evaluators {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.input, func(t *testing.T) {
+				goctx, cancel := context.WithTimeout(context.WithValue(context.Background(), contextKey{}, "request"), 50*time.Millisecond)
+				defer cancel()
+				result, err := EvaluateContext(goctx, evaluator(mustParse(t, tt.input)), evaluateContextTest(cancel))
+				if tt.err != nil {
+					var evalErr *EvalError
+					if !errors.Is(err, tt.err) || !errors.As(err, &evalErr) || evalErr.Span != tt.span {
+						t.Fatalf("expected %v at %v, got %#v", tt.err, tt.span, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("evaluation error: %v", err)
+				}
+				if !reflect.DeepEqual(result, tt.expected) {
+					t.Errorf("expected %v, got %v", tt.expected, result)
+				}
+			})
+		}
+
+		t.Run(name+"/cancelled", func(t *testing.T) {
+			goctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := EvaluateContext(goctx, evaluator(mustParse(t, "x")), vmContext()); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected the evaluation to be cancelled, got %v", err)
+			}
+		})
+
+		t.Run(name+"/background", func(t *testing.T) {
+			result, err := evaluator(mustParse(t, "tag()")).Evaluate(evaluateContextTest(func() {}))
+			if err != nil || result != StringValue("") {
+				t.Errorf("expected a background context, got %v (%v)", result, err)
+			}
+		})
+	}
+
+	// The tree walker checks on every node, while bytecode only runs
+	// straight through between calls
+	t.Run("tree/cancelled between calls", func(t *testing.T) {
+		goctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, err := EvaluateContext(goctx, mustParse(t, "cancel() and s + s"), evaluateContextTest(cancel))
+		var evalErr *EvalError
+		if !errors.Is(err, context.Canceled) || !errors.As(err, &evalErr) || evalErr.Span != (Span{Start: 13, End: 18}) {
+			t.Errorf("expected the evaluation to be cancelled at s + s, got %v", err)
+		}
+	})
+}
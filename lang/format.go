@@ -0,0 +1,695 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// FormatOptions controls how FormatWith lays out an expression.
+	FormatOptions struct {
+		// Width is the line width to wrap long expressions at. Zero keeps
+		// the expression on a single line.
+		Width int
+		// Indent is written once per nesting level on wrapped lines. It
+		// defaults to four spaces.
+		Indent string
+	}
+	printer struct {
+		sb     strings.Builder
+		width  int
+		indent string
+		depth  int
+		column int
+	}
+)
+
+// Operator precedence, from loosest to tightest. Operands are parenthesized
+// only when their precedence is lower than the grammar allows in their
+// position.
+const (
+	precLambda = iota
+	precConditional
+	precCoalesce
+	precLogical
+	precEquality
+	precRelational
+	precAdditive
+	precMultiplicative
+	precUnary
+	precPower
+	precPrimary
+)
+
+var keywords = map[string]bool{
+	"and": true, "or": true, "not": true, "in": true, "is": true, "null": true,
+	"true": true, "false": true, "case": true, "when": true, "then": true,
+	"else": true, "end": true,
+}
+
+// Format returns the canonical source of node on a single line, with only
+// the parentheses its structure requires. Parsing the result yields an
+// equivalent AST.
+func Format(node ExprNode) string {
+	return FormatWith(node, FormatOptions{})
+}
+
+// FormatWith returns the canonical source of node, wrapping calls,
+// literals, operator chains, conditionals and case expressions that do not
+// fit in options.Width over several indented lines.
+func FormatWith(node ExprNode, options FormatOptions) string {
+	p := &printer{width: options.Width, indent: options.Indent}
+	if p.indent == "" {
+		p.indent = "    "
+	}
+	p.expr(node, precLambda)
+	return p.sb.String()
+}
+
+func (n *BinaryOpNode) String() string     { return Format(n) }
+func (n *UnaryOpNode) String() string      { return Format(n) }
+func (n *LiteralNode) String() string      { return Format(n) }
+func (n *VariableNode) String() string     { return Format(n) }
+func (n *FieldAccessNode) String() string  { return Format(n) }
+func (n *IndexAccessNode) String() string  { return Format(n) }
+func (n *FunctionCallNode) String() string { return Format(n) }
+func (n *ListNode) String() string         { return Format(n) }
+func (n *TemplateNode) String() string     { return Format(n) }
+func (n *MapNode) String() string          { return Format(n) }
+func (n *EachNode) String() string         { return Format(n) }
+func (n *RangeNode) String() string        { return Format(n) }
+func (n *ConditionalNode) String() string  { return Format(n) }
+func (n *CaseNode) String() string         { return Format(n) }
+func (n *LambdaNode) String() string       { return Format(n) }
+func (n *BadNode) String() string          { return Format(n) }
+func (n *InSetNode) String() string        { return Format(n) }
+
+// precedence returns the precedence of node as it is printed.
+func precedence(node ExprNode) int {
+	switch n := node.(type) {
+	case *LambdaNode:
+		return precLambda
+	case *ConditionalNode:
+		return precConditional
+	case *BinaryOpNode:
+		return binaryPrecedence(n.Operator)
+	case *InSetNode:
+		return precRelational
+	case *UnaryOpNode:
+		if n.Operator == "is null" || n.Operator == "is not null" {
+			return precEquality
+		}
+		return precUnary
+	case *LiteralNode:
+		if number, ok := n.Value.(NumberValue); ok {
+			// Infinities and NaN are written as divisions
+			if f := float64(number); math.IsInf(f, 0) || math.IsNaN(f) {
+				return precMultiplicative
+			}
+			if math.Signbit(float64(number)) {
+				return precUnary
+			}
+		}
+		return precPrimary
+	default:
+		return precPrimary
+	}
+}
+
+func binaryPrecedence(operator string) int {
+	switch operator {
+	case "??":
+		return precCoalesce
+	case "and", "or":
+		return precLogical
+	case "=", "==", "!=":
+		return precEquality
+	case "<", "<=", ">", ">=", "in", "not in":
+		return precRelational
+	case "+", "-":
+		return precAdditive
+	case "*", "/", "%", "//":
+		return precMultiplicative
+	case "**":
+		return precPower
+	default:
+		return precPrimary
+	}
+}
+
+func (p *printer) write(s string) {
+	p.sb.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		p.column = utf8.RuneCountInString(s[i+1:])
+		return
+	}
+	p.column += utf8.RuneCountInString(s)
+}
+
+func (p *printer) newline() {
+	p.write("\n" + strings.Repeat(p.indent, p.depth))
+}
+
+// fits reports whether node can be written on the rest of the current line
+// and returns its single line form.
+func (p *printer) fits(node ExprNode, prec int) (string, bool) {
+	flat := &printer{indent: p.indent}
+	flat.expr(node, prec)
+	s := flat.sb.String()
+	return s, p.width <= 0 || p.column+utf8.RuneCountInString(s) <= p.width
+}
+
+// expr writes node, parenthesized if its precedence is below prec.
+func (p *printer) expr(node ExprNode, prec int) {
+	if precedence(node) < prec {
+		p.write("(")
+		p.node(node)
+		p.write(")")
+		return
+	}
+	p.node(node)
+}
+
+func (p *printer) node(node ExprNode) {
+	if p.width > 0 {
+		if s, ok := p.fits(node, precLambda); ok {
+			p.write(s)
+			return
+		}
+	}
+
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		{
+			p.binary(n)
+		}
+	case *UnaryOpNode:
+		{
+			switch n.Operator {
+			case "is null", "is not null":
+				{
+					p.expr(n.Operand, precEquality)
+					p.write(" " + n.Operator)
+				}
+			case "not":
+				{
+					p.write("not ")
+					p.expr(n.Operand, precUnary)
+				}
+			default:
+				{
+					p.write(n.Operator)
+					// Keep `- -a` from reading as a single token
+					if s, _ := p.fits(n.Operand, precUnary); strings.HasPrefix(s, n.Operator) {
+						p.write(" ")
+					}
+					p.expr(n.Operand, precUnary)
+				}
+			}
+		}
+	case *LiteralNode:
+		{
+			p.literal(n.Value)
+		}
+	case *VariableNode:
+		{
+			p.write(n.Name)
+		}
+	case *FieldAccessNode:
+		{
+			p.object(n.Object)
+			if n.Optional {
+				p.write("?.")
+			} else {
+				p.write(".")
+			}
+			p.write(n.Field)
+		}
+	case *IndexAccessNode:
+		{
+			p.object(n.Object)
+			if n.Optional {
+				p.write("?.[")
+			} else {
+				p.write("[")
+			}
+			p.expr(n.Index, precLambda)
+			p.write("]")
+		}
+	case *FunctionCallNode:
+		{
+			if n.Namespace != nil {
+				p.object(n.Namespace)
+				if n.Optional {
+					p.write("?.")
+				} else {
+					p.write(".")
+				}
+			}
+			p.write(n.Name)
+			p.list("(", ")", len(n.Args), func(i int) {
+				p.expr(n.Args[i], precLambda)
+			})
+		}
+	case *ListNode:
+		{
+			p.list("[", "]", len(n.Elements), func(i int) {
+				p.expr(n.Elements[i], precLambda)
+			})
+		}
+	case *MapNode:
+		{
+			p.list("{", "}", len(n.Entries), func(i int) {
+				p.entry(n.Entries[i])
+			})
+		}
+	case *TemplateNode:
+		{
+			p.template(n)
+		}
+	case *EachNode:
+		{
+			p.write("?")
+		}
+	case *RangeNode:
+		{
+			p.bound(n.Begin)
+			p.write(":")
+			p.bound(n.End)
+			if n.Step != nil {
+				p.write(":")
+				p.bound(n.Step)
+			}
+		}
+	case *ConditionalNode:
+		{
+			p.expr(n.Condition, precCoalesce)
+			if p.width <= 0 {
+				p.write(" ? ")
+				p.expr(n.Then, precLambda)
+				p.write(" : ")
+				p.expr(n.Else, precLambda)
+				return
+			}
+			p.depth++
+			p.newline()
+			p.write("? ")
+			p.expr(n.Then, precLambda)
+			p.newline()
+			p.write(": ")
+			p.expr(n.Else, precLambda)
+			p.depth--
+		}
+	case *CaseNode:
+		{
+			p.caseExpr(n)
+		}
+	case *LambdaNode:
+		{
+			if len(n.Params) == 1 {
+				p.write(n.Params[0])
+			} else {
+				p.write("(" + strings.Join(n.Params, ", ") + ")")
+			}
+			p.write(" => ")
+			p.expr(n.Body, precLambda)
+		}
+	case *InSetNode:
+		{
+			p.expr(n.Operand, precRelational)
+			if n.Negate {
+				p.write(" not in ")
+			} else {
+				p.write(" in ")
+			}
+			p.literal(n.List)
+		}
+	case *BadNode:
+		{
+			p.write("<invalid>")
+		}
+	default:
+		{
+			p.write(fmt.Sprintf("<%T>", node))
+		}
+	}
+}
+
+// binary writes a chain of operators of the same precedence, such as
+// `a and b or c`, breaking before each operator when it does not fit.
+func (p *printer) binary(n *BinaryOpNode) {
+	prec := binaryPrecedence(n.Operator)
+	if prec == precPower {
+		// Right associative, and only a primary expression may be raised
+		p.expr(n.Left, precPrimary)
+		p.write(" ** ")
+		p.expr(n.Right, precUnary)
+		return
+	}
+
+	chain := []*BinaryOpNode{n}
+	for {
+		left, ok := chain[0].Left.(*BinaryOpNode)
+		if !ok || binaryPrecedence(left.Operator) != prec {
+			break
+		}
+		chain = append([]*BinaryOpNode{left}, chain...)
+	}
+
+	p.expr(chain[0].Left, prec)
+	if p.width > 0 {
+		p.depth++
+	}
+	for _, link := range chain {
+		if p.width > 0 {
+			p.newline()
+		} else {
+			p.write(" ")
+		}
+		p.write(operatorText(link.Operator) + " ")
+		p.expr(link.Right, prec+1)
+	}
+	if p.width > 0 {
+		p.depth--
+	}
+}
+
+func operatorText(operator string) string {
+	if operator == "=" {
+		return "=="
+	}
+	return operator
+}
+
+// object writes the object of a field access, index or namespaced call.
+func (p *printer) object(node ExprNode) {
+	// A number followed by a dot would be read as a malformed number
+	if literal, ok := node.(*LiteralNode); ok {
+		if _, ok := literal.Value.(NumberValue); ok {
+			p.write("(")
+			p.literal(literal.Value)
+			p.write(")")
+			return
+		}
+	}
+	p.expr(node, precPrimary)
+}
+
+// bound writes a slice bound. Conditionals are parenthesized so that their
+// colon is not mistaken for the slice's.
+func (p *printer) bound(node ExprNode) {
+	if node != nil {
+		p.expr(node, precCoalesce)
+	}
+}
+
+// list writes count items between open and close, one per line when they
+// do not fit.
+func (p *printer) list(open, close string, count int, item func(i int)) {
+	p.write(open)
+	if count == 0 {
+		p.write(close)
+		return
+	}
+	if p.width <= 0 {
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				p.write(", ")
+			}
+			item(i)
+		}
+		p.write(close)
+		return
+	}
+	p.depth++
+	for i := 0; i < count; i++ {
+		p.newline()
+		item(i)
+		if i < count-1 {
+			p.write(",")
+		}
+	}
+	p.depth--
+	p.newline()
+	p.write(close)
+}
+
+func (p *printer) entry(entry MapEntry) {
+	if entry.Spread {
+		p.write("...")
+		p.expr(entry.Value, precLambda)
+		return
+	}
+	if literal, ok := entry.Key.(*LiteralNode); ok {
+		if key, ok := literal.Value.(StringValue); ok {
+			if isIdentifier(string(key)) {
+				p.write(string(key))
+			} else {
+				p.write(quote(string(key), '\''))
+			}
+			p.write(": ")
+			p.expr(entry.Value, precLambda)
+			return
+		}
+	}
+	p.write("[")
+	p.expr(entry.Key, precLambda)
+	p.write("]: ")
+	p.expr(entry.Value, precLambda)
+}
+
+func (p *printer) template(n *TemplateNode) {
+	var sb strings.Builder
+	sb.WriteByte('`')
+	for _, part := range n.Parts {
+		if literal, ok := part.(*LiteralNode); ok {
+			if text, ok := literal.Value.(StringValue); ok {
+				sb.WriteString(escape(string(text), '`'))
+				continue
+			}
+		}
+		sb.WriteString("${")
+		sb.WriteString(Format(part))
+		sb.WriteString("}")
+	}
+	sb.WriteByte('`')
+	p.write(sb.String())
+}
+
+func (p *printer) caseExpr(n *CaseNode) {
+	p.write("case")
+	if n.Subject != nil {
+		p.write(" ")
+		p.expr(n.Subject, precLambda)
+	}
+	if p.width > 0 {
+		p.depth++
+	}
+	clause := func(keyword string) {
+		if p.width > 0 {
+			p.newline()
+		} else {
+			p.write(" ")
+		}
+		p.write(keyword + " ")
+	}
+	for _, when := range n.Whens {
+		clause("when")
+		p.expr(when.Condition, precLambda)
+		p.write(" then ")
+		p.expr(when.Result, precLambda)
+	}
+	if n.Else != nil {
+		clause("else")
+		p.expr(n.Else, precLambda)
+	}
+	if p.width > 0 {
+		p.depth--
+		p.newline()
+		p.write("end")
+		return
+	}
+	p.write(" end")
+}
+
+// literal writes value as the literal that evaluates to it. Lists, maps
+// and ranges, which the parser never puts in a LiteralNode, are written as
+// list and map literals and slices so that folded constants can be printed
+// too. Values without a source form, see hasSource, are written as strings.
+func (p *printer) literal(value Value) {
+	switch v := value.(type) {
+	case nil:
+		{
+			p.write("null")
+		}
+	case BoolValue:
+		{
+			p.write(strconv.FormatBool(bool(v)))
+		}
+	case NumberValue:
+		{
+			p.write(formatNumber(float64(v)))
+		}
+	case StringValue:
+		{
+			p.write(quote(string(v), '\''))
+		}
+	case ListValue:
+		{
+			p.list("[", "]", len(v), func(i int) {
+				p.literal(v[i])
+			})
+		}
+	case RangeValue:
+		{
+			p.node(&RangeNode{Begin: boundNode(v.Begin), End: boundNode(v.End), Step: boundNode(v.Step)})
+		}
+	case MapValue:
+		{
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			p.list("{", "}", len(keys), func(i int) {
+				p.entry(MapEntry{Key: &LiteralNode{Value: StringValue(keys[i])}, Value: &LiteralNode{Value: v[keys[i]]}})
+			})
+		}
+	default:
+		{
+			p.write(quote(fmt.Sprint(v), '\''))
+		}
+	}
+}
+
+// hasSource reports whether literal writes value as source that evaluates
+// to it. Functions and contexts have no source form, and ranges only have
+// one as the index of a slice.
+func hasSource(value Value) bool {
+	switch v := value.(type) {
+	case nil, BoolValue, NumberValue, StringValue:
+		{
+			return true
+		}
+	case ListValue:
+		{
+			for _, item := range v {
+				if !hasSource(item) {
+					return false
+				}
+			}
+			return true
+		}
+	case MapValue:
+		{
+			for _, item := range v {
+				if !hasSource(item) {
+					return false
+				}
+			}
+			return true
+		}
+	default:
+		{
+			return false
+		}
+	}
+}
+
+// boundNode returns the node of a bound of a RangeValue, or nil for a
+// bound that was left out.
+func boundNode(value Value) ExprNode {
+	if value == nil {
+		return nil
+	}
+	return &LiteralNode{Value: value}
+}
+
+// formatNumber writes f as a number literal, or as the division that gives
+// it for infinities and NaN, which have none.
+func formatNumber(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		{
+			return "1 / 0"
+		}
+	case math.IsInf(f, -1):
+		{
+			return "-1 / 0"
+		}
+	case math.IsNaN(f):
+		{
+			return "0 / 0"
+		}
+	}
+	if abs := math.Abs(f); abs >= 1e21 || (abs != 0 && abs < 1e-6) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// quote returns s as a string literal delimited by q.
+func quote(s string, q byte) string {
+	return string(q) + escape(s, q) + string(q)
+}
+
+// escape escapes s for use between q delimiters.
+func escape(s string, q byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\\':
+			sb.WriteString(`\\`)
+		case r == rune(q):
+			sb.WriteByte('\\')
+			sb.WriteByte(q)
+		case q == '`' && strings.HasPrefix(s[i:], "${"):
+			sb.WriteString(`\$`)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			// Invalid UTF-8 is kept as is; there is no escape for it
+			sb.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return sb.String()
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || keywords[s] || isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) && !isDigit(s[i]) && s[i] != '_' {
+			return false
+		}
+	}
+	return true
+}
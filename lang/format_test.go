@@ -0,0 +1,245 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"variable", "  user ", "user"},
+		{"numbers", "1.50 + 0x10 + 1_000 + 2e3", "1.5 + 16 + 1000 + 2000"},
+		{"large number", "1e21", "1e+21"},
+		{"strings", `"it's" + 'a\tb'`, `'it\'s' + 'a\tb'`},
+		{"literals", "true and null", "true and null"},
+		{"equality", "a = b", "a == b"},
+		{"redundant parentheses", "((a + b)) * (c)", "(a + b) * c"},
+		{"left associative", "(a - b) - c", "a - b - c"},
+		{"right operand", "a - (b - c)", "a - (b - c)"},
+		{"same level logical", "a or (b and c)", "a or (b and c)"},
+		{"logical chain", "(a or b) and c", "a or b and c"},
+		{"power", "(2 ** 3) ** 2 + 2 ** 3 ** 2", "(2 ** 3) ** 2 + 2 ** 3 ** 2"},
+		{"negative base", "(-2) ** 2", "(-2) ** 2"},
+		{"negative power", "-2 ** 2", "-2 ** 2"},
+		{"double negation", "- -a", "- -a"},
+		{"not", "not (a and b)", "not (a and b)"},
+		{"is null", "(a + b) is not null", "a + b is not null"},
+		{"in", "a not in [1, 2]", "a not in [1, 2]"},
+		{"coalesce", "(a ?? b) ?? c", "a ?? b ?? c"},
+		{"field access", "(a + b).c", "(a + b).c"},
+		{"optional access", "a?.b?.[0]?.c()", "a?.b?.[0]?.c()"},
+		{"keyword field", "range?.end", "range?.end"},
+		{"number object", "(1).x", "(1).x"},
+		{"index", "a[1][b]", "a[1][b]"},
+		{"slice", "a[1:] + a[:-1] + a[::2] + a[:]", "a[1:] + a[:-1] + a[::2] + a[:]"},
+		{"conditional bound", "a[(b ? 1 : 2):]", "a[(b ? 1 : 2):]"},
+		{"each", "items[?].name", "items[?].name"},
+		{"calls", "f() + ns.g(1,2)", "f() + ns.g(1, 2)"},
+		{"list", "[ 1,2 , [3] ]", "[1, 2, [3]]"},
+		{"map", "{a: 1, 'b c': 2, 'end': 3, [k]: 4, ...rest}", "{a: 1, 'b c': 2, 'end': 3, [k]: 4, ...rest}"},
+		{"template", "`a ${b + 1} \\${c} \\` d`", "`a ${b + 1} \\${c} \\` d`"},
+		{"conditional", "a ? b : c ? d : e", "a ? b : c ? d : e"},
+		{"conditional condition", "(a ? b : c) ? d : e", "(a ? b : c) ? d : e"},
+		{"conditional operand", "(a ? b : c) + 1", "(a ? b : c) + 1"},
+		{"case", "case when a then 1 when b then 2 else 3 end", "case when a then 1 when b then 2 else 3 end"},
+		{"case subject", "case x when 1 then 'a' end", "case x when 1 then 'a' end"},
+		{"lambdas", "list.map(items, (x) => x * 2) + f(() => 1, (a, b) => a)", "list.map(items, x => x * 2) + f(() => 1, (a, b) => a)"},
+		{"curried lambda", "x => y => x", "x => y => x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			actual := Format(ast)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+
+			// The canonical form must parse back to the same canonical form
+			reparsed, err := ParseExpression(actual)
+			if err != nil {
+				t.Fatalf("canonical form does not parse: %v", err)
+			}
+			if again := Format(reparsed); again != actual {
+				t.Errorf("expected round trip to give %q, got %q", actual, again)
+			}
+		})
+	}
+}
+
+func TestFormatNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		node     ExprNode
+		expected string
+	}{
+		{
+			"built operands",
+			&BinaryOpNode{
+				Operator: "*",
+				Left:     &BinaryOpNode{Operator: "+", Left: &VariableNode{Name: "a"}, Right: &LiteralNode{Value: NumberValue(1)}},
+				Right:    &LiteralNode{Value: NumberValue(-2)},
+			},
+			"(a + 1) * -2",
+		},
+		{"negative base", &BinaryOpNode{Operator: "**", Left: &LiteralNode{Value: NumberValue(-2)}, Right: &LiteralNode{Value: NumberValue(2)}}, "(-2) ** 2"},
+		{"list value", &LiteralNode{Value: ListValue{NumberValue(1), StringValue("a"), nil}}, "[1, 'a', null]"},
+		{"map value", &LiteralNode{Value: MapValue{"b": BoolValue(true), "a": NumberValue(1)}}, "{a: 1, b: true}"},
+		{"control characters", &LiteralNode{Value: StringValue("a\nb\x01")}, `'a\nb\u0001'`},
+		{"lambda operand", &UnaryOpNode{Operator: "not", Operand: &LambdaNode{Params: []string{"x"}, Body: &VariableNode{Name: "x"}}}, "not (x => x)"},
+		{"range value", &IndexAccessNode{Object: &VariableNode{Name: "a"}, Index: &LiteralNode{Value: RangeValue{Begin: NumberValue(1)}}}, "a[1:]"},
+		{"bad node", &BinaryOpNode{Operator: "+", Left: &VariableNode{Name: "a"}, Right: &BadNode{}}, "a + <invalid>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := Format(tt.node); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+			if stringer, ok := tt.node.(interface{ String() string }); !ok || stringer.String() != tt.expected {
+				t.Errorf("expected String() to match Format")
+			}
+		})
+	}
+}
+
+func TestFormatOptimized(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"infinity", "1/0", "1 / 0"},
+		{"negative infinity", "-1/0", "-1 / 0"},
+		{"nan", "0/0", "0 / 0"},
+		{"negative zero", "0 * -1", "-0"},
+		{"list", "[1/0, 0/0, -0]", "[1 / 0, 0 / 0, -0]"},
+		{"operand", "x * (1/0)", "x * (1 / 0)"},
+		{"base", "(1/0) ** x", "(1 / 0) ** x"},
+		{"exponent", "2 ** (0 * -1)", "1"},
+		{"negated", "-x / (-1/0)", "-x / (-1 / 0)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			optimized := Optimize(ast, optimizeEnv{})
+			actual := Format(optimized)
+			if actual != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, actual)
+			}
+
+			// The source must evaluate to the folded value, down to the
+			// sign of zero, so values are compared by their literal form
+			reparsed, err := ParseExpression(actual)
+			if err != nil {
+				t.Fatalf("formatted source does not parse: %v", err)
+			}
+			ctx := NewMockContext()
+			ctx.SetVariable("x", NumberValue(2))
+			want, err := optimized.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("evaluate error: %v", err)
+			}
+			got, err := reparsed.Evaluate(ctx)
+			if err != nil {
+				t.Fatalf("evaluate error: %v", err)
+			}
+			if Format(&LiteralNode{Value: got}) != Format(&LiteralNode{Value: want}) {
+				t.Errorf("expected %s to evaluate to %v, got %v", actual, want, got)
+			}
+		})
+	}
+}
+
+func TestFormatWith(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected string
+	}{
+		{"fits", "a and b", 20, "a and b"},
+		{
+			"operator chain",
+			"user.age >= 18 and user.country == 'US' or user.admin",
+			30,
+			"user.age >= 18\n    and user.country == 'US'\n    or user.admin",
+		},
+		{
+			"call arguments",
+			"list.filter(items, x => x.price > threshold and x.stock > 0)",
+			50,
+			"list.filter(\n    items,\n    x => x.price > threshold and x.stock > 0\n)",
+		},
+		{
+			"nested",
+			"list.filter(items, x => x.price > threshold and x.stock > 0)",
+			30,
+			"list.filter(\n    items,\n    x => x.price > threshold\n        and x.stock > 0\n)",
+		},
+		{
+			"map",
+			"{name: user.name, email: user.email}",
+			20,
+			"{\n    name: user.name,\n    email: user.email\n}",
+		},
+		{
+			"conditional",
+			"user.age >= 18 ? 'adult' : 'minor'",
+			20,
+			"user.age >= 18\n    ? 'adult'\n    : 'minor'",
+		},
+		{
+			"case",
+			"case when score > 90 then 'A' when score > 80 then 'B' else 'C' end",
+			30,
+			"case\n    when score > 90 then 'A'\n    when score > 80 then 'B'\n    else 'C'\nend",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			actual := FormatWith(ast, FormatOptions{Width: tt.width})
+			if actual != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, actual)
+			}
+
+			reparsed, err := ParseExpression(actual)
+			if err != nil {
+				t.Fatalf("wrapped form does not parse: %v", err)
+			}
+			if Format(reparsed) != Format(ast) {
+				t.Errorf("expected wrapped form to parse to %q, got %q", Format(ast), Format(reparsed))
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
-// Code generated by goyacc -o .\lang.go lang.y. DO NOT EDIT.
-
-//line lang.y:1
+// Code generated by goyacc -o lang.go lang.y. DO NOT EDIT.
 
+//line lang.y:2
 /*
  * Copyright 2025 Pouya Vedadiyan
  *
@@ -21,44 +20,71 @@ package lang
 
 import __yyfmt__ "fmt"
 
-//line lang.y:18
+//line lang.y:17
+
+import "sort"
 
-//line lang.y:21
+//line lang.y:22
 type yySymType struct {
 	yys      int
 	expr     ExprNode
 	exprList []ExprNode
+	strList  []string
+	whenList []WhenClause
+	entry    MapEntry
+	entries  []MapEntry
 	str      string
 	num      float64
 	boolean  bool
+	pos      int
+	end      int
 }
 
 const IDENTIFIER = 57346
 const STRING = 57347
 const DSTRING = 57348
-const NUMBER = 57349
-const BOOLEAN = 57350
-const AND = 57351
-const OR = 57352
-const NOT = 57353
-const IN = 57354
-const EQ = 57355
-const NE = 57356
-const LT = 57357
-const LE = 57358
-const GT = 57359
-const GE = 57360
-const LPAREN = 57361
-const RPAREN = 57362
-const LBRACKET = 57363
-const RBRACKET = 57364
-const DOT = 57365
-const COMMA = 57366
-const QUOTE = 57367
-const DQUOTE = 57368
-const COLON = 57369
-const QMARK = 57370
-const UMINUS = 57371
+const TEMPLATE = 57349
+const NUMBER = 57350
+const BOOLEAN = 57351
+const AND = 57352
+const OR = 57353
+const NOT = 57354
+const IN = 57355
+const IS = 57356
+const NULL = 57357
+const EQ = 57358
+const NE = 57359
+const LT = 57360
+const LE = 57361
+const GT = 57362
+const GE = 57363
+const LPAREN = 57364
+const RPAREN = 57365
+const LBRACKET = 57366
+const RBRACKET = 57367
+const LBRACE = 57368
+const RBRACE = 57369
+const DOT = 57370
+const COMMA = 57371
+const QUOTE = 57372
+const DQUOTE = 57373
+const COLON = 57374
+const QMARK = 57375
+const ARROW = 57376
+const QDOT = 57377
+const QLBRACKET = 57378
+const COALESCE = 57379
+const IDIV = 57380
+const POW = 57381
+const ELLIPSIS = 57382
+const CASE = 57383
+const WHEN = 57384
+const THEN = 57385
+const ELSE = 57386
+const END = 57387
+const ILLEGAL = 57388
+const EOI = 57389
+const UMINUS = 57390
 
 var yyToknames = [...]string{
 	"$end",
@@ -67,12 +93,15 @@ var yyToknames = [...]string{
 	"IDENTIFIER",
 	"STRING",
 	"DSTRING",
+	"TEMPLATE",
 	"NUMBER",
 	"BOOLEAN",
 	"AND",
 	"OR",
 	"NOT",
 	"IN",
+	"IS",
+	"NULL",
 	"EQ",
 	"NE",
 	"LT",
@@ -83,16 +112,33 @@ var yyToknames = [...]string{
 	"RPAREN",
 	"LBRACKET",
 	"RBRACKET",
+	"LBRACE",
+	"RBRACE",
 	"DOT",
 	"COMMA",
 	"QUOTE",
 	"DQUOTE",
 	"COLON",
 	"QMARK",
+	"ARROW",
+	"QDOT",
+	"QLBRACKET",
+	"COALESCE",
+	"IDIV",
+	"POW",
+	"ELLIPSIS",
+	"CASE",
+	"WHEN",
+	"THEN",
+	"ELSE",
+	"END",
+	"ILLEGAL",
+	"EOI",
 	"'+'",
 	"'-'",
 	"'*'",
 	"'/'",
+	"'%'",
 	"UMINUS",
 }
 
@@ -102,116 +148,266 @@ const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line lang.y:194
+//line lang.y:388
+
+// DefaultMaxDepth bounds the nesting depth of every parsed expression, so
+// that the passes that recurse over the AST, such as Format, Optimize and
+// Walk, cannot exhaust the stack. ParseLimited can lower it.
+const DefaultMaxDepth = 10000
 
+// ParseExpression parses input and returns its AST, or the first syntax
+// error found. Use ParsePartial to get every syntax error.
 func ParseExpression(input string) (ExprNode, error) {
+	return ParseLimited(input, Limits{})
+}
+
+// ParseLimited is ParseExpression for an expression that must not be
+// nested deeper than the MaxDepth of limits. A tree that is too deep fails
+// with a SyntaxError wrapping ErrLimitExceeded.
+func ParseLimited(input string, limits Limits) (ExprNode, error) {
+	ast, errs := parse(&yyLex{input: input, limit: limits.MaxDepth})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return ast, nil
+}
+
+// ParsePartial parses input, recovering from syntax errors, and returns
+// every error found ordered by position together with a partial AST in
+// which the parts that could not be parsed are BadNodes. Elements, call
+// arguments and map entries next to an error are kept, and brackets left
+// open at the end of the input are closed. The AST is never nil: it is a
+// single BadNode if nothing could be recovered or if it is nested deeper
+// than DefaultMaxDepth.
+func ParsePartial(input string) (ExprNode, []*SyntaxError) {
+	return parse(&yyLex{input: input})
+}
+
+func init() {
+	// Set once here rather than per parse, so that parsing is safe for
+	// concurrent use
 	yyErrorVerbose = true
-	lexer := &yyLex{input: input}
+}
+
+func parse(lexer *yyLex) (ExprNode, []*SyntaxError) {
 	yyParse(lexer)
-	if lexer.error != nil {
-		return nil, lexer.error
+	// The parser builds the tree without recursing, so it is only checked
+	// once it is whole, before anything walks it
+	if lexer.depth == 0 && lexer.result != nil {
+		if deep := deeper(lexer.result, lexer.maxDepth()); deep != nil {
+			lexer.tooDeep(Span{Start: SpanOf(deep).Start, End: SpanOf(deep).Start})
+		}
+	}
+	// A tree cut short by the depth limit is dropped, and so is nothing at
+	// all, for a BadNode that spans the whole input
+	if lexer.stop || lexer.result == nil {
+		lexer.result = &BadNode{Span: Span{Start: lexer.offset, End: lexer.offset + len(lexer.input)}}
+	}
+	sort.SliceStable(lexer.errors, func(i, j int) bool {
+		return lexer.errors[i].Offset < lexer.errors[j].Offset
+	})
+	return lexer.result, lexer.errors
+}
+
+// cover returns span widened to include the spans of the non-nil nodes.
+func cover(span Span, nodes ...ExprNode) Span {
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		span.Start = min(span.Start, SpanOf(node).Start)
+		span.End = max(span.End, SpanOf(node).End)
 	}
-	return lexer.result, nil
+	return span
 }
 
 //line yacctab:1
-var yyExca = [...]int8{
+var yyExca = [...]int16{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 65,
+	32, 74,
+	-2, 0,
+	-1, 67,
+	32, 74,
+	-2, 0,
+	-1, 160,
+	25, 74,
+	32, 74,
+	-2, 0,
+	-1, 194,
+	25, 74,
+	-2, 0,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 147
-
-var yyAct = [...]int8{
-	65, 2, 6, 63, 34, 35, 32, 33, 12, 14,
-	15, 13, 16, 7, 71, 9, 84, 75, 41, 72,
-	76, 76, 44, 17, 85, 21, 81, 82, 5, 49,
-	50, 51, 52, 53, 10, 12, 14, 15, 13, 16,
-	60, 67, 9, 68, 73, 66, 55, 56, 70, 39,
-	17, 38, 21, 47, 48, 40, 54, 69, 62, 61,
-	59, 10, 1, 74, 12, 14, 15, 13, 16, 77,
-	4, 9, 42, 80, 79, 24, 25, 83, 20, 17,
-	78, 21, 12, 14, 15, 13, 16, 19, 18, 9,
-	10, 22, 23, 45, 46, 8, 11, 17, 64, 21,
-	12, 14, 15, 13, 16, 36, 37, 9, 10, 3,
-	0, 0, 0, 0, 0, 17, 0, 21, 43, 0,
-	12, 14, 15, 13, 16, 0, 10, 9, 0, 0,
-	57, 58, 0, 0, 0, 17, 0, 21, 31, 30,
-	0, 0, 26, 27, 28, 29, 10,
+const yyLast = 428
+
+var yyAct = [...]uint8{
+	94, 2, 123, 92, 11, 139, 73, 86, 40, 12,
+	58, 188, 122, 13, 53, 54, 189, 140, 171, 141,
+	34, 175, 55, 56, 57, 88, 191, 149, 59, 62,
+	9, 70, 95, 87, 194, 187, 89, 29, 91, 6,
+	19, 20, 21, 18, 22, 10, 8, 14, 38, 160,
+	23, 144, 105, 106, 107, 108, 109, 7, 185, 30,
+	37, 31, 117, 111, 112, 33, 120, 170, 125, 113,
+	114, 115, 116, 99, 100, 136, 32, 118, 137, 138,
+	127, 35, 65, 90, 15, 36, 64, 135, 134, 143,
+	101, 102, 179, 66, 67, 142, 148, 63, 180, 29,
+	133, 6, 19, 20, 21, 18, 22, 147, 164, 14,
+	38, 163, 23, 150, 162, 154, 159, 98, 158, 7,
+	181, 30, 37, 31, 157, 131, 130, 132, 195, 79,
+	165, 80, 75, 76, 146, 167, 168, 169, 32, 166,
+	161, 172, 173, 97, 156, 176, 15, 177, 174, 128,
+	178, 77, 147, 129, 72, 38, 97, 153, 110, 104,
+	182, 184, 103, 183, 155, 186, 192, 78, 81, 82,
+	83, 84, 85, 193, 42, 43, 190, 96, 147, 146,
+	29, 152, 6, 19, 20, 21, 18, 22, 196, 197,
+	14, 124, 198, 23, 119, 184, 1, 199, 74, 145,
+	7, 71, 30, 151, 31, 146, 46, 68, 44, 45,
+	29, 126, 6, 19, 20, 21, 18, 22, 27, 32,
+	14, 26, 25, 23, 24, 17, 16, 15, 28, 5,
+	7, 3, 30, 4, 31, 0, 0, 0, 0, 0,
+	29, 121, 6, 19, 20, 21, 18, 22, 0, 32,
+	14, 0, 0, 23, 0, 0, 0, 15, 0, 0,
+	7, 93, 30, 0, 31, 0, 0, 0, 29, 0,
+	6, 19, 20, 21, 18, 22, 52, 51, 14, 32,
+	0, 23, 47, 48, 49, 50, 0, 15, 7, 0,
+	30, 0, 31, 0, 0, 0, 29, 0, 6, 19,
+	20, 21, 18, 22, 0, 0, 14, 32, 88, 23,
+	0, 0, 0, 0, 0, 15, 7, 0, 30, 69,
+	31, 0, 0, 0, 29, 0, 41, 19, 20, 21,
+	18, 22, 0, 0, 14, 32, 0, 23, 0, 0,
+	0, 0, 0, 15, 7, 39, 30, 0, 31, 0,
+	0, 0, 29, 0, 6, 19, 20, 21, 18, 22,
+	0, 0, 14, 32, 0, 23, 0, 0, 0, 0,
+	0, 15, 7, 0, 30, 79, 31, 80, 75, 76,
+	29, 0, 60, 19, 20, 21, 18, 22, 0, 0,
+	14, 32, 0, 23, 0, 0, 0, 77, 0, 15,
+	61, 0, 30, 0, 31, 0, 0, 0, 0, 0,
+	0, 0, 0, 78, 81, 82, 83, 84, 85, 32,
+	0, 0, 0, 0, 0, 0, 0, 15,
 }
 
 var yyPact = [...]int16{
-	116, -1000, -1000, 82, 62, 127, -23, -27, -1000, 116,
-	116, 28, 36, -1000, -1000, -1000, -1000, 116, -1000, -1000,
-	-1000, 96, 116, 116, 116, 116, 116, 116, 116, 116,
-	116, 44, 116, 116, 116, 116, -1000, -1000, 56, 31,
-	78, 25, 19, -1000, -1000, 62, 62, 127, 127, -23,
-	-23, -23, -23, -23, 116, -27, -27, -1000, -1000, 29,
-	-8, 22, 116, -3, -1000, -1000, -1000, -1000, 116, -23,
-	60, -1000, 4, -1000, 5, -1000, 116, -1000, -1000, -4,
-	2, -1000, -1000, -1000, -1000, -1000,
+	350, -32768, 18, -32768, -32768, 48, 26, 322, 164, 192,
+	264, -34, -28, -32768, 378, 378, -32768, 58, -32768, -32768,
+	-32768, -32768, -32768, -32768, -32768, -32768, -32768, -32768, -32768, -32768,
+	294, 127, 266, -32768, -32768, 350, 378, 350, 238, -2,
+	154, 88, 378, 378, 378, 378, 147, 378, 378, 378,
+	378, 378, 145, 378, 378, 378, 378, 378, 378, -32768,
+	133, 350, -32768, 378, 190, 208, 187, 178, 124, -32768,
+	-32768, 98, -32768, -32768, 56, 55, 43, 350, 350, -32768,
+	-32768, -32768, -32768, -32768, -32768, -32768, -25, -17, 350, 19,
+	164, -32768, 176, -32768, -32768, 350, -7, 90, 177, 192,
+	192, 264, 264, -32768, 142, -34, -34, -34, -34, -34,
+	378, -28, -28, -32768, -32768, -32768, -32768, 141, -32768, 122,
+	99, 93, 91, 17, 118, 89, 86, 83, -32768, 350,
+	-32768, -32768, 373, -32768, 350, 350, 350, 42, -32768, -27,
+	350, 350, -25, -22, 350, -32768, 350, -32768, -32768, 350,
+	-32768, 69, -32768, -32768, -34, -32768, 97, -32768, -32768, -32768,
+	350, 35, -32768, -32768, -32768, -32768, -32768, -32768, -32768, -32768,
+	3, -32768, -32, -32768, -29, 350, -32768, -32768, -32768, -8,
+	162, -32768, 150, 2, -32768, -32768, 105, 350, 350, -32768,
+	-32768, 350, -32768, -32768, 350, -32768, -32768, -32768, -32768, -32768,
 }
 
-var yyPgo = [...]int8{
-	0, 0, 109, 70, 28, 2, 13, 95, 96, 88,
-	87, 78, 3, 72, 62,
+var yyPgo = [...]uint8{
+	0, 0, 233, 231, 229, 228, 5, 46, 30, 45,
+	4, 9, 13, 226, 225, 224, 222, 221, 218, 12,
+	2, 3, 207, 203, 7, 6, 201, 198, 196,
 }
 
 var yyR1 = [...]int8{
-	0, 14, 1, 2, 2, 2, 3, 3, 3, 4,
-	4, 4, 4, 4, 4, 4, 5, 5, 5, 6,
-	6, 6, 7, 7, 7, 8, 8, 8, 8, 8,
-	8, 8, 8, 8, 9, 9, 9, 9, 9, 9,
-	10, 10, 10, 10, 11, 11, 12, 12, 13, 13,
+	0, 28, 28, 1, 1, 3, 3, 4, 4, 2,
+	2, 2, 2, 23, 23, 7, 7, 7, 8, 8,
+	8, 8, 8, 9, 9, 9, 9, 9, 9, 9,
+	10, 10, 10, 11, 11, 11, 11, 11, 12, 12,
+	12, 13, 13, 14, 14, 14, 14, 14, 14, 14,
+	14, 14, 14, 14, 14, 14, 14, 14, 5, 5,
+	24, 24, 6, 6, 15, 15, 15, 15, 15, 15,
+	15, 15, 19, 19, 20, 20, 16, 16, 16, 16,
+	16, 16, 17, 17, 18, 18, 26, 26, 26, 25,
+	25, 25, 25, 25, 25, 27, 27, 27, 27, 27,
+	27, 21, 21, 21, 22, 22, 22,
 }
 
 var yyR2 = [...]int8{
-	0, 1, 1, 3, 3, 1, 3, 3, 1, 3,
-	3, 3, 3, 3, 4, 1, 3, 3, 1, 3,
-	3, 1, 2, 2, 1, 1, 1, 1, 1, 1,
-	3, 1, 1, 1, 3, 4, 4, 6, 5, 5,
-	4, 3, 5, 6, 3, 2, 1, 3, 1, 3,
+	0, 2, 2, 1, 1, 5, 1, 3, 1, 3,
+	4, 5, 7, 1, 3, 3, 3, 1, 3, 3,
+	3, 4, 1, 3, 3, 3, 3, 3, 4, 1,
+	3, 3, 1, 3, 3, 3, 3, 1, 2, 2,
+	1, 3, 1, 1, 1, 1, 1, 1, 1, 1,
+	3, 4, 1, 1, 1, 1, 1, 1, 4, 5,
+	4, 5, 0, 2, 3, 4, 4, 4, 3, 4,
+	4, 4, 3, 5, 0, 1, 4, 3, 5, 6,
+	5, 6, 3, 2, 3, 2, 1, 3, 2, 3,
+	3, 3, 5, 2, 1, 1, 1, 1, 1, 1,
+	1, 1, 3, 2, 1, 3, 2,
 }
 
 var yyChk = [...]int16{
-	-1000, -14, -1, -2, -3, -4, -5, -6, -7, 11,
-	30, -8, 4, 7, 5, 6, 8, 19, -9, -10,
-	-11, 21, 9, 10, 13, 14, 15, 16, 17, 18,
-	12, 11, 29, 30, 31, 32, -7, -7, 23, 21,
-	19, -1, -13, 22, -1, -3, -3, -4, -4, -5,
-	-5, -5, -5, -5, 12, -6, -6, -7, -7, 4,
-	-1, 28, 27, -12, 20, -1, 20, 22, 24, -5,
-	19, 22, 27, 22, -1, 20, 24, -1, 20, -12,
-	-1, 22, 22, -1, 20, 22,
+	-32768, -28, -1, -3, -2, -4, 4, 22, -7, -8,
+	-9, -10, -11, -12, 12, 49, -13, -14, 8, 5,
+	6, 7, 9, 15, -15, -16, -17, -18, -5, 2,
+	24, 26, 41, 47, 2, 33, 37, 34, 22, 23,
+	-1, 4, 10, 11, 16, 17, 14, 18, 19, 20,
+	21, 13, 12, 48, 49, 50, 51, 52, 38, -12,
+	4, 22, -12, 39, 28, 24, 35, 36, -22, 25,
+	-1, -26, 27, -25, -27, 5, 6, 24, 40, 2,
+	4, 41, 42, 43, 44, 45, -24, -1, 42, -1,
+	-7, -1, -21, 23, -1, 34, 23, 2, 29, -8,
+	-8, -9, -9, 15, 12, -10, -10, -10, -10, -10,
+	13, -11, -11, -12, -12, -12, -12, -1, -12, 4,
+	-1, 33, -19, -20, 4, -1, 33, -19, 25, 29,
+	2, 27, 29, 2, 32, 32, 32, -1, -1, -6,
+	42, 44, -24, -1, 32, 23, 29, 2, -1, 34,
+	23, -23, 4, 15, -10, 23, 22, 25, 25, 25,
+	32, 22, 25, 25, 25, -1, -25, -1, -1, -1,
+	25, 45, -1, -1, -6, 43, -1, -1, -1, 23,
+	29, 23, -21, -20, -1, 23, -21, 32, 43, 45,
+	-1, 34, 4, 23, 32, 23, -1, -1, -1, -20,
 }
 
 var yyDef = [...]int8{
-	0, -2, 1, 2, 5, 8, 15, 18, 21, 0,
-	0, 24, 25, 26, 27, 28, 29, 0, 31, 32,
-	33, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 22, 23, 0, 0,
-	0, 0, 0, 45, 48, 3, 4, 6, 7, 9,
-	10, 11, 12, 13, 0, 16, 17, 19, 20, 34,
-	0, 0, 0, 0, 41, 46, 30, 44, 0, 14,
-	0, 35, 0, 36, 0, 40, 0, 49, 42, 0,
-	0, 39, 38, 47, 43, 37,
+	0, -2, 0, 3, 4, 6, 43, 0, 8, 17,
+	22, 29, 32, 37, 0, 0, 40, 42, 44, 45,
+	46, 47, 48, 49, 52, 53, 54, 55, 56, 57,
+	0, 0, 0, 1, 2, 0, 0, 0, 0, 0,
+	0, 43, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 38,
+	43, 0, 39, 0, 0, -2, 0, -2, 0, 83,
+	104, 0, 85, 86, 0, 0, 0, 0, 0, 94,
+	95, 96, 97, 98, 99, 100, 62, 0, 0, 0,
+	7, 9, 0, 77, 101, 0, 50, 0, 0, 15,
+	16, 18, 19, 20, 0, 23, 24, 25, 26, 27,
+	0, 30, 31, 33, 34, 35, 36, 0, 41, 64,
+	75, 0, 0, 0, 68, 75, 0, 0, 82, 0,
+	106, 84, 0, 88, 0, 0, 0, 0, 93, 0,
+	0, 0, 62, 0, 0, 76, 0, 103, 10, 0,
+	51, 0, 13, 21, 28, 50, 0, 65, 66, 67,
+	-2, 0, 69, 70, 71, 105, 87, 89, 90, 91,
+	0, 58, 0, 63, 0, 0, 5, 102, 11, 0,
+	0, 78, 0, 72, 75, 80, 0, 0, 0, 59,
+	60, 0, 14, 79, -2, 81, 92, 61, 12, 73,
 }
 
 var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 31, 29, 3, 30, 3, 32,
+	3, 3, 3, 3, 3, 3, 3, 52, 3, 3,
+	3, 3, 50, 48, 3, 49, 3, 51,
 }
 
 var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
-	22, 23, 24, 25, 26, 27, 28, 33,
+	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
+	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
+	42, 43, 44, 45, 46, 47, 53,
 }
 
 var yyTok3 = [...]int8{
@@ -257,7 +453,7 @@ func yyNewParser() yyParser {
 	return &yyParserImpl{}
 }
 
-const yyFlag = -1000
+const yyFlag = -32768
 
 func yyTokname(c int) string {
 	if c >= 1 && c-1 < len(yyToknames) {
@@ -556,299 +752,653 @@ yydefault:
 	switch yynt {
 
 	case 1:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:55
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:74
 		{
 			yylex.(*yyLex).result = yyDollar[1].expr
 		}
 	case 2:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:57
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:75
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yylex.(*yyLex).result = yyDollar[1].expr
 		}
 	case 3:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:59
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:77
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "and"}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 4:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:62
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:78
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "or"}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 5:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:65
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:80
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &ConditionalNode{Condition: yyDollar[1].expr, Then: yyDollar[3].expr, Else: yyDollar[5].expr, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[5].expr).End}}
 		}
 	case 6:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:67
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:83
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "="}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 7:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:70
+//line lang.y:85
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "!="}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "??", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 8:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:73
+//line lang.y:88
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:75
+//line lang.y:90
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "<"}
+			yyVAL.expr = &LambdaNode{Params: []string{yyDollar[1].str}, Body: yyDollar[3].expr, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 10:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:78
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:93
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "<="}
+			yyVAL.expr = &LambdaNode{Params: []string{}, Body: yyDollar[4].expr, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[4].expr).End}}
 		}
 	case 11:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:81
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:96
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: ">"}
+			variable, ok := yyDollar[2].expr.(*VariableNode)
+			if !ok {
+				yylex.(*yyLex).errorSpan(SpanOf(yyDollar[2].expr), "syntax error: lambda parameters must be identifiers")
+				yyVAL.expr = &BadNode{Span: Span{yyDollar[1].pos, SpanOf(yyDollar[5].expr).End}}
+			} else {
+				yyVAL.expr = &LambdaNode{Params: []string{variable.Name}, Body: yyDollar[5].expr, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[5].expr).End}}
+			}
 		}
 	case 12:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:84
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line lang.y:105
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: ">="}
+			yyVAL.expr = &LambdaNode{Params: append([]string{yyDollar[2].str}, yyDollar[4].strList...), Body: yyDollar[7].expr, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[7].expr).End}}
 		}
 	case 13:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:87
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:109
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "in"}
+			yyVAL.strList = []string{yyDollar[1].str}
 		}
 	case 14:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line lang.y:90
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:112
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[4].expr, Operator: "not in"}
+			yyVAL.strList = append(yyDollar[1].strList, yyDollar[3].str)
 		}
 	case 15:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:93
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:116
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "and", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:95
+//line lang.y:119
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "+"}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "or", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 17:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:98
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:122
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "-"}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 18:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:101
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:124
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "=", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 19:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:103
+//line lang.y:127
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "*"}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "!=", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 20:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:106
+//line lang.y:130
 		{
-			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "/"}
+			yyVAL.expr = &UnaryOpNode{Operand: yyDollar[1].expr, Operator: "is null", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, yyDollar[3].end}}
 		}
 	case 21:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:109
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:133
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &UnaryOpNode{Operand: yyDollar[1].expr, Operator: "is not null", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
 		}
 	case 22:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line lang.y:111
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:136
 		{
-			yyVAL.expr = &UnaryOpNode{Operand: yyDollar[2].expr, Operator: "not"}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 23:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line lang.y:114
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:138
 		{
-			yyVAL.expr = &UnaryOpNode{Operand: yyDollar[2].expr, Operator: "-"}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "<", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 24:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:117
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:141
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "<=", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 25:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:119
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:144
 		{
-			yyVAL.expr = &VariableNode{Name: yyDollar[1].str}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: ">", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 26:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:122
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:147
 		{
-			yyVAL.expr = &LiteralNode{Value: NumberValue(yyDollar[1].num)}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: ">=", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 27:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:125
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:150
 		{
-			yyVAL.expr = &LiteralNode{Value: StringValue(yyDollar[1].str)}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "in", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 28:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:128
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:153
 		{
-			yyVAL.expr = &LiteralNode{Value: StringValue(yyDollar[1].str)}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[4].expr, Operator: "not in", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[4].expr).End}}
 		}
 	case 29:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:131
+//line lang.y:156
 		{
-			yyVAL.expr = &LiteralNode{Value: BoolValue(yyDollar[1].boolean)}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 30:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:134
+//line lang.y:158
 		{
-			yyVAL.expr = yyDollar[2].expr
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "+", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 31:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:137
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:161
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "-", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 32:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:138
+//line lang.y:164
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
 	case 33:
-		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:139
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:166
 		{
-			yyVAL.expr = yyDollar[1].expr
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "*", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:141
+//line lang.y:169
 		{
-			yyVAL.expr = &FieldAccessNode{Object: yyDollar[1].expr, Field: yyDollar[3].str}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "/", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 35:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line lang.y:144
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:172
 		{
-			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: yyDollar[3].expr}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "%", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 36:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line lang.y:147
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:175
 		{
-			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: &EachNode{}}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "//", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 37:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line lang.y:150
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:178
 		{
-			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: &RangeNode{Begin: yyDollar[3].expr, End: yyDollar[5].expr}}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 38:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line lang.y:153
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:180
 		{
-			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: &RangeNode{Begin: NumberValue(0), End: yyDollar[4].expr}}
+			yyVAL.expr = &UnaryOpNode{Operand: yyDollar[2].expr, Operator: "not", Pos: yyDollar[1].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[2].expr).End}}
 		}
 	case 39:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line lang.y:156
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:183
 		{
-			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: &RangeNode{Begin: yyDollar[3].expr, End: NumberValue(-1)}}
+			yyVAL.expr = &UnaryOpNode{Operand: yyDollar[2].expr, Operator: "-", Pos: yyDollar[1].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[2].expr).End}}
 		}
 	case 40:
-		yyDollar = yyS[yypt-4 : yypt+1]
-//line lang.y:160
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:186
 		{
-			yyVAL.expr = &FunctionCallNode{Name: yyDollar[1].str, Args: yyDollar[3].exprList}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 41:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:163
+//line lang.y:188
 		{
-			yyVAL.expr = &FunctionCallNode{Name: yyDollar[1].str, Args: []ExprNode{}}
+			yyVAL.expr = &BinaryOpNode{Left: yyDollar[1].expr, Right: yyDollar[3].expr, Operator: "**", Pos: yyDollar[2].pos, Span: Span{yyDollar[1].pos, SpanOf(yyDollar[3].expr).End}}
 		}
 	case 42:
-		yyDollar = yyS[yypt-5 : yypt+1]
-//line lang.y:166
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:191
 		{
-			yyVAL.expr = &FunctionCallNode{Namespace: yyDollar[1].expr, Name: yyDollar[3].str, Args: []ExprNode{}}
+			yyVAL.expr = yyDollar[1].expr
 		}
 	case 43:
-		yyDollar = yyS[yypt-6 : yypt+1]
-//line lang.y:169
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:193
 		{
-			yyVAL.expr = &FunctionCallNode{Namespace: yyDollar[1].expr, Name: yyDollar[3].str, Args: yyDollar[5].exprList}
+			yyVAL.expr = &VariableNode{Name: yyDollar[1].str, Pos: yyDollar[1].pos, Span: Span{yyDollar[1].pos, yyDollar[1].end}}
 		}
 	case 44:
-		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:173
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:196
 		{
-			yyVAL.expr = &ListNode{Elements: yyDollar[2].exprList}
+			yyVAL.expr = &LiteralNode{Value: NumberValue(yyDollar[1].num), Span: Span{yyDollar[1].pos, yyDollar[1].end}}
 		}
 	case 45:
-		yyDollar = yyS[yypt-2 : yypt+1]
-//line lang.y:176
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:199
 		{
-			yyVAL.expr = &ListNode{Elements: []ExprNode{}}
+			yyVAL.expr = &LiteralNode{Value: StringValue(yyDollar[1].str), Span: Span{yyDollar[1].pos, yyDollar[1].end}}
 		}
 	case 46:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:180
+//line lang.y:202
 		{
-			yyVAL.exprList = []ExprNode{yyDollar[1].expr}
+			yyVAL.expr = &LiteralNode{Value: StringValue(yyDollar[1].str), Span: Span{yyDollar[1].pos, yyDollar[1].end}}
 		}
 	case 47:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:205
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 48:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:206
+		{
+			yyVAL.expr = &LiteralNode{Value: BoolValue(yyDollar[1].boolean), Span: Span{yyDollar[1].pos, yyDollar[1].end}}
+		}
+	case 49:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:209
+		{
+			yyVAL.expr = &LiteralNode{Value: nil, Span: Span{yyDollar[1].pos, yyDollar[1].end}}
+		}
+	case 50:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:183
+//line lang.y:212
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 51:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:215
+		{
+			// The tokens after the expression are dropped, and the error for
+			// the first of them is reported
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 52:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:220
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 53:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:221
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 54:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:222
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 55:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:223
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 56:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:224
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 57:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:225
+		{
+			node := yylex.(*yyLex).badNode()
+			yyVAL.pos = node.Span.Start
+			yyVAL.expr = node
+		}
+	case 58:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:231
+		{
+			yyVAL.expr = &CaseNode{Whens: yyDollar[2].whenList, Else: yyDollar[3].expr, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 59:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:234
+		{
+			yyVAL.expr = &CaseNode{Subject: yyDollar[2].expr, Whens: yyDollar[3].whenList, Else: yyDollar[4].expr, Span: Span{yyDollar[1].pos, yyDollar[5].end}}
+		}
+	case 60:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:238
+		{
+			yyVAL.whenList = []WhenClause{{Condition: yyDollar[2].expr, Result: yyDollar[4].expr}}
+		}
+	case 61:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:241
+		{
+			yyVAL.whenList = append(yyDollar[1].whenList, WhenClause{Condition: yyDollar[3].expr, Result: yyDollar[5].expr})
+		}
+	case 62:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:245
+		{
+			yyVAL.expr = nil
+		}
+	case 63:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:246
+		{
+			yyVAL.expr = yyDollar[2].expr
+		}
+	case 64:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:248
+		{
+			yyVAL.expr = &FieldAccessNode{Object: yyDollar[1].expr, Field: yyDollar[3].str, Span: Span{yyDollar[1].pos, yyDollar[3].end}}
+		}
+	case 65:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:251
+		{
+			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: yyDollar[3].expr, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 66:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:254
+		{
+			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: &EachNode{Span: Span{yyDollar[3].pos, yyDollar[3].end}}, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 67:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:257
+		{
+			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: yyDollar[3].expr, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 68:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:260
+		{
+			yyVAL.expr = &FieldAccessNode{Object: yyDollar[1].expr, Field: yyDollar[3].str, Optional: true, Span: Span{yyDollar[1].pos, yyDollar[3].end}}
+		}
+	case 69:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:263
+		{
+			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: yyDollar[3].expr, Optional: true, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 70:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:266
+		{
+			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: &EachNode{Span: Span{yyDollar[3].pos, yyDollar[3].end}}, Optional: true, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 71:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:269
+		{
+			yyVAL.expr = &IndexAccessNode{Object: yyDollar[1].expr, Index: yyDollar[3].expr, Optional: true, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 72:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:273
+		{
+			yyVAL.expr = &RangeNode{Begin: yyDollar[1].expr, End: yyDollar[3].expr, Span: cover(Span{yyDollar[2].pos, yyDollar[2].end}, yyDollar[1].expr, yyDollar[3].expr)}
+		}
+	case 73:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:276
+		{
+			yyVAL.expr = &RangeNode{Begin: yyDollar[1].expr, End: yyDollar[3].expr, Step: yyDollar[5].expr, Span: cover(Span{yyDollar[2].pos, yyDollar[4].end}, yyDollar[1].expr, yyDollar[3].expr, yyDollar[5].expr)}
+		}
+	case 74:
+		yyDollar = yyS[yypt-0 : yypt+1]
+//line lang.y:280
+		{
+			yyVAL.expr = nil
+		}
+	case 75:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:281
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 76:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line lang.y:283
+		{
+			yyVAL.expr = &FunctionCallNode{Name: yyDollar[1].str, Args: yyDollar[3].exprList, Pos: yyDollar[1].pos, Span: Span{yyDollar[1].pos, yyDollar[4].end}}
+		}
+	case 77:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:286
+		{
+			yyVAL.expr = &FunctionCallNode{Name: yyDollar[1].str, Args: []ExprNode{}, Pos: yyDollar[1].pos, Span: Span{yyDollar[1].pos, yyDollar[3].end}}
+		}
+	case 78:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:289
+		{
+			yyVAL.expr = &FunctionCallNode{Namespace: yyDollar[1].expr, Name: yyDollar[3].str, Args: []ExprNode{}, Pos: yyDollar[3].pos, Span: Span{yyDollar[1].pos, yyDollar[5].end}}
+		}
+	case 79:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line lang.y:292
+		{
+			yyVAL.expr = &FunctionCallNode{Namespace: yyDollar[1].expr, Name: yyDollar[3].str, Args: yyDollar[5].exprList, Pos: yyDollar[3].pos, Span: Span{yyDollar[1].pos, yyDollar[6].end}}
+		}
+	case 80:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:295
+		{
+			yyVAL.expr = &FunctionCallNode{Namespace: yyDollar[1].expr, Name: yyDollar[3].str, Args: []ExprNode{}, Optional: true, Pos: yyDollar[3].pos, Span: Span{yyDollar[1].pos, yyDollar[5].end}}
+		}
+	case 81:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line lang.y:298
+		{
+			yyVAL.expr = &FunctionCallNode{Namespace: yyDollar[1].expr, Name: yyDollar[3].str, Args: yyDollar[5].exprList, Optional: true, Pos: yyDollar[3].pos, Span: Span{yyDollar[1].pos, yyDollar[6].end}}
+		}
+	case 82:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:302
+		{
+			yyVAL.expr = &ListNode{Elements: yyDollar[2].exprList, Span: Span{yyDollar[1].pos, yyDollar[3].end}}
+		}
+	case 83:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:305
+		{
+			yyVAL.expr = &ListNode{Elements: []ExprNode{}, Span: Span{yyDollar[1].pos, yyDollar[2].end}}
+		}
+	case 84:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:309
+		{
+			yyVAL.expr = &MapNode{Entries: yyDollar[2].entries, Span: Span{yyDollar[1].pos, yyDollar[3].end}}
+		}
+	case 85:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:312
+		{
+			yyVAL.expr = &MapNode{Entries: []MapEntry{}, Span: Span{yyDollar[1].pos, yyDollar[2].end}}
+		}
+	case 86:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:316
+		{
+			yyVAL.entries = []MapEntry{yyDollar[1].entry}
+		}
+	case 87:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:319
+		{
+			yyVAL.entries = append(yyDollar[1].entries, yyDollar[3].entry)
+		}
+	case 88:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:322
+		{
+			yyVAL.entries = append(yyDollar[1].entries, MapEntry{Value: yylex.(*yyLex).badNode(), Spread: true})
+		}
+	case 89:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:326
+		{
+			yyVAL.entry = MapEntry{Key: &LiteralNode{Value: StringValue(yyDollar[1].str), Span: Span{yyDollar[1].pos, yyDollar[1].end}}, Value: yyDollar[3].expr}
+		}
+	case 90:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:329
+		{
+			yyVAL.entry = MapEntry{Key: &LiteralNode{Value: StringValue(yyDollar[1].str), Span: Span{yyDollar[1].pos, yyDollar[1].end}}, Value: yyDollar[3].expr}
+		}
+	case 91:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:332
+		{
+			yyVAL.entry = MapEntry{Key: &LiteralNode{Value: StringValue(yyDollar[1].str), Span: Span{yyDollar[1].pos, yyDollar[1].end}}, Value: yyDollar[3].expr}
+		}
+	case 92:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line lang.y:335
+		{
+			yyVAL.entry = MapEntry{Key: yyDollar[2].expr, Value: yyDollar[5].expr}
+		}
+	case 93:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:338
+		{
+			yyVAL.entry = MapEntry{Value: yyDollar[2].expr, Spread: true}
+		}
+	case 94:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:341
+		{
+			// A malformed entry spreads a BadNode, so that evaluating it fails
+			// there
+			yyVAL.entry = MapEntry{Value: yylex.(*yyLex).badNode(), Spread: true}
+		}
+	case 95:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:349
+		{
+			yyVAL.str = yyDollar[1].str
+		}
+	case 96:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:352
+		{
+			yyVAL.str = "case"
+		}
+	case 97:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:355
+		{
+			yyVAL.str = "when"
+		}
+	case 98:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:358
+		{
+			yyVAL.str = "then"
+		}
+	case 99:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:361
+		{
+			yyVAL.str = "else"
+		}
+	case 100:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:364
+		{
+			yyVAL.str = "end"
+		}
+	case 101:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line lang.y:368
+		{
+			yyVAL.exprList = []ExprNode{yyDollar[1].expr}
+		}
+	case 102:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line lang.y:371
 		{
 			yyVAL.exprList = append(yyDollar[1].exprList, yyDollar[3].expr)
 		}
-	case 48:
+	case 103:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:374
+		{
+			yyVAL.exprList = append(yyDollar[1].exprList, yylex.(*yyLex).badNode())
+		}
+	case 104:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line lang.y:187
+//line lang.y:378
 		{
 			yyVAL.exprList = []ExprNode{yyDollar[1].expr}
 		}
-	case 49:
+	case 105:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line lang.y:190
+//line lang.y:381
 		{
 			yyVAL.exprList = append(yyDollar[1].exprList, yyDollar[3].expr)
 		}
+	case 106:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line lang.y:384
+		{
+			yyVAL.exprList = append(yyDollar[1].exprList, yylex.(*yyLex).badNode())
+		}
 	}
 	goto yystack /* stack new state and value */
 }
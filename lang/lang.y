@@ -16,165 +16,353 @@
  */
 package lang
 
+import "sort"
 %}
 
 %union {
     expr     ExprNode
     exprList []ExprNode
+    strList  []string
+    whenList []WhenClause
+    entry    MapEntry
+    entries  []MapEntry
     str      string
     num      float64
     boolean  bool
+    pos      int
+    end      int
 }
 
 %token <str> IDENTIFIER STRING DSTRING
+%token <expr> TEMPLATE
 %token <num> NUMBER
 %token <boolean> BOOLEAN
 
-%token AND OR NOT IN
+%token AND OR NOT IN IS NULL
 %token EQ NE LT LE GT GE
-%token LPAREN RPAREN LBRACKET RBRACKET
+%token LPAREN RPAREN LBRACKET RBRACKET LBRACE RBRACE
 %token DOT COMMA QUOTE DQUOTE COLON
-%token QMARK
+%token QMARK ARROW QDOT QLBRACKET COALESCE IDIV POW ELLIPSIS
+%token CASE WHEN THEN ELSE END
+%token ILLEGAL EOI
 
-%type <expr> expr logical_expr equality_expr relational_expr additive_expr multiplicative_expr unary_expr primary_expr
-%type <expr> field_access function_call list_literal
+%type <expr> expr lambda conditional_expr coalesce_expr case_expr else_clause logical_expr equality_expr relational_expr additive_expr multiplicative_expr unary_expr power_expr primary_expr
+%type <expr> field_access function_call list_literal map_literal slice optional_expr
 %type <exprList> argument_list expression_list
+%type <strList> parameter_list
+%type <whenList> when_list
+%type <entry> map_entry
+%type <entries> map_entries
+%type <str> map_key
 
+%left COALESCE
 %left OR
 %left AND
 %left IN
-%left EQ NE
+%left EQ NE IS
 %left LT LE GT GE
 %left '+' '-'
-%left '*' '/'
+%left '*' '/' '%' IDIV
 %right NOT UMINUS
-%left DOT LBRACKET
+%right POW
+%left DOT LBRACKET QDOT QLBRACKET
 
 %%
 
-program: expr { yylex.(*yyLex).result = $1 }
+// The lexer ends every input with EOI, so that tokens left over after a
+// whole expression are an error the parser recovers from.
+program: expr EOI { yylex.(*yyLex).result = $1 }
+    | expr error { yylex.(*yyLex).result = $1 }
 
-expr: logical_expr { $$ = $1 }
+expr: conditional_expr { $$ = $1 }
+    | lambda { $$ = $1 }
+
+conditional_expr: coalesce_expr QMARK expr COLON expr {
+        $$ = &ConditionalNode{Condition: $1, Then: $3, Else: $5, Span: Span{$<pos>1, SpanOf($5).End}}
+    }
+    | coalesce_expr { $$ = $1 }
+
+coalesce_expr: coalesce_expr COALESCE logical_expr {
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "??", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
+    }
+    | logical_expr { $$ = $1 }
+
+lambda: IDENTIFIER ARROW expr {
+        $$ = &LambdaNode{Params: []string{$1}, Body: $3, Span: Span{$<pos>1, SpanOf($3).End}}
+    }
+    | LPAREN RPAREN ARROW expr {
+        $$ = &LambdaNode{Params: []string{}, Body: $4, Span: Span{$<pos>1, SpanOf($4).End}}
+    }
+    | LPAREN expr RPAREN ARROW expr {
+        variable, ok := $2.(*VariableNode)
+        if !ok {
+            yylex.(*yyLex).errorSpan(SpanOf($2), "syntax error: lambda parameters must be identifiers")
+            $$ = &BadNode{Span: Span{$<pos>1, SpanOf($5).End}}
+        } else {
+            $$ = &LambdaNode{Params: []string{variable.Name}, Body: $5, Span: Span{$<pos>1, SpanOf($5).End}}
+        }
+    }
+    | LPAREN IDENTIFIER COMMA parameter_list RPAREN ARROW expr {
+        $$ = &LambdaNode{Params: append([]string{$2}, $4...), Body: $7, Span: Span{$<pos>1, SpanOf($7).End}}
+    }
+
+parameter_list: IDENTIFIER {
+        $$ = []string{$1}
+    }
+    | parameter_list COMMA IDENTIFIER {
+        $$ = append($1, $3)
+    }
 
 logical_expr: logical_expr AND equality_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "and"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "and", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | logical_expr OR equality_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "or"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "or", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | equality_expr { $$ = $1 }
 
 equality_expr: equality_expr EQ relational_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "="}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "=", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | equality_expr NE relational_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "!="}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "!=", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
+    }
+    | equality_expr IS NULL {
+        $$ = &UnaryOpNode{Operand: $1, Operator: "is null", Pos: $<pos>2, Span: Span{$<pos>1, $<end>3}}
+    }
+    | equality_expr IS NOT NULL {
+        $$ = &UnaryOpNode{Operand: $1, Operator: "is not null", Pos: $<pos>2, Span: Span{$<pos>1, $<end>4}}
     }
     | relational_expr { $$ = $1 }
 
 relational_expr: relational_expr LT additive_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "<"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "<", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | relational_expr LE additive_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "<="}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "<=", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | relational_expr GT additive_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: ">"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: ">", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | relational_expr GE additive_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: ">="}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: ">=", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | relational_expr IN additive_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "in"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "in", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | relational_expr NOT IN additive_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $4, Operator: "not in"}
+        $$ = &BinaryOpNode{Left: $1, Right: $4, Operator: "not in", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($4).End}}
     }
     | additive_expr { $$ = $1 }
 
 additive_expr: additive_expr '+' multiplicative_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "+"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "+", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | additive_expr '-' multiplicative_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "-"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "-", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | multiplicative_expr { $$ = $1 }
 
 multiplicative_expr: multiplicative_expr '*' unary_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "*"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "*", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | multiplicative_expr '/' unary_expr {
-        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "/"}
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "/", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
+    }
+    | multiplicative_expr '%' unary_expr {
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "%", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
+    }
+    | multiplicative_expr IDIV unary_expr {
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "//", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | unary_expr { $$ = $1 }
 
 unary_expr: NOT unary_expr {
-        $$ = &UnaryOpNode{Operand: $2, Operator: "not"}
+        $$ = &UnaryOpNode{Operand: $2, Operator: "not", Pos: $<pos>1, Span: Span{$<pos>1, SpanOf($2).End}}
     }
     | '-' unary_expr %prec UMINUS {
-        $$ = &UnaryOpNode{Operand: $2, Operator: "-"}
+        $$ = &UnaryOpNode{Operand: $2, Operator: "-", Pos: $<pos>1, Span: Span{$<pos>1, SpanOf($2).End}}
+    }
+    | power_expr { $$ = $1 }
+
+power_expr: primary_expr POW unary_expr {
+        $$ = &BinaryOpNode{Left: $1, Right: $3, Operator: "**", Pos: $<pos>2, Span: Span{$<pos>1, SpanOf($3).End}}
     }
     | primary_expr { $$ = $1 }
 
 primary_expr: IDENTIFIER { 
-        $$ = &VariableNode{Name: $1}
+        $$ = &VariableNode{Name: $1, Pos: $<pos>1, Span: Span{$<pos>1, $<end>1}}
     }
     | NUMBER {
-        $$ = &LiteralNode{Value: NumberValue($1)}
+        $$ = &LiteralNode{Value: NumberValue($1), Span: Span{$<pos>1, $<end>1}}
     }
     | STRING {
-        $$ = &LiteralNode{Value: StringValue($1)}
+        $$ = &LiteralNode{Value: StringValue($1), Span: Span{$<pos>1, $<end>1}}
     }
     | DSTRING {
-        $$ = &LiteralNode{Value: StringValue($1)}
+        $$ = &LiteralNode{Value: StringValue($1), Span: Span{$<pos>1, $<end>1}}
     }
+    | TEMPLATE { $$ = $1 }
     | BOOLEAN {
-        $$ = &LiteralNode{Value: BoolValue($1)}
+        $$ = &LiteralNode{Value: BoolValue($1), Span: Span{$<pos>1, $<end>1}}
+    }
+    | NULL {
+        $$ = &LiteralNode{Value: nil, Span: Span{$<pos>1, $<end>1}}
     }
     | LPAREN expr RPAREN {
         $$ = $2
     }
+    | LPAREN expr error RPAREN {
+        // The tokens after the expression are dropped, and the error for
+        // the first of them is reported
+        $$ = $2
+    }
     | field_access { $$ = $1 }
     | function_call { $$ = $1 }
     | list_literal { $$ = $1 }
+    | map_literal { $$ = $1 }
+    | case_expr { $$ = $1 }
+    | error {
+        node := yylex.(*yyLex).badNode()
+        $<pos>$ = node.Span.Start
+        $$ = node
+    }
+
+case_expr: CASE when_list else_clause END {
+        $$ = &CaseNode{Whens: $2, Else: $3, Span: Span{$<pos>1, $<end>4}}
+    }
+    | CASE expr when_list else_clause END {
+        $$ = &CaseNode{Subject: $2, Whens: $3, Else: $4, Span: Span{$<pos>1, $<end>5}}
+    }
+
+when_list: WHEN expr THEN expr {
+        $$ = []WhenClause{{Condition: $2, Result: $4}}
+    }
+    | when_list WHEN expr THEN expr {
+        $$ = append($1, WhenClause{Condition: $3, Result: $5})
+    }
+
+else_clause: /* empty */ { $$ = nil }
+    | ELSE expr { $$ = $2 }
 
 field_access: primary_expr DOT IDENTIFIER {
-        $$ = &FieldAccessNode{Object: $1, Field: $3}
+        $$ = &FieldAccessNode{Object: $1, Field: $3, Span: Span{$<pos>1, $<end>3}}
     }
     | primary_expr LBRACKET expr RBRACKET {
-        $$ = &IndexAccessNode{Object: $1, Index: $3}
+        $$ = &IndexAccessNode{Object: $1, Index: $3, Span: Span{$<pos>1, $<end>4}}
     }
     | primary_expr LBRACKET QMARK RBRACKET {
-        $$ = &IndexAccessNode{Object: $1, Index: &EachNode{}}
+        $$ = &IndexAccessNode{Object: $1, Index: &EachNode{Span: Span{$<pos>3, $<end>3}}, Span: Span{$<pos>1, $<end>4}}
+    }
+    | primary_expr LBRACKET slice RBRACKET {
+        $$ = &IndexAccessNode{Object: $1, Index: $3, Span: Span{$<pos>1, $<end>4}}
+    }
+    | primary_expr QDOT IDENTIFIER {
+        $$ = &FieldAccessNode{Object: $1, Field: $3, Optional: true, Span: Span{$<pos>1, $<end>3}}
     }
-    | primary_expr LBRACKET expr COLON expr RBRACKET {
-        $$ = &IndexAccessNode{Object: $1, Index: &RangeNode{ Begin: $3, End: $5}}
+    | primary_expr QLBRACKET expr RBRACKET {
+        $$ = &IndexAccessNode{Object: $1, Index: $3, Optional: true, Span: Span{$<pos>1, $<end>4}}
     }
-    | primary_expr LBRACKET COLON expr RBRACKET {
-        $$ = &IndexAccessNode{Object: $1, Index: &RangeNode{ Begin: NumberValue(0), End: $4}}
+    | primary_expr QLBRACKET QMARK RBRACKET {
+        $$ = &IndexAccessNode{Object: $1, Index: &EachNode{Span: Span{$<pos>3, $<end>3}}, Optional: true, Span: Span{$<pos>1, $<end>4}}
     }
-    | primary_expr LBRACKET expr COLON RBRACKET {
-        $$ = &IndexAccessNode{Object: $1, Index: &RangeNode{ Begin: $3, End: NumberValue(-1)}}
+    | primary_expr QLBRACKET slice RBRACKET {
+        $$ = &IndexAccessNode{Object: $1, Index: $3, Optional: true, Span: Span{$<pos>1, $<end>4}}
     }
 
+slice: optional_expr COLON optional_expr {
+        $$ = &RangeNode{Begin: $1, End: $3, Span: cover(Span{$<pos>2, $<end>2}, $1, $3)}
+    }
+    | optional_expr COLON optional_expr COLON optional_expr {
+        $$ = &RangeNode{Begin: $1, End: $3, Step: $5, Span: cover(Span{$<pos>2, $<end>4}, $1, $3, $5)}
+    }
+
+optional_expr: /* empty */ { $$ = nil }
+    | expr { $$ = $1 }
+
 function_call: IDENTIFIER LPAREN argument_list RPAREN {
-        $$ = &FunctionCallNode{Name: $1, Args: $3}
+        $$ = &FunctionCallNode{Name: $1, Args: $3, Pos: $<pos>1, Span: Span{$<pos>1, $<end>4}}
     }
     | IDENTIFIER LPAREN RPAREN {
-        $$ = &FunctionCallNode{Name: $1, Args: []ExprNode{}}
+        $$ = &FunctionCallNode{Name: $1, Args: []ExprNode{}, Pos: $<pos>1, Span: Span{$<pos>1, $<end>3}}
     }
     | primary_expr DOT IDENTIFIER LPAREN RPAREN {
-        $$ = &FunctionCallNode{Namespace: $1, Name: $3, Args: []ExprNode{}}
+        $$ = &FunctionCallNode{Namespace: $1, Name: $3, Args: []ExprNode{}, Pos: $<pos>3, Span: Span{$<pos>1, $<end>5}}
     }
     | primary_expr DOT IDENTIFIER LPAREN argument_list RPAREN {
-        $$ = &FunctionCallNode{Namespace: $1, Name: $3, Args: $5}
+        $$ = &FunctionCallNode{Namespace: $1, Name: $3, Args: $5, Pos: $<pos>3, Span: Span{$<pos>1, $<end>6}}
+    }
+    | primary_expr QDOT IDENTIFIER LPAREN RPAREN {
+        $$ = &FunctionCallNode{Namespace: $1, Name: $3, Args: []ExprNode{}, Optional: true, Pos: $<pos>3, Span: Span{$<pos>1, $<end>5}}
+    }
+    | primary_expr QDOT IDENTIFIER LPAREN argument_list RPAREN {
+        $$ = &FunctionCallNode{Namespace: $1, Name: $3, Args: $5, Optional: true, Pos: $<pos>3, Span: Span{$<pos>1, $<end>6}}
     }
 
 list_literal: LBRACKET expression_list RBRACKET {
-        $$ = &ListNode{Elements: $2}
+        $$ = &ListNode{Elements: $2, Span: Span{$<pos>1, $<end>3}}
     }
     | LBRACKET RBRACKET {
-        $$ = &ListNode{Elements: []ExprNode{}}
+        $$ = &ListNode{Elements: []ExprNode{}, Span: Span{$<pos>1, $<end>2}}
+    }
+
+map_literal: LBRACE map_entries RBRACE {
+        $$ = &MapNode{Entries: $2, Span: Span{$<pos>1, $<end>3}}
+    }
+    | LBRACE RBRACE {
+        $$ = &MapNode{Entries: []MapEntry{}, Span: Span{$<pos>1, $<end>2}}
+    }
+
+map_entries: map_entry {
+        $$ = []MapEntry{$1}
+    }
+    | map_entries COMMA map_entry {
+        $$ = append($1, $3)
+    }
+    | map_entries error {
+        $$ = append($1, MapEntry{Value: yylex.(*yyLex).badNode(), Spread: true})
+    }
+
+map_entry: map_key COLON expr {
+        $$ = MapEntry{Key: &LiteralNode{Value: StringValue($1), Span: Span{$<pos>1, $<end>1}}, Value: $3}
+    }
+    | STRING COLON expr {
+        $$ = MapEntry{Key: &LiteralNode{Value: StringValue($1), Span: Span{$<pos>1, $<end>1}}, Value: $3}
+    }
+    | DSTRING COLON expr {
+        $$ = MapEntry{Key: &LiteralNode{Value: StringValue($1), Span: Span{$<pos>1, $<end>1}}, Value: $3}
+    }
+    | LBRACKET expr RBRACKET COLON expr {
+        $$ = MapEntry{Key: $2, Value: $5}
+    }
+    | ELLIPSIS expr {
+        $$ = MapEntry{Value: $2, Spread: true}
+    }
+    | error {
+        // A malformed entry spreads a BadNode, so that evaluating it fails
+        // there
+        $$ = MapEntry{Value: yylex.(*yyLex).badNode(), Spread: true}
+    }
+
+// Keywords are also read as map keys, so that {end: 1} keeps its meaning
+// from before case expressions reserved them.
+map_key: IDENTIFIER {
+        $$ = $1
+    }
+    | CASE {
+        $$ = "case"
+    }
+    | WHEN {
+        $$ = "when"
+    }
+    | THEN {
+        $$ = "then"
+    }
+    | ELSE {
+        $$ = "else"
+    }
+    | END {
+        $$ = "end"
     }
 
 argument_list: expr {
@@ -183,6 +371,9 @@ argument_list: expr {
     | argument_list COMMA expr {
         $$ = append($1, $3)
     }
+    | argument_list error {
+        $$ = append($1, yylex.(*yyLex).badNode())
+    }
 
 expression_list: expr {
         $$ = []ExprNode{$1}
@@ -190,15 +381,79 @@ expression_list: expr {
     | expression_list COMMA expr {
         $$ = append($1, $3)
     }
+    | expression_list error {
+        $$ = append($1, yylex.(*yyLex).badNode())
+    }
 
 %%
 
+// DefaultMaxDepth bounds the nesting depth of every parsed expression, so
+// that the passes that recurse over the AST, such as Format, Optimize and
+// Walk, cannot exhaust the stack. ParseLimited can lower it.
+const DefaultMaxDepth = 10000
+
+// ParseExpression parses input and returns its AST, or the first syntax
+// error found. Use ParsePartial to get every syntax error.
 func ParseExpression(input string) (ExprNode, error) {
+	return ParseLimited(input, Limits{})
+}
+
+// ParseLimited is ParseExpression for an expression that must not be
+// nested deeper than the MaxDepth of limits. A tree that is too deep fails
+// with a SyntaxError wrapping ErrLimitExceeded.
+func ParseLimited(input string, limits Limits) (ExprNode, error) {
+	ast, errs := parse(&yyLex{input: input, limit: limits.MaxDepth})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return ast, nil
+}
+
+// ParsePartial parses input, recovering from syntax errors, and returns
+// every error found ordered by position together with a partial AST in
+// which the parts that could not be parsed are BadNodes. Elements, call
+// arguments and map entries next to an error are kept, and brackets left
+// open at the end of the input are closed. The AST is never nil: it is a
+// single BadNode if nothing could be recovered or if it is nested deeper
+// than DefaultMaxDepth.
+func ParsePartial(input string) (ExprNode, []*SyntaxError) {
+	return parse(&yyLex{input: input})
+}
+
+func init() {
+	// Set once here rather than per parse, so that parsing is safe for
+	// concurrent use
 	yyErrorVerbose = true
-	lexer := &yyLex{input: input}
+}
+
+func parse(lexer *yyLex) (ExprNode, []*SyntaxError) {
 	yyParse(lexer)
-	if lexer.error != nil {
-		return nil, lexer.error
+	// The parser builds the tree without recursing, so it is only checked
+	// once it is whole, before anything walks it
+	if lexer.depth == 0 && lexer.result != nil {
+		if deep := deeper(lexer.result, lexer.maxDepth()); deep != nil {
+			lexer.tooDeep(Span{Start: SpanOf(deep).Start, End: SpanOf(deep).Start})
+		}
+	}
+	// A tree cut short by the depth limit is dropped, and so is nothing at
+	// all, for a BadNode that spans the whole input
+	if lexer.stop || lexer.result == nil {
+		lexer.result = &BadNode{Span: Span{Start: lexer.offset, End: lexer.offset + len(lexer.input)}}
+	}
+	sort.SliceStable(lexer.errors, func(i, j int) bool {
+		return lexer.errors[i].Offset < lexer.errors[j].Offset
+	})
+	return lexer.result, lexer.errors
+}
+
+// cover returns span widened to include the spans of the non-nil nodes.
+func cover(span Span, nodes ...ExprNode) Span {
+	for _, node := range nodes {
+		if node == nil {
+			continue
+		}
+		span.Start = min(span.Start, SpanOf(node).Start)
+		span.End = max(span.End, SpanOf(node).End)
 	}
-	return lexer.result, nil
+	return span
 }
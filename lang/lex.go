@@ -16,53 +16,175 @@
 package lang
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type yyLex struct {
 	input  string
+	source string // Enclosing source of embedded expressions
 	pos    int
+	start  int // Start of the last token read
+	offset int // Position of input within the enclosing source
+	last   int
+	bad    *Span       // Source span of the token the parser last rejected
+	limit  int         // Maximum nesting depth, see maxDepth
+	depth  int         // Nesting depth of embedded expressions
+	scan   bool        // Only finds where templates end, without parsing them
+	stop   bool        // Set once the depth is exceeded, ends the input early
+	ended  bool        // Set once EOI has been read
+	open   []int       // Closers of the brackets still open, innermost last
+	ends   map[int]int // Ends of the placeholders scanned, by source position
 	result ExprNode
-	error  error
+	errors []*SyntaxError
 }
 
 func (l *yyLex) Lex(lval *yySymType) int {
+	token := l.lex(lval)
+	switch {
+	case token == 0 && !l.stop && !l.scan:
+		{
+			token = l.end(lval)
+		}
+	case !l.ended:
+		{
+			l.track(token)
+		}
+	}
+	lval.end = l.offset + l.pos
+	l.last = token
+	return token
+}
+
+// track keeps the closers of the brackets that are open.
+func (l *yyLex) track(token int) {
+	switch token {
+	case LPAREN:
+		{
+			l.open = append(l.open, RPAREN)
+		}
+	case LBRACKET, QLBRACKET:
+		{
+			l.open = append(l.open, RBRACKET)
+		}
+	case LBRACE:
+		{
+			l.open = append(l.open, RBRACE)
+		}
+	case RPAREN, RBRACKET, RBRACE:
+		{
+			if n := len(l.open); n > 0 && l.open[n-1] == token {
+				l.open = l.open[:n-1]
+			}
+		}
+	}
+}
+
+// end returns the tokens read at the end of the input: EOI, then the
+// closers of the brackets left open, innermost first, and then 0. Only a
+// parser that is recovering from an error accepts the closers, which lets
+// it finish the brackets that are open instead of giving up.
+func (l *yyLex) end(lval *yySymType) int {
+	lval.pos = l.offset + l.pos
+	if !l.ended {
+		l.ended = true
+		return EOI
+	}
+	if n := len(l.open); n > 0 {
+		closer := l.open[n-1]
+		l.open = l.open[:n-1]
+		return closer
+	}
+	return 0
+}
+
+func (l *yyLex) lex(lval *yySymType) int {
 	// Skip whitespace
 	for l.pos < len(l.input) && (isWhitespace(l.input[l.pos])) {
 		l.pos++
 	}
+	l.start = l.pos
 
-	if l.pos >= len(l.input) {
+	if l.pos >= len(l.input) || l.stop {
 		return 0 // EOF
 	}
+	lval.pos = l.offset + l.pos
+	reported := len(l.errors)
 
-	// Check for keywords and operators
-	if matched, newPos := l.matchKeyword("and"); matched {
-		l.pos = newPos
-		return AND
-	}
-	if matched, newPos := l.matchKeyword("or"); matched {
-		l.pos = newPos
-		return OR
-	}
-	if matched, newPos := l.matchKeyword("not"); matched {
-		l.pos = newPos
-		return NOT
-	}
-	if matched, newPos := l.matchKeyword("in"); matched {
-		l.pos = newPos
-		return IN
+	// Check for keywords and operators. Keywords directly after a dot are
+	// field names, so `range.end` and `range?.end` still read as field
+	// access.
+	if l.last != DOT && l.last != QDOT {
+		if matched, newPos := l.matchKeyword("and"); matched {
+			l.pos = newPos
+			return AND
+		}
+		if matched, newPos := l.matchKeyword("or"); matched {
+			l.pos = newPos
+			return OR
+		}
+		if matched, newPos := l.matchKeyword("not"); matched {
+			l.pos = newPos
+			return NOT
+		}
+		if matched, newPos := l.matchKeyword("in"); matched {
+			l.pos = newPos
+			return IN
+		}
+		if matched, newPos := l.matchKeyword("true"); matched {
+			l.pos = newPos
+			lval.boolean = true
+			return BOOLEAN
+		}
+		if matched, newPos := l.matchKeyword("false"); matched {
+			l.pos = newPos
+			lval.boolean = false
+			return BOOLEAN
+		}
+		if matched, newPos := l.matchKeyword("null"); matched {
+			l.pos = newPos
+			return NULL
+		}
+		if matched, newPos := l.matchKeyword("is"); matched {
+			l.pos = newPos
+			return IS
+		}
+		if matched, newPos := l.matchKeyword("case"); matched {
+			l.pos = newPos
+			return CASE
+		}
+		if matched, newPos := l.matchKeyword("when"); matched {
+			l.pos = newPos
+			return WHEN
+		}
+		if matched, newPos := l.matchKeyword("then"); matched {
+			l.pos = newPos
+			return THEN
+		}
+		if matched, newPos := l.matchKeyword("else"); matched {
+			l.pos = newPos
+			return ELSE
+		}
+		if matched, newPos := l.matchKeyword("end"); matched {
+			l.pos = newPos
+			return END
+		}
 	}
-	if matched, newPos := l.matchKeyword("true"); matched {
-		l.pos = newPos
-		lval.boolean = true
-		return BOOLEAN
+
+	// Three-character operators
+	if l.pos+2 < len(l.input) && l.input[l.pos:l.pos+3] == "..." {
+		l.pos += 3
+		return ELLIPSIS
 	}
-	if matched, newPos := l.matchKeyword("false"); matched {
-		l.pos = newPos
-		lval.boolean = false
-		return BOOLEAN
+	// Optional indexing is spelled `?.[` as in JavaScript, so that a `?[`
+	// still starts a list in a conditional, as in `flag ?[1] : [2]`
+	if l.pos+2 < len(l.input) && l.input[l.pos:l.pos+3] == "?.[" {
+		l.pos += 3
+		return QLBRACKET
 	}
 
 	// Two-character operators
@@ -72,6 +194,21 @@ func (l *yyLex) Lex(lval *yySymType) int {
 		case "==":
 			l.pos += 2
 			return EQ
+		case "=>":
+			l.pos += 2
+			return ARROW
+		case "?.":
+			l.pos += 2
+			return QDOT
+		case "??":
+			l.pos += 2
+			return COALESCE
+		case "//":
+			l.pos += 2
+			return IDIV
+		case "**":
+			l.pos += 2
+			return POW
 		case "!=":
 			l.pos += 2
 			return NE
@@ -99,6 +236,12 @@ func (l *yyLex) Lex(lval *yySymType) int {
 	case ']':
 		l.pos++
 		return RBRACKET
+	case '{':
+		l.pos++
+		return LBRACE
+	case '}':
+		l.pos++
+		return RBRACE
 	case '.':
 		l.pos++
 		return DOT
@@ -117,6 +260,9 @@ func (l *yyLex) Lex(lval *yySymType) int {
 	case '/':
 		l.pos++
 		return int(ch)
+	case '%':
+		l.pos++
+		return int(ch)
 	case '=':
 		l.pos++
 		return EQ
@@ -136,6 +282,11 @@ func (l *yyLex) Lex(lval *yySymType) int {
 			l.pos = newPos
 			return token
 		}
+	case '`':
+		if token, newPos := l.readTemplate(lval); token != 0 {
+			l.pos = newPos
+			return token
+		}
 	case '?':
 		{
 			l.pos++
@@ -160,7 +311,55 @@ func (l *yyLex) Lex(lval *yySymType) int {
 			}
 		}
 	}
-	return 0
+
+	// Report what could not be read and skip over it, so that the parser
+	// can recover and later errors are found as well
+	start := l.pos
+	switch {
+	case ch == '\'' || ch == '"' || ch == '`':
+		{
+			l.pos = l.quotedEnd(start)
+			if len(l.errors) == reported && ch == '`' {
+				l.errorAt(start, l.pos, "unclosed template")
+			} else if len(l.errors) == reported {
+				l.errorAt(start, l.pos, "unclosed string")
+			}
+			return ILLEGAL
+		}
+	case isDigit(ch):
+		{
+			for l.pos < len(l.input) && (isDigit(l.input[l.pos]) || isLetter(l.input[l.pos]) || l.input[l.pos] == '_' || l.input[l.pos] == '.') {
+				l.pos++
+			}
+			return ILLEGAL
+		}
+	default:
+		{
+			r, size := utf8.DecodeRuneInString(l.input[start:])
+			l.errorAt(start, start+size, fmt.Sprintf("unexpected character '%c'", r))
+			l.pos += size
+			return l.lex(lval)
+		}
+	}
+}
+
+// quotedEnd returns the position after the string or template starting at
+// start, or the end of input if it is not closed.
+func (l *yyLex) quotedEnd(start int) int {
+	quote := l.input[start]
+	for pos := start + 1; pos < len(l.input); pos++ {
+		switch l.input[pos] {
+		case '\\':
+			{
+				pos++
+			}
+		case quote:
+			{
+				return pos + 1
+			}
+		}
+	}
+	return len(l.input)
 }
 
 func (l *yyLex) matchKeyword(keyword string) (bool, int) {
@@ -186,51 +385,312 @@ func (l *yyLex) matchKeyword(keyword string) (bool, int) {
 }
 
 func (l *yyLex) readSingleQuotedString(lval *yySymType) (int, int) {
-	start := l.pos + 1 // Skip opening quote
-	pos := start
-	for pos < len(l.input) && l.input[pos] != '\'' {
+	str, pos, ok := l.readQuoted('\'')
+	if !ok {
+		return 0, l.pos
+	}
+	lval.str = str
+	return STRING, pos
+}
+
+func (l *yyLex) readDoubleQuotedString(lval *yySymType) (int, int) {
+	str, pos, ok := l.readQuoted('"')
+	if !ok {
+		return 0, l.pos
+	}
+	lval.str = str
+	return DSTRING, pos
+}
+
+// readQuoted reads a string delimited by quote starting at l.pos and decodes
+// its escape sequences. It reports false for unclosed strings and invalid
+// escapes.
+func (l *yyLex) readQuoted(quote byte) (string, int, bool) {
+	var sb strings.Builder
+	pos := l.pos + 1 // Skip opening quote
+	for pos < len(l.input) && l.input[pos] != quote {
 		if l.input[pos] == '\\' {
-			pos++
+			decoded, next, ok := l.readEscape(pos)
+			if !ok {
+				return "", l.pos, false
+			}
+			sb.WriteString(decoded)
+			pos = next
+			continue
 		}
+		sb.WriteByte(l.input[pos])
 		pos++
 	}
-	if pos < len(l.input) {
-		pos++ // Skip closing quote
-		lval.str = l.input[start : pos-1]
-		return STRING, pos
+	if pos >= len(l.input) {
+		return "", l.pos, false // Error - unclosed string
 	}
-	return 0, l.pos // Error - unclosed string
+	return sb.String(), pos + 1, true
 }
 
-func (l *yyLex) readDoubleQuotedString(lval *yySymType) (int, int) {
-	start := l.pos + 1 // Skip opening quote
-	pos := start
-	for pos < len(l.input) && l.input[pos] != '"' {
-		if l.input[pos] == '\\' {
-			pos++
+// readEscape decodes the escape sequence starting with the backslash at pos
+// and returns the decoded text and the position after the sequence.
+func (l *yyLex) readEscape(pos int) (string, int, bool) {
+	if pos+1 >= len(l.input) {
+		return "", pos, false
+	}
+	switch ch := l.input[pos+1]; ch {
+	case 'n':
+		return "\n", pos + 2, true
+	case 't':
+		return "\t", pos + 2, true
+	case 'r':
+		return "\r", pos + 2, true
+	case 'b':
+		return "\b", pos + 2, true
+	case 'f':
+		return "\f", pos + 2, true
+	case 'v':
+		return "\v", pos + 2, true
+	case '0':
+		return "\x00", pos + 2, true
+	case '\\', '\'', '"', '`', '$', '/':
+		return string(ch), pos + 2, true
+	case 'x':
+		return l.readCodePoint(pos, pos+2, 2)
+	case 'u':
+		if pos+2 < len(l.input) && l.input[pos+2] == '{' {
+			end := strings.IndexByte(l.input[pos+3:], '}')
+			if end < 1 || end > 6 {
+				l.errorAt(pos, pos+2, "invalid escape sequence")
+				return "", pos, false
+			}
+			decoded, _, ok := l.readCodePoint(pos, pos+3, end)
+			return decoded, pos + 3 + end + 1, ok
 		}
-		pos++
+		return l.readCodePoint(pos, pos+2, 4)
+	}
+	l.errorAt(pos, pos+2, "invalid escape sequence")
+	return "", pos, false
+}
+
+func (l *yyLex) readCodePoint(escape, start, digits int) (string, int, bool) {
+	if start+digits > len(l.input) {
+		l.errorAt(escape, len(l.input), "invalid escape sequence")
+		return "", escape, false
 	}
-	if pos < len(l.input) {
-		pos++ // Skip closing quote
-		lval.str = l.input[start : pos-1]
-		return DSTRING, pos
+	code, err := strconv.ParseUint(l.input[start:start+digits], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		l.errorAt(escape, start+digits, "invalid escape sequence")
+		return "", escape, false
+	}
+	return string(rune(code)), start + digits, true
+}
+
+// readTemplate reads a backtick template string. Literal text and `${expr}`
+// placeholders become the parts of a TemplateNode.
+func (l *yyLex) readTemplate(lval *yySymType) (int, int) {
+	var sb strings.Builder
+	parts := make([]ExprNode, 0)
+	pos := l.pos + 1 // Skip opening backtick
+	text := pos
+	failed := false
+	flush := func() {
+		if sb.Len() > 0 {
+			span := Span{Start: l.offset + text, End: l.offset + pos}
+			parts = append(parts, &LiteralNode{Value: StringValue(sb.String()), Span: span})
+			sb.Reset()
+		}
+	}
+	for pos < len(l.input) && l.input[pos] != '`' {
+		switch {
+		case l.input[pos] == '\\':
+			{
+				decoded, next, ok := l.readEscape(pos)
+				if !ok {
+					return 0, l.pos
+				}
+				sb.WriteString(decoded)
+				pos = next
+			}
+		case strings.HasPrefix(l.input[pos:], "${"):
+			{
+				// Placeholders nest lexers and parsers, so their depth is
+				// bounded before they are read
+				if l.depth >= l.maxDepth() {
+					l.tooDeep(Span{Start: l.offset + pos, End: l.offset + pos})
+					return 0, l.pos
+				}
+				end, ok := l.placeholderEnd(pos + 2)
+				if !ok {
+					return 0, l.pos
+				}
+				if l.scan {
+					pos = end + 1
+					continue
+				}
+				expr, errs := l.parseEmbedded(pos+2, end)
+				// Keep going so that later placeholders are checked too
+				for _, err := range errs {
+					err.Message = "invalid template placeholder: " + err.Message
+					l.errors = append(l.errors, err)
+				}
+				failed = failed || len(errs) > 0
+				flush()
+				parts = append(parts, expr)
+				pos = end + 1
+				text = pos
+			}
+		default:
+			{
+				sb.WriteByte(l.input[pos])
+				pos++
+			}
+		}
+	}
+	if pos >= len(l.input) || failed {
+		return 0, l.pos // Error - unclosed template or invalid placeholder
+	}
+	flush()
+	lval.expr = &TemplateNode{Parts: parts, Span: Span{Start: l.offset + l.pos, End: l.offset + pos + 1}}
+	return TEMPLATE, pos + 1
+}
+
+// placeholderEnd finds the closing brace of a template placeholder whose
+// expression starts at start, skipping over nested braces and strings.
+func (l *yyLex) placeholderEnd(start int) (int, bool) {
+	// Nested placeholders are found by the scan of the outermost one, so
+	// that each is only scanned once
+	if end, ok := l.ends[l.offset+start]; ok {
+		return end - l.offset, true
+	}
+	if l.ends == nil {
+		l.ends = make(map[int]int)
+	}
+	inner := &yyLex{input: l.input, source: l.source, pos: start, offset: l.offset, limit: l.limit, depth: l.depth + 1, scan: true, ends: l.ends}
+	depth := 0
+	for {
+		var lval yySymType
+		switch inner.Lex(&lval) {
+		case 0:
+			{
+				// A placeholder nested too deep ends the scan early
+				for _, err := range inner.errors {
+					if errors.Is(err, ErrLimitExceeded) {
+						l.errors = append(l.errors, err)
+						l.stop = true
+						return 0, false
+					}
+				}
+				l.errorAt(start-2, start, "unclosed template placeholder")
+				return 0, false
+			}
+		case LBRACE:
+			{
+				depth++
+			}
+		case RBRACE:
+			{
+				if depth == 0 {
+					l.ends[l.offset+start] = l.offset + inner.pos - 1
+					return inner.pos - 1, true
+				}
+				depth--
+			}
+		}
 	}
-	return 0, l.pos // Error - unclosed string
 }
 
 func (l *yyLex) readNumber(lval *yySymType) (int, int) {
 	start := l.pos
-	pos := l.pos
-	for pos < len(l.input) && ((l.input[pos] >= '0' && l.input[pos] <= '9') || l.input[pos] == '.') {
-		pos++
+	if start >= len(l.input) || !isDigit(l.input[start]) {
+		return 0, l.pos
 	}
-	if pos > start {
-		num, _ := strconv.ParseFloat(l.input[start:pos], 64)
-		lval.num = num
-		return NUMBER, pos
+
+	// Take the whole run of characters that could belong to the literal so
+	// that malformed numbers such as `1.2.3` or `12abc` are reported as a
+	// single error instead of being split into several tokens.
+	prefixed := start+1 < len(l.input) && l.input[start] == '0' && strings.IndexByte("xXoObB", l.input[start+1]) >= 0
+	pos := start
+	for pos < len(l.input) {
+		ch := l.input[pos]
+		if isDigit(ch) || isLetter(ch) || ch == '_' || ch == '.' {
+			pos++
+			continue
+		}
+		if (ch == '+' || ch == '-') && !prefixed && (l.input[pos-1] == 'e' || l.input[pos-1] == 'E') {
+			pos++
+			continue
+		}
+		break
 	}
-	return 0, l.pos
+
+	num, ok := parseNumber(l.input[start:pos])
+	if !ok {
+		l.errorAt(start, pos, fmt.Sprintf("malformed number '%s'", l.input[start:pos]))
+		return 0, l.pos
+	}
+	lval.num = num
+	return NUMBER, pos
+}
+
+var decimalPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// parseNumber parses decimal literals with an optional fraction and exponent
+// as well as 0x, 0o and 0b prefixed integers. Underscores may separate digits.
+func parseNumber(text string) (float64, bool) {
+	base := 10
+	digits := text
+	if len(text) > 2 && text[0] == '0' {
+		switch text[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			digits = text[2:]
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(digits); i++ {
+		if digits[i] != '_' {
+			sb.WriteByte(digits[i])
+			continue
+		}
+		if i == 0 || i == len(digits)-1 || !isDigitOf(digits[i-1], base) || !isDigitOf(digits[i+1], base) {
+			return 0, false
+		}
+	}
+	clean := sb.String()
+
+	if base != 10 {
+		n, err := strconv.ParseUint(clean, base, 64)
+		if err != nil {
+			return 0, false
+		}
+		return float64(n), true
+	}
+	if !decimalPattern.MatchString(clean) {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigitOf(ch byte, base int) bool {
+	if base == 16 {
+		return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+	}
+	return isDigit(ch)
 }
 
 func (l *yyLex) readIdentifier(lval *yySymType) (int, int) {
@@ -254,69 +714,67 @@ func isWhitespace(ch byte) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
-func (l *yyLex) Error(s string) {
-	// Find the current token or problematic area
-	start := l.pos
-	end := l.pos
-
-	// If we're at EOF, back up to show last token
-	if l.pos >= len(l.input) {
-		start = len(l.input) - 1
-		if start < 0 {
-			start = 0
-		}
-	}
-
-	// Extend backwards to find token start
-	for start > 0 && !isWhitespace(l.input[start-1]) {
-		start--
+// errorAt reports an error for the text at input[start:end].
+func (l *yyLex) errorAt(start, end int, s string) {
+	source := l.source
+	if source == "" {
+		source = l.input
 	}
+	l.errors = append(l.errors, newSyntaxError(source, l.offset+start, l.offset+end, s))
+}
 
-	// Extend forwards to find token end
-	for end < len(l.input) && !isWhitespace(l.input[end]) {
-		end++
-	}
+// errorSpan reports an error for the source text covered by span.
+func (l *yyLex) errorSpan(span Span, s string) {
+	l.errorAt(span.Start-l.offset, span.End-l.offset, s)
+}
 
-	// Extract the problematic token
-	var token string
-	if start < len(l.input) && end <= len(l.input) && start < end {
-		token = l.input[start:end]
-	} else {
-		token = "<EOF>"
+// Error reports an error for the last token read. It is called by the
+// parser, which then recovers and carries on.
+func (l *yyLex) Error(s string) {
+	l.bad = &Span{Start: l.offset + l.start, End: l.offset + l.pos}
+	// The lexer has already reported why it could not read the token, or
+	// why it stopped. Past EOI the parser is finishing what the error at
+	// the end of the input left open
+	if l.last == ILLEGAL || l.stop || l.ended && l.last != EOI {
+		return
 	}
+	l.errorAt(l.start, l.pos, s)
+}
 
-	// Create context around the error
-	contextStart := 0
-	if l.pos > 20 {
-		contextStart = l.pos - 20
-	}
-	contextEnd := len(l.input)
-	if l.pos+20 < len(l.input) {
-		contextEnd = l.pos + 20
+// badNode returns a BadNode for the parser's error production. It spans the
+// token that was rejected, or is empty before the current token when the
+// parser is still recovering from an earlier error.
+func (l *yyLex) badNode() *BadNode {
+	if l.bad == nil {
+		return &BadNode{Span: Span{Start: l.offset + l.start, End: l.offset + l.start}}
 	}
+	node := &BadNode{Span: *l.bad}
+	l.bad = nil
+	return node
+}
 
-	context := l.input[contextStart:contextEnd]
-
-	// Calculate relative position in context for pointer
-	relativePos := l.pos - contextStart
-	if relativePos < 0 {
-		relativePos = 0
-	}
-	if relativePos > len(context) {
-		relativePos = len(context)
+// parseEmbedded parses input[start:end] as an expression of its own whose
+// positions point into the enclosing source.
+func (l *yyLex) parseEmbedded(start, end int) (ExprNode, []*SyntaxError) {
+	source := l.source
+	if source == "" {
+		source = l.input
 	}
+	return parse(&yyLex{input: l.input[start:end], source: source, offset: l.offset + start, limit: l.limit, depth: l.depth + 1, ends: l.ends})
+}
 
-	// Create pointer string
-	pointer := ""
-	for i := 0; i < relativePos-len(token); i++ {
-		if context[i] == '\t' {
-			pointer += "\t"
-		} else {
-			pointer += " "
-		}
+// maxDepth returns the depth the expression may be nested to.
+func (l *yyLex) maxDepth() int {
+	if l.limit <= 0 || l.limit > DefaultMaxDepth {
+		return DefaultMaxDepth
 	}
-	pointer += "^"
+	return l.limit
+}
 
-	l.error = fmt.Errorf("%s near token '%s' at position %d\n%s\n%s",
-		s, token, l.pos-len(token), context, pointer)
+// tooDeep reports that the expression is nested deeper than the limit of
+// the parse, at the start of span.
+func (l *yyLex) tooDeep(span Span) {
+	l.errorSpan(span, fmt.Sprintf("expression is nested deeper than %d", l.maxDepth()))
+	l.errors[len(l.errors)-1].err = ErrLimitExceeded
+	l.stop = true
 }
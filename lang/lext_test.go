@@ -36,6 +36,13 @@ func TestKeywordTokens(t *testing.T) {
 		{"in keyword", "in", IN},
 		{"true keyword", "true", BOOLEAN},
 		{"false keyword", "false", BOOLEAN},
+		{"null keyword", "null", NULL},
+		{"is keyword", "is", IS},
+		{"case keyword", "case", CASE},
+		{"when keyword", "when", WHEN},
+		{"then keyword", "then", THEN},
+		{"else keyword", "else", ELSE},
+		{"end keyword", "end", END},
 	}
 
 	for _, tt := range tests {
@@ -76,6 +83,13 @@ func TestOperatorTokens(t *testing.T) {
 		{"minus", "-", int('-')},
 		{"multiply", "*", int('*')},
 		{"divide", "/", int('/')},
+		{"modulo", "%", int('%')},
+		{"integer divide", "//", IDIV},
+		{"power", "**", POW},
+		{"arrow", "=>", ARROW},
+		{"optional dot", "?.", QDOT},
+		{"optional bracket", "?.[", QLBRACKET},
+		{"coalesce", "??", COALESCE},
 	}
 
 	for _, tt := range tests {
@@ -105,6 +119,9 @@ func TestPunctuationTokens(t *testing.T) {
 		{"comma", ",", COMMA},
 		{"question mark", "?", QMARK},
 		{"colon", ":", COLON},
+		{"left brace", "{", LBRACE},
+		{"right brace", "}", RBRACE},
+		{"ellipsis", "...", ELLIPSIS},
 	}
 
 	for _, tt := range tests {
@@ -132,8 +149,14 @@ func TestStringTokens(t *testing.T) {
 		{"empty single quoted", "''", STRING, ""},
 		{"empty double quoted", `""`, DSTRING, ""},
 		{"string with spaces", "'hello world'", STRING, "hello world"},
-		{"string with escape", `'hello\'s'`, STRING, "hello\\'s"},
-		{"double string with escape", `"say \"hello\""`, DSTRING, `say \"hello\"`},
+		{"string with escape", `'hello\'s'`, STRING, "hello's"},
+		{"double string with escape", `"say \"hello\""`, DSTRING, `say "hello"`},
+		{"newline and tab", `'a\nb\tc'`, STRING, "a\nb\tc"},
+		{"backslash", `'C:\\temp'`, STRING, `C:\temp`},
+		{"hex escape", `'\x41'`, STRING, "A"},
+		{"unicode escape", `'caf\u00e9'`, STRING, "café"},
+		{"unicode code point escape", `'\u{1F600}'`, STRING, "\U0001F600"},
+		{"raw utf-8", `'é'`, STRING, "é"},
 	}
 
 	for _, tt := range tests {
@@ -152,6 +175,52 @@ func TestStringTokens(t *testing.T) {
 	}
 }
 
+func TestTemplateTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		parts     int
+		expectErr bool
+	}{
+		{"plain template", "`hello`", 1, false},
+		{"empty template", "``", 0, false},
+		{"placeholder", "`hello ${name}!`", 3, false},
+		{"only placeholder", "`${a}${b}`", 2, false},
+		{"nested braces", "`${ {a: 1}.a }`", 1, false},
+		{"nested template", "`a ${ `b ${c}` }`", 2, false},
+		{"string with brace", "`${ '}' }`", 1, false},
+		{"escaped placeholder", "`\\${x}`", 1, false},
+		{"unclosed template", "`hello", 0, true},
+		{"unclosed placeholder", "`${x`", 0, true},
+		{"invalid placeholder", "`${1 +}`", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := &yyLex{input: tt.input}
+			var lval yySymType
+			token := lexer.Lex(&lval)
+
+			if tt.expectErr {
+				if token == TEMPLATE {
+					t.Error("expected template to be rejected")
+				}
+				return
+			}
+			if token != TEMPLATE {
+				t.Fatalf("expected TEMPLATE token, got %d", token)
+			}
+			node, ok := lval.expr.(*TemplateNode)
+			if !ok {
+				t.Fatalf("expected TemplateNode, got %T", lval.expr)
+			}
+			if len(node.Parts) != tt.parts {
+				t.Errorf("expected %d parts, got %d", tt.parts, len(node.Parts))
+			}
+		})
+	}
+}
+
 func TestNumberTokens(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -222,7 +291,7 @@ func TestWhitespaceHandling(t *testing.T) {
 		{"tabs before number", "\t\t42", NUMBER},
 		{"newlines before string", "\n\n'test'", STRING},
 		{"mixed whitespace", " \t\n\r and", AND},
-		{"only whitespace", "   ", EOF},
+		{"only whitespace", "   ", EOI},
 	}
 
 	for _, tt := range tests {
@@ -271,12 +340,20 @@ func TestMultipleTokens(t *testing.T) {
 		input    string
 		expected []int
 	}{
-		{"simple expression", "x + 1", []int{IDENTIFIER, int('+'), NUMBER, EOF}},
-		{"boolean expression", "true and false", []int{BOOLEAN, AND, BOOLEAN, EOF}},
-		{"comparison", "x == 42", []int{IDENTIFIER, EQ, NUMBER, EOF}},
-		{"function call", "func(arg)", []int{IDENTIFIER, LPAREN, IDENTIFIER, RPAREN, EOF}},
-		{"array access", "arr[0]", []int{IDENTIFIER, LBRACKET, NUMBER, RBRACKET, EOF}},
-		{"ternary operator", "x ? y : z", []int{IDENTIFIER, QMARK, IDENTIFIER, COLON, IDENTIFIER, EOF}},
+		{"simple expression", "x + 1", []int{IDENTIFIER, int('+'), NUMBER, EOI, EOF}},
+		{"boolean expression", "true and false", []int{BOOLEAN, AND, BOOLEAN, EOI, EOF}},
+		{"comparison", "x == 42", []int{IDENTIFIER, EQ, NUMBER, EOI, EOF}},
+		{"function call", "func(arg)", []int{IDENTIFIER, LPAREN, IDENTIFIER, RPAREN, EOI, EOF}},
+		{"array access", "arr[0]", []int{IDENTIFIER, LBRACKET, NUMBER, RBRACKET, EOI, EOF}},
+		{"ternary operator", "x ? y : z", []int{IDENTIFIER, QMARK, IDENTIFIER, COLON, IDENTIFIER, EOI, EOF}},
+		{"case expression", "case when x then 1 else 2 end", []int{CASE, WHEN, IDENTIFIER, THEN, NUMBER, ELSE, NUMBER, END, EOI, EOF}},
+		{"keyword as field", "range.end", []int{IDENTIFIER, DOT, IDENTIFIER, EOI, EOF}},
+		{"is not null", "x is not null", []int{IDENTIFIER, IS, NOT, NULL, EOI, EOF}},
+		{"optional chain", "a?.b?.[0] ?? c", []int{IDENTIFIER, QDOT, IDENTIFIER, QLBRACKET, NUMBER, RBRACKET, COALESCE, IDENTIFIER, EOI, EOF}},
+		{"ternary with list", "a ?[1] : [2]", []int{IDENTIFIER, QMARK, LBRACKET, NUMBER, RBRACKET, COLON, LBRACKET, NUMBER, RBRACKET, EOI, EOF}},
+		{"each index", "a[?]", []int{IDENTIFIER, LBRACKET, QMARK, RBRACKET, EOI, EOF}},
+		{"map literal", "{a: 1, ...b}", []int{LBRACE, IDENTIFIER, COLON, NUMBER, COMMA, ELLIPSIS, IDENTIFIER, RBRACE, EOI, EOF}},
+		{"arithmetic operators", "a % b // c ** d", []int{IDENTIFIER, int('%'), IDENTIFIER, IDIV, IDENTIFIER, POW, IDENTIFIER, EOI, EOF}},
 	}
 
 	for _, tt := range tests {
@@ -341,7 +418,9 @@ func TestReadSingleQuotedString(t *testing.T) {
 		{"empty string", "''", 0, 2, "", STRING},
 		{"string with space", "'hello world'", 0, 13, "hello world", STRING},
 		{"unclosed string", "'hello", 0, 0, "", 0},
-		{"escaped quote", "'don\\'t'", 0, 8, "don\\'t", STRING},
+		{"escaped quote", "'don\\'t'", 0, 8, "don't", STRING},
+		{"invalid escape", "'\\q'", 0, 0, "", 0},
+		{"short unicode escape", "'\\u12'", 0, 0, "", 0},
 	}
 
 	for _, tt := range tests {
@@ -376,7 +455,7 @@ func TestReadDoubleQuotedString(t *testing.T) {
 		{"empty string", `""`, 0, 2, "", DSTRING},
 		{"string with space", `"hello world"`, 0, 13, "hello world", DSTRING},
 		{"unclosed string", `"hello`, 0, 0, "", 0},
-		// {"escaped quote", `"say \"hi\""`, 0, 11, `say \"hi\"`, DSTRING},
+		{"escaped quote", `"say \"hi\""`, 0, 12, `say "hi"`, DSTRING},
 	}
 
 	for _, tt := range tests {
@@ -413,6 +492,15 @@ func TestReadNumber(t *testing.T) {
 		{"leading zero", "0.5", 0, 3, 0.5, NUMBER},
 		{"number at position", "abc123", 3, 6, 123, NUMBER},
 		{"not a number", "abc", 0, 0, 0, 0},
+		{"exponent", "1e6", 0, 3, 1e6, NUMBER},
+		{"negative exponent", "2.5E-3", 0, 6, 2.5e-3, NUMBER},
+		{"positive exponent", "1e+2", 0, 4, 100, NUMBER},
+		{"hex", "0xFF", 0, 4, 255, NUMBER},
+		{"octal", "0o17", 0, 4, 15, NUMBER},
+		{"binary", "0b101", 0, 5, 5, NUMBER},
+		{"separators", "1_000_000", 0, 9, 1000000, NUMBER},
+		{"hex separators", "0xFF_FF", 0, 7, 65535, NUMBER},
+		{"stops at operator", "1e3+1", 0, 3, 1000, NUMBER},
 	}
 
 	for _, tt := range tests {
@@ -434,6 +522,28 @@ func TestReadNumber(t *testing.T) {
 	}
 }
 
+func TestMalformedNumber(t *testing.T) {
+	tests := []string{"1.2.3", "1e", "1e+", "0xZZ", "0x", "0b2", "1__0", "1_", "1._5", "12abc", "1."}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			lexer := &yyLex{input: input}
+			var lval yySymType
+			token, _ := lexer.readNumber(&lval)
+
+			if token != 0 {
+				t.Errorf("expected no token, got %d", token)
+			}
+			if len(lexer.errors) != 1 {
+				t.Fatalf("expected one error, got %v", lexer.errors)
+			}
+			if !strings.Contains(lexer.errors[0].Error(), "malformed number") {
+				t.Errorf("expected malformed number error, got %v", lexer.errors[0])
+			}
+		})
+	}
+}
+
 func TestReadIdentifier(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -512,11 +622,11 @@ func TestErrorHandling(t *testing.T) {
 			lexer := &yyLex{input: tt.input, pos: tt.position}
 			lexer.Error(tt.message)
 
-			if lexer.error == nil {
-				t.Error("expected error to be set")
+			if len(lexer.errors) == 0 {
+				t.Fatal("expected error to be set")
 			}
 
-			errorStr := lexer.error.Error()
+			errorStr := lexer.errors[0].Error()
 			if !strings.Contains(errorStr, tt.message) {
 				t.Errorf("expected error to contain %q, got %q", tt.message, errorStr)
 			}
@@ -547,11 +657,11 @@ func TestErrorContext(t *testing.T) {
 			lexer := &yyLex{input: tt.input, pos: tt.position}
 			lexer.Error(tt.message)
 
-			if lexer.error == nil {
+			if len(lexer.errors) == 0 {
 				t.Fatal("expected error to be set")
 			}
 
-			errorStr := lexer.error.Error()
+			errorStr := lexer.errors[0].Error()
 
 			// Should contain the error message
 			if !strings.Contains(errorStr, tt.message) {
@@ -581,7 +691,7 @@ func TestUnknownCharacter(t *testing.T) {
 		{"hash", "#", '#'},
 		{"at symbol", "@", '@'},
 		{"dollar", "$", '$'},
-		{"percent", "%", '%'},
+		{"caret", "^", '^'},
 		{"ampersand", "&", '&'},
 	}
 
@@ -591,9 +701,10 @@ func TestUnknownCharacter(t *testing.T) {
 			var lval yySymType
 			token := lexer.Lex(&lval)
 
-			// Unknown characters should return 0 (EOF/error)
-			if token != 0 {
-				t.Errorf("expected token 0 for unknown character, got %d", token)
+			// Unknown characters are reported and skipped, which leaves
+			// the end of the input
+			if token != EOI {
+				t.Errorf("expected EOI for unknown character, got %d", token)
 			}
 		})
 	}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type (
+	// Limits bounds the resources an evaluation started by EvaluateContext
+	// may use, so that expressions from untrusted sources cannot exhaust
+	// memory or CPU. Zero fields are not limited. Limits travel in the
+	// context.Context of the evaluation, see WithLimits, so that
+	// ContextFunctions can check them before allocating.
+	Limits struct {
+		// MaxDepth bounds the nesting depth of the expression tree.
+		MaxDepth int
+		// MaxSteps bounds the number of nodes evaluated, counting every
+		// evaluation of a lambda body again. Bytecode counts each run of an
+		// expression or lambda body as its number of instructions instead.
+		MaxSteps int
+		// MaxCollectionSize bounds the number of elements of the lists and
+		// maps built by operators and functions, counting the elements
+		// nested in them too.
+		MaxCollectionSize int
+		// MaxStringLength bounds the length, in bytes, of the strings built
+		// by operators, templates and functions.
+		MaxStringLength int
+		// MaxDuration bounds the wall time of the evaluation.
+		MaxDuration time.Duration
+	}
+	limitsKey struct{}
+)
+
+// ErrLimitExceeded is wrapped by the errors of evaluations that exceeded
+// their Limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// WithLimits returns a copy of ctx carrying limits.
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// LimitsFrom returns the limits ctx carries, or zero Limits if it carries
+// none.
+func LimitsFrom(ctx context.Context) Limits {
+	limits, _ := ctx.Value(limitsKey{}).(Limits)
+	return limits
+}
+
+// CheckDepth fails if node is nested deeper than MaxDepth. It only visits
+// the first MaxDepth levels, so it is safe to call on any tree.
+func (l Limits) CheckDepth(node ExprNode) error {
+	if l.MaxDepth <= 0 {
+		return nil
+	}
+	if deep := deeper(node, l.MaxDepth); deep != nil {
+		return fmt.Errorf("%w: expression is nested deeper than %d at position %d", ErrLimitExceeded, l.MaxDepth, SpanOf(deep).Start)
+	}
+	return nil
+}
+
+// CheckString fails if a string of n bytes is longer than MaxStringLength.
+func (l Limits) CheckString(n int) error {
+	if l.MaxStringLength > 0 && n > l.MaxStringLength {
+		return fmt.Errorf("%w: string of %d bytes is longer than %d", ErrLimitExceeded, n, l.MaxStringLength)
+	}
+	return nil
+}
+
+// CheckCollection fails if a list or map of n elements is larger than
+// MaxCollectionSize. The elements of nested lists and maps count as well,
+// see Elements.
+func (l Limits) CheckCollection(n int) error {
+	if l.MaxCollectionSize > 0 && n > l.MaxCollectionSize {
+		return fmt.Errorf("%w: collection is larger than %d elements", ErrLimitExceeded, l.MaxCollectionSize)
+	}
+	return nil
+}
+
+// Elements counts the elements of a list holding values, together with
+// the elements of every list and map nested in them, so that a list that
+// repeats one large list cannot get around MaxCollectionSize. It stops
+// once it has counted more than max, so that it is cheap on any value.
+func Elements(max int, values ...Value) int {
+	n := 0
+	for _, value := range values {
+		if n++; n > max {
+			return n
+		}
+		if n = nested(value, n, max); n > max {
+			return n
+		}
+	}
+	return n
+}
+
+// nested adds the elements of value, nested ones included, to n, stopping
+// once n is more than max.
+func nested(value Value, n, max int) int {
+	switch value := value.(type) {
+	case ListValue:
+		{
+			return n + Elements(max-n, value...)
+		}
+	case MapValue:
+		{
+			for _, item := range value {
+				if n++; n > max {
+					return n
+				}
+				if n = nested(item, n, max); n > max {
+					return n
+				}
+			}
+			return n
+		}
+	default:
+		{
+			return n
+		}
+	}
+}
+
+// checkValue checks the size of a string, list or map value. Other values
+// always pass.
+func (l Limits) checkValue(value Value) error {
+	switch value := value.(type) {
+	case StringValue:
+		{
+			return l.CheckString(len(value))
+		}
+	case ListValue, MapValue:
+		{
+			if l.MaxCollectionSize <= 0 {
+				return nil
+			}
+			return l.CheckCollection(nested(value, 0, l.MaxCollectionSize))
+		}
+	default:
+		{
+			return nil
+		}
+	}
+}
+
+// tooLong is the error of a string found to be longer than MaxStringLength
+// while it was still being built.
+func (l Limits) tooLong() error {
+	return fmt.Errorf("%w: string is longer than %d bytes", ErrLimitExceeded, l.MaxStringLength)
+}
+
+// deeper returns the first node found below depth levels of node, or nil.
+func deeper(node ExprNode, depth int) ExprNode {
+	if depth == 0 {
+		return node
+	}
+	for _, child := range Children(node) {
+		if deep := deeper(child, depth-1); deep != nil {
+			return deep
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	limits := Limits{MaxDepth: 3, MaxCollectionSize: 2, MaxStringLength: 4}
+	if err := limits.CheckDepth(mustParse(t, "1 + 2 + 3")); err != nil {
+		t.Errorf("expected depth 3 to pass, got %v", err)
+	}
+	if err := limits.CheckDepth(mustParse(t, "1 + 2 + 3 + 4")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected depth 4 to fail, got %v", err)
+	}
+	if err := limits.CheckString(4); err != nil {
+		t.Errorf("expected 4 bytes to pass, got %v", err)
+	}
+	if err := limits.CheckString(5); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected 5 bytes to fail, got %v", err)
+	}
+	if err := limits.CheckCollection(3); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected 3 elements to fail, got %v", err)
+	}
+	if err := (Limits{}).CheckDepth(mustParse(t, "1 + 2 + 3 + 4")); err != nil {
+		t.Errorf("expected zero limits to pass, got %v", err)
+	}
+	pair := ListValue{NumberValue(1), NumberValue(2)}
+	if n := Elements(100, pair, MapValue{"a": pair}); n != 7 {
+		t.Errorf("expected 7 elements, got %d", n)
+	}
+	if n := Elements(3, ListValue{pair, pair}); n != 4 {
+		t.Errorf("expected counting to stop at 4, got %d", n)
+	}
+	if LimitsFrom(context.Background()) != (Limits{}) {
+		t.Errorf("expected no limits")
+	}
+	if LimitsFrom(WithLimits(context.Background(), limits)) != limits {
+		t.Errorf("expected the limits of the context")
+	}
+}
+
+func TestEvaluateLimits(t *testing.T) {
+	evaluators := map[string]func(node ExprNode) ExprNode{
+		"tree":     func(node ExprNode) ExprNode { return node },
+		"bytecode": func(node ExprNode) ExprNode { return Compile(node) },
+	}
+	tests := []struct {
+		input    string
+		limits   Limits
+		expected Value
+		fails    bool
+	}{
+		{input: "map([1, 2, 3], i => i * x)", limits: Limits{MaxSteps: 100}, expected: ListValue{NumberValue(2), NumberValue(4), NumberValue(6)}},
+		{input: "map([1, 2, 3], i => i * x)", limits: Limits{MaxSteps: 10}, fails: true},
+		{input: "s + s", limits: Limits{MaxStringLength: 10}, expected: StringValue("hellohello")},
+		{input: "s + s", limits: Limits{MaxStringLength: 8}, fails: true},
+		{input: "`${s}${s}`", limits: Limits{MaxStringLength: 8}, fails: true},
+		{input: "upper(s + s)", limits: Limits{MaxStringLength: 8}, fails: true},
+		{input: "[x, x]", limits: Limits{MaxCollectionSize: 2}, expected: ListValue{NumberValue(2), NumberValue(2)}},
+		{input: "[x, x, x]", limits: Limits{MaxCollectionSize: 2}, fails: true},
+		{input: "user.roles + user.roles", limits: Limits{MaxCollectionSize: 3}, fails: true},
+		{input: "{a: x, b: x, c: x}", limits: Limits{MaxCollectionSize: 2}, fails: true},
+		{input: "{...user, id: x}", limits: Limits{MaxCollectionSize: 3}, fails: true},
+		{input: "map(user.roles, i => i)", limits: Limits{MaxCollectionSize: 1}, fails: true},
+		{input: "block() and upper(s)", limits: Limits{MaxDuration: 10 * time.Millisecond}, fails: true},
+		{input: "'hellohello'", limits: Limits{MaxStringLength: 8}, fails: true},
+		{input: "'' + [s, s]", limits: Limits{MaxStringLength: 12}, fails: true},
+		{input: "`${[s]}`", limits: Limits{MaxStringLength: 7}, expected: StringValue("[hello]")},
+		{input: "`${[s, s]}`", limits: Limits{MaxStringLength: 12}, fails: true},
+		{input: "[[x, x], [x, x]]", limits: Limits{MaxCollectionSize: 6}, expected: ListValue{ListValue{NumberValue(2), NumberValue(2)}, ListValue{NumberValue(2), NumberValue(2)}}},
+		{input: "[[x, x], [x, x, x]]", limits: Limits{MaxCollectionSize: 6}, fails: true},
+		{input: "{a: [x, x], b: [x]}", limits: Limits{MaxCollectionSize: 4}, fails: true},
+		{input: "s + s", expected: StringValue("hellohello")},
+	}
+	for name, evaluator := range evaluators {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.input, func(t *testing.T) {
+				ctx := functionContext{MockContext: vmContext(), contextFunctions: map[string]ContextFunction{
+					"block": func(ctx context.Context, args []Value) (Value, error) {
+						<-ctx.Done()
+						return BoolValue(true), nil
+					},
+				}}
+				result, err := EvaluateContext(WithLimits(context.Background(), tt.limits), evaluator(mustParse(t, tt.input)), ctx)
+				if tt.fails {
+					var evalErr *EvalError
+					if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &evalErr) {
+						t.Fatalf("expected the limit to be exceeded, got %v (%v)", result, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("evaluation error: %v", err)
+				}
+				if !reflect.DeepEqual(result, tt.expected) {
+					t.Errorf("expected %v, got %v", tt.expected, result)
+				}
+			})
+		}
+	}
+
+	t.Run("depth", func(t *testing.T) {
+		goctx := WithLimits(context.Background(), Limits{MaxDepth: 2})
+		if _, err := EvaluateContext(goctx, mustParse(t, "x + 1"), vmContext()); err != nil {
+			t.Errorf("evaluation error: %v", err)
+		}
+		if _, err := EvaluateContext(goctx, mustParse(t, "x + 1 + 1"), vmContext()); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("expected the depth to be exceeded, got %v", err)
+		}
+	})
+}
+
+func TestParseLimits(t *testing.T) {
+	deep := strings.Repeat("(", DefaultMaxDepth) + "1" + strings.Repeat(")", DefaultMaxDepth)
+	chain := "1" + strings.Repeat(" + 1", DefaultMaxDepth)
+	templates := strings.Repeat("`${", DefaultMaxDepth+1) + "1" + strings.Repeat("}`", DefaultMaxDepth+1)
+
+	for _, input := range []string{chain, templates} {
+		if _, err := ParseExpression(input); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("expected the depth to be exceeded, got %v", err)
+		}
+		ast, errs := ParsePartial(input)
+		if len(errs) != 1 || !errors.Is(errs[0], ErrLimitExceeded) {
+			t.Errorf("expected only the depth to be exceeded, got %v", errs)
+		}
+		if bad, ok := ast.(*BadNode); !ok || bad.Span != (Span{Start: 0, End: len(input)}) {
+			t.Errorf("expected a BadNode spanning the input, got %#v", ast)
+		}
+	}
+	// Parentheses do not add nodes
+	if _, err := ParseExpression(deep); err != nil {
+		t.Errorf("parse error: %v", err)
+	}
+
+	if _, err := ParseLimited("x + 1", Limits{MaxDepth: 2}); err != nil {
+		t.Errorf("parse error: %v", err)
+	}
+	if _, err := ParseLimited("`${`${x}`}`", Limits{MaxDepth: 2}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected the depth to be exceeded, got %v", err)
+	}
+	_, err := ParseLimited("x + 1 + 1", Limits{MaxDepth: 2})
+	var syntaxErr *SyntaxError
+	if !errors.Is(err, ErrLimitExceeded) || !errors.As(err, &syntaxErr) || syntaxErr.Offset != 0 {
+		t.Errorf("expected the depth to be exceeded at 0, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+type (
+	// OptimizeEnv describes the environment an optimized expression will be
+	// evaluated in.
+	OptimizeEnv interface {
+		// PureFunction returns the function an expression calls as name,
+		// in namespace when it is not empty, if that function is pure: it
+		// has no side effects and always returns the same result for the
+		// same arguments. It returns nil for any other function.
+		PureFunction(namespace, name string) Function
+		// OperandResults reports whether `and` and `or` will return the
+		// operand that decided the result, as for OperandResultContext.
+		OperandResults() bool
+	}
+	optimizer struct {
+		env OptimizeEnv
+	}
+	// constants is the context constant subexpressions are evaluated in.
+	// They read no variables and call no functions.
+	constants struct {
+		operands bool
+	}
+)
+
+// Optimize returns an equivalent expression that is cheaper to evaluate.
+// Subexpressions whose operands are all constant are computed once,
+// including calls to pure functions, lists and maps of constants are built
+// once, `in` and `not in` against a constant list become hash lookups and
+// conditions with a constant side are simplified. Subexpressions that fail
+// are left in place so that they fail at evaluation time, as before, and
+// so are those whose value has no source form, such as a function, so that
+// Format always prints the result as source that parses back.
+//
+// The lists and maps built ahead of time are shared by every evaluation, so
+// functions must not modify their arguments. node itself is not modified.
+func Optimize(node ExprNode, env OptimizeEnv) ExprNode {
+	o := &optimizer{env: env}
+	return o.optimize(node, nil)
+}
+
+func (c constants) GetVariable(name string) Value    { return nil }
+func (c constants) GetFunction(name string) Function { return nil }
+func (c constants) OperandResults() bool             { return c.operands }
+
+func (o *optimizer) optimize(node ExprNode, params map[string]bool) ExprNode {
+	if lambda, ok := node.(*LambdaNode); ok {
+		scope := make(map[string]bool, len(params)+len(lambda.Params))
+		for name := range params {
+			scope[name] = true
+		}
+		for _, name := range lambda.Params {
+			scope[name] = true
+		}
+		params = scope
+	}
+	node = mapChildren(node, func(child ExprNode) ExprNode {
+		return o.optimize(child, params)
+	})
+
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		{
+			if folded, ok := o.fold(n); ok {
+				return folded
+			}
+			return o.binary(n)
+		}
+	case *UnaryOpNode:
+		{
+			if folded, ok := o.fold(n); ok {
+				return folded
+			}
+			// `not not x` is x when x is a boolean or null
+			if inner, ok := n.Operand.(*UnaryOpNode); ok && n.Operator == "not" && inner.Operator == "not" && o.boolean(inner.Operand) {
+				return inner.Operand
+			}
+		}
+	case *FieldAccessNode, *IndexAccessNode, *ListNode, *MapNode, *TemplateNode:
+		{
+			if folded, ok := o.fold(n); ok {
+				return folded
+			}
+		}
+	case *ConditionalNode:
+		{
+			if condition, ok := n.Condition.(*LiteralNode); ok {
+				if ToBool(condition.Value) {
+					return n.Then
+				}
+				return n.Else
+			}
+		}
+	case *CaseNode:
+		{
+			return o.caseExpr(n)
+		}
+	case *FunctionCallNode:
+		{
+			return o.call(n, params)
+		}
+	}
+	return node
+}
+
+// fold evaluates node if all of its operands are constant.
+func (o *optimizer) fold(node ExprNode) (ExprNode, bool) {
+	for _, child := range Children(node) {
+		if !constant(child) {
+			return node, false
+		}
+	}
+	// An optional access that found null is left in place, since a null
+	// literal would not skip the rest of its access chain, and so is a
+	// value that Format could not write back as source.
+	value, short, err := chain(node, constants{operands: o.env.OperandResults()}, true, false)
+	if err != nil || short || !hasSource(value) {
+		return node, false
+	}
+	return &LiteralNode{Value: value, Span: SpanOf(node)}, true
+}
+
+// constant reports whether node evaluates to the same value in any context.
+func constant(node ExprNode) bool {
+	switch n := node.(type) {
+	case *LiteralNode, *EachNode:
+		{
+			return true
+		}
+	case *RangeNode:
+		{
+			for _, child := range Children(n) {
+				if !constant(child) {
+					return false
+				}
+			}
+			return true
+		}
+	default:
+		{
+			return false
+		}
+	}
+}
+
+func (o *optimizer) binary(n *BinaryOpNode) ExprNode {
+	switch n.Operator {
+	case "and", "or":
+		{
+			return o.logical(n)
+		}
+	case "??":
+		{
+			if left, ok := n.Left.(*LiteralNode); ok {
+				if left.Value == nil {
+					return n.Right
+				}
+				return left
+			}
+		}
+	case "in", "not in":
+		{
+			if list, ok := n.Right.(*LiteralNode); ok {
+				if items, ok := list.Value.(ListValue); ok {
+					return NewInSetNode(n.Left, items, n.Operator == "not in", n.Pos, n.Span)
+				}
+			}
+		}
+	}
+	return n
+}
+
+// logical simplifies `and` and `or` with a constant operand.
+func (o *optimizer) logical(n *BinaryOpNode) ExprNode {
+	decisive := n.Operator == "or"
+	if left, ok := n.Left.(*LiteralNode); ok {
+		if o.env.OperandResults() {
+			if ToBool(left.Value) == decisive {
+				return left
+			}
+			return n.Right
+		}
+		if left.Value != nil {
+			if ToBool(left.Value) == decisive {
+				return &LiteralNode{Value: BoolValue(decisive), Span: n.Span}
+			}
+			// `true and x` is x when x is a boolean or null
+			if o.boolean(n.Right) {
+				return n.Right
+			}
+		}
+		return n
+	}
+	// `x and true` is x when x is a boolean or null. A decisive right side
+	// cannot be folded, since the left side must still be evaluated.
+	if right, ok := n.Right.(*LiteralNode); ok && !o.env.OperandResults() {
+		if right.Value != nil && ToBool(right.Value) != decisive && o.boolean(n.Left) {
+			return n.Left
+		}
+	}
+	return n
+}
+
+// boolean reports whether node always evaluates to a BoolValue or null.
+func (o *optimizer) boolean(node ExprNode) bool {
+	switch n := node.(type) {
+	case *LiteralNode:
+		{
+			_, ok := n.Value.(BoolValue)
+			return ok || n.Value == nil
+		}
+	case *BinaryOpNode:
+		{
+			switch n.Operator {
+			case "and", "or":
+				{
+					return !o.env.OperandResults()
+				}
+			case "=", "==", "!=", "<", "<=", ">", ">=", "in", "not in":
+				{
+					return true
+				}
+			}
+		}
+	case *UnaryOpNode:
+		{
+			return n.Operator != "-"
+		}
+	case *InSetNode:
+		{
+			return true
+		}
+	}
+	return false
+}
+
+// caseExpr drops the clauses of a case expression that can never match and
+// resolves it entirely when a constant clause is known to match.
+func (o *optimizer) caseExpr(n *CaseNode) ExprNode {
+	if folded, ok := o.fold(n); ok {
+		return folded
+	}
+	subject, ok := n.Subject.(*LiteralNode)
+	if n.Subject != nil && !ok {
+		return n
+	}
+
+	out := *n
+	out.Whens = nil
+	for _, when := range n.Whens {
+		condition, ok := when.Condition.(*LiteralNode)
+		if !ok {
+			out.Whens = append(out.Whens, when)
+			continue
+		}
+		matched := ToBool(condition.Value)
+		if subject != nil {
+			matched = equal(subject.Value, condition.Value)
+		}
+		if matched {
+			// Later clauses are unreachable
+			out.Else = when.Result
+			break
+		}
+	}
+
+	if len(out.Whens) == 0 {
+		if out.Else == nil {
+			return &LiteralNode{Span: n.Span}
+		}
+		return out.Else
+	}
+	if len(out.Whens) == len(n.Whens) && out.Else == n.Else {
+		return n
+	}
+	return &out
+}
+
+// call calls pure functions whose arguments are all constant.
+func (o *optimizer) call(n *FunctionCallNode, params map[string]bool) ExprNode {
+	namespace := ""
+	switch ns := n.Namespace.(type) {
+	case nil:
+		{
+			if params[n.Name] {
+				return n
+			}
+		}
+	case *VariableNode:
+		{
+			if params[ns.Name] {
+				return n
+			}
+			namespace = ns.Name
+		}
+	default:
+		{
+			return n
+		}
+	}
+
+	args := make([]Value, len(n.Args))
+	for i, arg := range n.Args {
+		literal, ok := arg.(*LiteralNode)
+		if !ok {
+			return n
+		}
+		args[i] = literal.Value
+	}
+	fn := o.env.PureFunction(namespace, n.Name)
+	if fn == nil {
+		return n
+	}
+	value, err := fn(args)
+	if err != nil || !hasSource(value) {
+		return n
+	}
+	return &LiteralNode{Value: value, Span: n.Span}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type optimizeEnv struct {
+	operands bool
+}
+
+func (e optimizeEnv) PureFunction(namespace, name string) Function {
+	switch namespace + "." + name {
+	case "string.upper":
+		return upper
+	case ".double":
+		return func(args []Value) (Value, error) {
+			return NumberValue(ToNumber(args[0]) * 2), nil
+		}
+	case ".fail":
+		return func(args []Value) (Value, error) {
+			return nil, fmt.Errorf("fail: always fails")
+		}
+	}
+	return nil
+}
+
+func (e optimizeEnv) OperandResults() bool {
+	return e.operands
+}
+
+func upper(args []Value) (Value, error) {
+	return StringValue(strings.ToUpper(toString(args[0]))), nil
+}
+
+// optimizeContext is a context with the functions of optimizeEnv, plus an
+// impure one, and a few variables to evaluate the original and optimized
+// expressions with.
+func optimizeContext() *MockContext {
+	ctx := NewMockContext()
+	ctx.SetFunction("double", optimizeEnv{}.PureFunction("", "double"))
+	ctx.SetFunction("fail", optimizeEnv{}.PureFunction("", "fail"))
+	ctx.SetFunction("random", func(args []Value) (Value, error) {
+		return NumberValue(4), nil
+	})
+	strs := NewMockContext()
+	strs.SetFunction("upper", upper)
+	ctx.SetVariable("string", strs)
+	ctx.SetVariable("x", NumberValue(2))
+	ctx.SetVariable("name", StringValue("admin"))
+	return ctx
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		operands bool
+		expected string
+	}{
+		{"arithmetic", "1 + 2 * 3", false, "7"},
+		{"partly constant", "x * (60 * 60)", false, "x * 3600"},
+		{"strings", "'a' + 'b' + name", false, "'ab' + name"},
+		{"comparison", "2 > 1", false, "true"},
+		{"unary", "-(2 + 3)", false, "-5"},
+		{"list", "[1, 2 + 3, 'a']", false, "[1, 5, 'a']"},
+		{"list with variable", "[1, x]", false, "[1, x]"},
+		{"map", "{a: 1, b: [2]}.b", false, "[2]"},
+		{"template", "`a${1 + 1}`", false, "'a2'"},
+		{"index", "[1, 2, 3][1:][0]", false, "2"},
+		{"pure call", "string.upper('abc') + double(2)", false, "'ABC4'"},
+		{"call with variable", "string.upper(name)", false, "string.upper(name)"},
+		{"impure call", "random()", false, "random()"},
+		{"failing call", "fail(1)", false, "fail(1)"},
+		{"failing operator", "1 % 0", false, "1 % 0"},
+		{"shadowed function", "list(f => f(1))", false, "list(f => f(1))"},
+		{"shadowed namespace", "apply(string => string.upper('a'))", false, "apply(string => string.upper('a'))"},
+		{"inside lambda", "apply(y => y + 2 * 3)", false, "apply(y => y + 6)"},
+		{"in list", "name in ['admin', 'root']", false, "name in ['admin', 'root']"},
+		{"not in list", "x not in [1, 2]", false, "x not in [1, 2]"},
+		{"constant in", "1 in [1, 2]", false, "true"},
+		{"false and", "false and x > 1", false, "false"},
+		{"true or", "true or x > 1", false, "true"},
+		{"true and", "true and x > 1", false, "x > 1"},
+		{"and true", "x > 1 and true", false, "x > 1"},
+		{"or false", "x > 1 or false", false, "x > 1"},
+		{"true and value", "true and x", false, "true and x"},
+		{"and false", "x > 1 and false", false, "x > 1 and false"},
+		{"operand true and", "true and x", true, "x"},
+		{"operand false and", "false and x", true, "false"},
+		{"operand null or", "null or name", true, "name"},
+		{"operand and true", "x and true", true, "x and true"},
+		{"not not", "not not (x > 1)", false, "x > 1"},
+		{"not not value", "not not x", false, "not not x"},
+		{"coalesce null", "null ?? x", false, "x"},
+		{"coalesce value", "1 ?? x", false, "1"},
+		{"conditional", "1 > 2 ? x : name", false, "name"},
+		{"case", "case when false then 1 when x > 1 then 2 when true then 3 when x then 4 end", false, "case when x > 1 then 2 else 3 end"},
+		{"case resolved", "case 2 when 1 then 'a' when 2 then 'b' else 'c' end", false, "'b'"},
+		{"case unmatched", "case when false then x end", false, "null"},
+		{"case subject", "case x when 1 then 'a' end", false, "case x when 1 then 'a' end"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			optimized := Optimize(ast, optimizeEnv{operands: tt.operands})
+			if actual := Format(optimized); actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+			if Format(ast) != Format(mustParse(t, tt.input)) {
+				t.Error("expected the original expression to be unchanged")
+			}
+
+			mock := optimizeContext()
+			var ctx Context = mock
+			if tt.operands {
+				ctx = operandResultContext{mock}
+			}
+			mock.SetFunction("apply", func(args []Value) (Value, error) {
+				return args[0].(FunctionValue)([]Value{ctx.GetVariable("string")})
+			})
+			mock.SetFunction("list", func(args []Value) (Value, error) {
+				return args[0].(FunctionValue)([]Value{FunctionValue(upper)})
+			})
+			expected, expectedErr := ast.Evaluate(ctx)
+			actual, actualErr := optimized.Evaluate(ctx)
+			if fmt.Sprint(expectedErr) != fmt.Sprint(actualErr) {
+				t.Fatalf("expected error %v, got %v", expectedErr, actualErr)
+			}
+			if !equal(expected, actual) {
+				t.Errorf("expected optimized expression to evaluate to %v, got %v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestInSetNode(t *testing.T) {
+	ast := Optimize(mustParse(t, "x in [1, '1', true, null, [2]]"), optimizeEnv{})
+	set, ok := ast.(*InSetNode)
+	if !ok {
+		t.Fatalf("expected InSetNode, got %T", ast)
+	}
+	if SpanOf(set) != SpanOf(mustParse(t, "x in [1, '1', true, null, [2]]")) {
+		t.Errorf("expected the span of the original expression, got %v", SpanOf(set))
+	}
+
+	tests := []struct {
+		value    Value
+		expected bool
+	}{
+		{NumberValue(1), true},
+		{NumberValue(1.0), true},
+		{StringValue("1"), true},
+		{BoolValue(true), true},
+		{nil, true},
+		{ListValue{NumberValue(2)}, true},
+		{NumberValue(2), false},
+		{BoolValue(false), false},
+		{StringValue("true"), false},
+	}
+	for _, tt := range tests {
+		ctx := NewMockContext()
+		ctx.SetVariable("x", tt.value)
+		result, err := set.Evaluate(ctx)
+		if err != nil {
+			t.Fatalf("evaluation error: %v", err)
+		}
+		if result != BoolValue(tt.expected) {
+			t.Errorf("expected %v in list to be %v, got %v", tt.value, tt.expected, result)
+		}
+	}
+
+	// Built by hand, without the set
+	manual := &InSetNode{Operand: &LiteralNode{Value: NumberValue(2)}, List: ListValue{NumberValue(2)}, Negate: true}
+	if result, _ := manual.Evaluate(NewMockContext()); result != BoolValue(false) {
+		t.Errorf("expected false, got %v", result)
+	}
+}
+
+func BenchmarkOptimize(b *testing.B) {
+	input := "role in ['admin', 'owner', 'editor', 'reviewer', 'auditor'] and score * (60 * 60) > 1000"
+	ast, err := ParseExpression(input)
+	if err != nil {
+		b.Fatalf("parse error: %v", err)
+	}
+	ctx := NewMockContext()
+	ctx.SetVariable("role", StringValue("auditor"))
+	ctx.SetVariable("score", NumberValue(1))
+
+	for name, node := range map[string]ExprNode{"original": ast, "optimized": Optimize(ast, optimizeEnv{})} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := node.Evaluate(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,531 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Type is the static type of an expression. AnyType stands for a type that is
+// not known until runtime and is compatible with every other type.
+type Type int
+
+const (
+	AnyType Type = iota
+	NullType
+	BoolType
+	NumberType
+	StringType
+	ListType
+	MapType
+	FunctionType
+)
+
+type (
+	// Signature describes the parameter and result types of a function.
+	// When Variadic is set the last parameter may repeat.
+	Signature struct {
+		Params   []Type
+		Variadic bool
+		Result   Type
+	}
+	// TypeEnv supplies the declared types of variables and functions. A
+	// namespace is empty for functions that are called without one.
+	TypeEnv interface {
+		VariableType(name string) Type
+		FunctionSignature(namespace, name string) *Signature
+	}
+	// TypeError is a type mismatch found by Check. Pos is the byte offset
+	// of the offending operator or name in the source expression.
+	TypeError struct {
+		Pos     int
+		Message string
+	}
+	typeChecker struct {
+		env    TypeEnv
+		errors []*TypeError
+	}
+)
+
+func (t Type) String() string {
+	switch t {
+	case NullType:
+		return "null"
+	case BoolType:
+		return "bool"
+	case NumberType:
+		return "number"
+	case StringType:
+		return "string"
+	case ListType:
+		return "list"
+	case MapType:
+		return "map"
+	case FunctionType:
+		return "function"
+	default:
+		return "any"
+	}
+}
+
+// TypeOf returns the type of a runtime value.
+func TypeOf(value Value) Type {
+	switch value.(type) {
+	case nil:
+		return NullType
+	case BoolValue:
+		return BoolType
+	case NumberValue:
+		return NumberType
+	case StringValue:
+		return StringType
+	case ListValue:
+		return ListType
+	case MapValue, MapObject:
+		return MapType
+	case FunctionValue:
+		return FunctionType
+	default:
+		return AnyType
+	}
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
+
+// Check infers the type of node and reports every type mismatch it finds,
+// ordered by position. Names that env does not know are typed AnyType;
+// resolving them is left to the caller.
+func Check(node ExprNode, env TypeEnv) (Type, []*TypeError) {
+	c := &typeChecker{env: env}
+	typ := c.check(node, nil)
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		return c.errors[i].Pos < c.errors[j].Pos
+	})
+	return typ, c.errors
+}
+
+func (c *typeChecker) errorf(pos int, format string, args ...any) {
+	c.errors = append(c.errors, &TypeError{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// assignable reports whether a value of type actual may be used where want
+// is expected. Null is accepted everywhere since it propagates at runtime.
+func assignable(actual, want Type) bool {
+	return actual == want || actual == AnyType || want == AnyType || actual == NullType
+}
+
+// accepts reports whether an argument of type actual may be passed to a
+// parameter of type want. It follows the conversions the library applies
+// to arguments at run time, see lib.Accepts: strings, numbers and bools
+// convert to strings and numbers, and any value but a function converts to
+// a bool. Operands of operators are checked with assignable instead.
+func accepts(actual, want Type) bool {
+	if assignable(actual, want) {
+		return true
+	}
+	switch want {
+	case StringType, NumberType:
+		{
+			return actual == StringType || actual == NumberType || actual == BoolType
+		}
+	case BoolType:
+		{
+			return actual != FunctionType
+		}
+	default:
+		{
+			return false
+		}
+	}
+}
+
+// join returns the common type of two branches.
+func join(a, b Type) Type {
+	switch {
+	case a == b:
+		return a
+	case a == NullType:
+		return b
+	case b == NullType:
+		return a
+	default:
+		return AnyType
+	}
+}
+
+// check returns the type of node. params holds the parameters of the
+// enclosing lambdas, which are untyped.
+func (c *typeChecker) check(node ExprNode, params map[string]bool) Type {
+	switch n := node.(type) {
+	case *LiteralNode:
+		{
+			return TypeOf(n.Value)
+		}
+	case *VariableNode:
+		{
+			if params[n.Name] {
+				return AnyType
+			}
+			return c.env.VariableType(n.Name)
+		}
+	case *BinaryOpNode:
+		{
+			return c.checkBinary(n, params)
+		}
+	case *InSetNode:
+		{
+			c.check(n.Operand, params)
+			return BoolType
+		}
+	case *UnaryOpNode:
+		{
+			operand := c.check(n.Operand, params)
+			switch n.Operator {
+			case "-":
+				{
+					if !assignable(operand, NumberType) {
+						c.errorf(n.Pos, "operator '-' expects a number, got %s", operand)
+					}
+					return NumberType
+				}
+			default:
+				{
+					return BoolType
+				}
+			}
+		}
+	case *FieldAccessNode:
+		{
+			object := c.check(n.Object, params)
+			switch object {
+			case ListType:
+				{
+					return ListType
+				}
+			case MapType, AnyType, NullType:
+				{
+					return AnyType
+				}
+			default:
+				{
+					c.errorf(c.position(n.Object, -1), "cannot access field '%s' of %s", n.Field, object)
+					return AnyType
+				}
+			}
+		}
+	case *IndexAccessNode:
+		{
+			return c.checkIndex(n, params)
+		}
+	case *FunctionCallNode:
+		{
+			return c.checkCall(n, params)
+		}
+	case *ListNode:
+		{
+			for _, elem := range n.Elements {
+				c.check(elem, params)
+			}
+			return ListType
+		}
+	case *TemplateNode:
+		{
+			for _, part := range n.Parts {
+				c.check(part, params)
+			}
+			return StringType
+		}
+	case *MapNode:
+		{
+			for _, entry := range n.Entries {
+				value := c.check(entry.Value, params)
+				if entry.Spread {
+					if !assignable(value, MapType) {
+						c.errorf(c.position(entry.Value, -1), "cannot spread %s into a map", value)
+					}
+					continue
+				}
+				c.check(entry.Key, params)
+			}
+			return MapType
+		}
+	case *ConditionalNode:
+		{
+			c.check(n.Condition, params)
+			return join(c.check(n.Then, params), c.check(n.Else, params))
+		}
+	case *CaseNode:
+		{
+			if n.Subject != nil {
+				c.check(n.Subject, params)
+			}
+			result := NullType
+			for _, when := range n.Whens {
+				c.check(when.Condition, params)
+				result = join(result, c.check(when.Result, params))
+			}
+			if n.Else == nil {
+				return result
+			}
+			return join(result, c.check(n.Else, params))
+		}
+	case *LambdaNode:
+		{
+			scope := make(map[string]bool)
+			for name := range params {
+				scope[name] = true
+			}
+			for _, name := range n.Params {
+				scope[name] = true
+			}
+			c.check(n.Body, scope)
+			return FunctionType
+		}
+	default:
+		{
+			return AnyType
+		}
+	}
+}
+
+func (c *typeChecker) checkBinary(n *BinaryOpNode, params map[string]bool) Type {
+	left := c.check(n.Left, params)
+	right := c.check(n.Right, params)
+
+	switch n.Operator {
+	case "and", "or", "==", "=", "!=":
+		{
+			return BoolType
+		}
+	case "??":
+		{
+			return join(left, right)
+		}
+	case "in", "not in":
+		{
+			if !assignable(right, ListType) {
+				c.errorf(n.Pos, "operator '%s' expects a list, got %s", n.Operator, right)
+			}
+			return BoolType
+		}
+	case "<", "<=", ">", ">=":
+		{
+			c.expectNumbers(n, left, right)
+			return BoolType
+		}
+	case "+":
+		{
+			switch {
+			case left == StringType || right == StringType:
+				{
+					return StringType
+				}
+			case left == ListType && right == ListType:
+				{
+					return ListType
+				}
+			case left == AnyType || right == AnyType:
+				{
+					if left == ListType || right == ListType {
+						return ListType
+					}
+					return AnyType
+				}
+			default:
+				{
+					c.expectNumbers(n, left, right)
+					return NumberType
+				}
+			}
+		}
+	default:
+		{
+			c.expectNumbers(n, left, right)
+			return NumberType
+		}
+	}
+}
+
+func (c *typeChecker) expectNumbers(n *BinaryOpNode, left, right Type) {
+	if !assignable(left, NumberType) || !assignable(right, NumberType) {
+		c.errorf(n.Pos, "operator '%s' expects numbers, got %s and %s", n.Operator, left, right)
+	}
+}
+
+func (c *typeChecker) checkIndex(n *IndexAccessNode, params map[string]bool) Type {
+	object := c.check(n.Object, params)
+
+	var index Type
+	switch idx := n.Index.(type) {
+	case *RangeNode:
+		{
+			for _, bound := range []ExprNode{idx.Begin, idx.End, idx.Step} {
+				if bound == nil {
+					continue
+				}
+				if typ := c.check(bound, params); !assignable(typ, NumberType) {
+					c.errorf(c.position(bound, c.position(n.Object, -1)), "slice bounds must be numbers, got %s", typ)
+				}
+			}
+			switch object {
+			case ListType, StringType:
+				{
+					return object
+				}
+			case AnyType, NullType:
+				{
+					return AnyType
+				}
+			default:
+				{
+					c.errorf(c.position(n.Object, -1), "cannot slice %s", object)
+					return AnyType
+				}
+			}
+		}
+	case *EachNode:
+		{
+			if !assignable(object, ListType) {
+				c.errorf(c.position(n.Object, -1), "cannot iterate over %s", object)
+			}
+			return ListType
+		}
+	default:
+		{
+			index = c.check(n.Index, params)
+		}
+	}
+
+	switch object {
+	case ListType:
+		{
+			if !assignable(index, NumberType) && !assignable(index, StringType) {
+				c.errorf(c.position(n.Index, c.position(n.Object, -1)), "list index must be a number or string, got %s", index)
+			}
+		}
+	case MapType:
+		{
+			if !assignable(index, StringType) {
+				c.errorf(c.position(n.Index, c.position(n.Object, -1)), "map key must be a string, got %s", index)
+			}
+		}
+	case AnyType, NullType:
+		{
+		}
+	default:
+		{
+			c.errorf(c.position(n.Object, -1), "cannot index %s", object)
+		}
+	}
+	return AnyType
+}
+
+func (c *typeChecker) checkCall(n *FunctionCallNode, params map[string]bool) Type {
+	args := make([]Type, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = c.check(arg, params)
+	}
+
+	namespace := ""
+	switch ns := n.Namespace.(type) {
+	case nil:
+		{
+			if params[n.Name] {
+				return AnyType
+			}
+		}
+	case *VariableNode:
+		{
+			namespace = ns.Name
+		}
+	default:
+		{
+			c.check(n.Namespace, params)
+			return AnyType
+		}
+	}
+
+	sig := c.env.FunctionSignature(namespace, n.Name)
+	if sig == nil {
+		return AnyType
+	}
+	for i, arg := range args {
+		want, ok := sig.param(i)
+		if !ok {
+			break
+		}
+		if !accepts(arg, want) {
+			c.errorf(c.position(n.Args[i], n.Pos), "argument %d of '%s' must be %s, got %s", i+1, n.Name, want, arg)
+		}
+	}
+	return sig.Result
+}
+
+// param returns the type of the i-th parameter, repeating the last one for
+// variadic functions.
+func (s *Signature) param(i int) (Type, bool) {
+	if i < len(s.Params) {
+		return s.Params[i], true
+	}
+	if s.Variadic && len(s.Params) > 0 {
+		return s.Params[len(s.Params)-1], true
+	}
+	return AnyType, false
+}
+
+// position returns the best known source offset for node, which is the
+// position of its leftmost operator or name, or fallback if it has none.
+func (c *typeChecker) position(node ExprNode, fallback int) int {
+	switch n := node.(type) {
+	case *VariableNode:
+		{
+			return n.Pos
+		}
+	case *FunctionCallNode:
+		{
+			if n.Namespace != nil {
+				return c.position(n.Namespace, n.Pos)
+			}
+			return n.Pos
+		}
+	case *BinaryOpNode:
+		{
+			return c.position(n.Left, n.Pos)
+		}
+	case *UnaryOpNode:
+		{
+			if n.Operator == "is null" || n.Operator == "is not null" {
+				return c.position(n.Operand, n.Pos)
+			}
+			return n.Pos
+		}
+	case *FieldAccessNode:
+		{
+			return c.position(n.Object, fallback)
+		}
+	case *IndexAccessNode:
+		{
+			return c.position(n.Object, fallback)
+		}
+	default:
+		{
+			return fallback
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"testing"
+)
+
+type mockTypeEnv struct {
+	variables  map[string]Type
+	signatures map[string]*Signature
+}
+
+func (e *mockTypeEnv) VariableType(name string) Type {
+	return e.variables[name]
+}
+
+func (e *mockTypeEnv) FunctionSignature(namespace, name string) *Signature {
+	if namespace != "" {
+		name = namespace + "." + name
+	}
+	return e.signatures[name]
+}
+
+func newMockTypeEnv() *mockTypeEnv {
+	return &mockTypeEnv{
+		variables: map[string]Type{
+			"age":   NumberType,
+			"name":  StringType,
+			"tags":  ListType,
+			"user":  MapType,
+			"flag":  BoolType,
+			"other": AnyType,
+		},
+		signatures: map[string]*Signature{
+			"string.upper": {Params: []Type{StringType}, Result: StringType},
+			"math.max":     {Params: []Type{NumberType}, Variadic: true, Result: NumberType},
+			"util.if":      {Params: []Type{BoolType, AnyType, AnyType}, Result: AnyType},
+		},
+	}
+}
+
+func TestCheckInference(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Type
+	}{
+		{"number literal", "42", NumberType},
+		{"string literal", "'a'", StringType},
+		{"null literal", "null", NullType},
+		{"arithmetic", "age * 2 + 1", NumberType},
+		{"concatenation", "name + 1", StringType},
+		{"list append", "tags + [1]", ListType},
+		{"comparison", "age > 18", BoolType},
+		{"logical", "flag and age > 18", BoolType},
+		{"membership", "name in tags", BoolType},
+		{"is null", "user is null", BoolType},
+		{"not", "not flag", BoolType},
+		{"template", "`${age}`", StringType},
+		{"list literal", "[1, 2]", ListType},
+		{"map literal", "{a: 1}", MapType},
+		{"map field", "user.name", AnyType},
+		{"list field", "tags.name", ListType},
+		{"list slice", "tags[1:]", ListType},
+		{"string slice", "name[:2]", StringType},
+		{"conditional same types", "flag ? 1 : 2", NumberType},
+		{"conditional mixed types", "flag ? 1 : 'a'", AnyType},
+		{"conditional with null", "flag ? null : 'a'", StringType},
+		{"coalesce", "null ?? 'a'", StringType},
+		{"case", "case when flag then 'a' else 'b' end", StringType},
+		{"function result", "string.upper(name)", StringType},
+		{"unknown function", "util.foo(name)", AnyType},
+		{"lambda", "x => x * 2", FunctionType},
+		{"unknown variable", "missing", AnyType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			typ, errs := Check(ast, newMockTypeEnv())
+			if len(errs) != 0 {
+				t.Fatalf("unexpected type errors: %v", errs)
+			}
+			if typ != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, typ)
+			}
+		})
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		pos     int
+		message string
+	}{
+		{"string times number", "'abc' * 2", 6, "operator '*' expects numbers, got string and number"},
+		{"string comparison", "name > 3", 5, "operator '>' expects numbers, got string and number"},
+		{"bool plus number", "flag + 1", 5, "operator '+' expects numbers, got bool and number"},
+		{"map minus", "user - 1", 5, "operator '-' expects numbers, got map and number"},
+		{"negate string", "-name", 0, "operator '-' expects a number, got string"},
+		{"in non list", "1 in name", 2, "operator 'in' expects a list, got string"},
+		{"field of number", "age.value", 0, "cannot access field 'value' of number"},
+		{"index number", "age[0]", 0, "cannot index number"},
+		{"map key", "user[1]", 0, "map key must be a string, got number"},
+		{"slice bound", "tags['a':]", 0, "slice bounds must be numbers, got string"},
+		{"slice map", "user[1:2]", 0, "cannot slice map"},
+		{"argument type", "string.upper(tags)", 13, "argument 1 of 'upper' must be string, got list"},
+		{"argument literal", "string.upper({})", 7, "argument 1 of 'upper' must be string, got map"},
+		{"variadic argument", "math.max(1, 2, tags)", 15, "argument 3 of 'max' must be number, got list"},
+		{"bool argument", "util.if(x => x, 1, 2)", 5, "argument 1 of 'if' must be bool, got function"},
+		{"spread", "{...tags}", 4, "cannot spread list into a map"},
+		{"inside lambda", "x => x + (name * 2)", 15, "operator '*' expects numbers, got string and number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			_, errs := Check(ast, newMockTypeEnv())
+			if len(errs) != 1 {
+				t.Fatalf("expected 1 type error, got %v", errs)
+			}
+			if errs[0].Pos != tt.pos {
+				t.Errorf("expected position %d, got %d", tt.pos, errs[0].Pos)
+			}
+			if errs[0].Message != tt.message {
+				t.Errorf("expected %q, got %q", tt.message, errs[0].Message)
+			}
+		})
+	}
+}
+
+func TestCheckAnyAndNull(t *testing.T) {
+	tests := []string{
+		"other * 2",
+		"other.x.y[1]",
+		"null + 1",
+		"string.upper(other)",
+		"string.upper(null)",
+		"x => x.name * 2",
+		"age in other",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			ast, err := ParseExpression(input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			if _, errs := Check(ast, newMockTypeEnv()); len(errs) != 0 {
+				t.Errorf("unexpected type errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestCheckArgumentConversions(t *testing.T) {
+	tests := []string{
+		"string.upper(age)",
+		"string.upper(flag)",
+		"math.max(1, '2', flag)",
+		"util.if(tags, 1, 2)",
+		"util.if(user, 1, 2)",
+		"util.if(name, 1, 2)",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			ast, err := ParseExpression(input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			if _, errs := Check(ast, newMockTypeEnv()); len(errs) != 0 {
+				t.Errorf("unexpected type errors: %v", errs)
+			}
+		})
+	}
+}
+
+func TestCheckMultipleErrors(t *testing.T) {
+	ast, err := ParseExpression("name * 2 > 1 and -flag")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	_, errs := Check(ast, newMockTypeEnv())
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 type errors, got %v", errs)
+	}
+	if errs[0].Pos != 5 || errs[1].Pos != 17 {
+		t.Errorf("expected errors ordered by position, got %v", errs)
+	}
+}
@@ -0,0 +1,480 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"strings"
+)
+
+type (
+	// frame holds the arguments of a running lambda.
+	frame struct {
+		values []Value
+		parent *frame
+	}
+)
+
+// Evaluate runs the bytecode against ctx.
+func (b *Bytecode) Evaluate(ctx Context) (Value, error) {
+	e, ctx := begin(ctx)
+	return b.run(ctx, nil, e, e.OperandResults())
+}
+
+func (f *frame) local(depth, index int32) Value {
+	for ; depth > 0; depth-- {
+		f = f.parent
+	}
+	return f.values[index]
+}
+
+// function returns the function a lambda creates when it is evaluated.
+func (l *lambda) function(ctx Context, env *frame, e *evaluation, operands bool) FunctionValue {
+	return func(args []Value) (Value, error) {
+		if err := e.cancelled(l.span); err != nil {
+			return nil, err
+		}
+		values := make([]Value, l.params)
+		copy(values, args)
+		return l.body.run(ctx, &frame{values: values, parent: env}, e, operands)
+	}
+}
+
+// context returns the context a deferred node is evaluated in, with the
+// lambda parameters in env in scope.
+func (d deferred) context(ctx Context, env *frame, e *evaluation) Context {
+	for i := range d.scopes {
+		// Outermost lambda first
+		depth := int32(len(d.scopes) - 1 - i)
+		values := make(map[string]Value, len(d.scopes[i]))
+		for index, name := range d.scopes[i] {
+			values[name] = env.local(depth, int32(index))
+		}
+		ctx = &scope{parent: ctx, values: values, evaluation: e}
+	}
+	return ctx
+}
+
+// run runs the bytecode of the expression, or of a lambda with its
+// arguments in env. The value stack lives on the Go stack unless it outgrows
+// the buffer, and so do the first variables read, which are only read from
+// ctx once. The arguments of all calls are allocated together. e is the
+// evaluation the bytecode runs in.
+func (b *Bytecode) run(ctx Context, env *frame, e *evaluation, operands bool) (Value, error) {
+	if err := e.charge(len(b.code), b.span); err != nil {
+		return nil, err
+	}
+	var buffer [16]Value
+	stack := buffer[:0]
+	var variables [8]Value
+	var read uint8
+	var arguments []Value
+
+	code := b.code
+	for pc := 0; pc < len(code); pc++ {
+		in := &code[pc]
+		switch in.op {
+		case opConst:
+			{
+				if in.b != 0 && e.sized() {
+					if err := e.checkSize(b.constants[in.a], SpanOf(b.nodes[in.b-1])); err != nil {
+						return nil, err
+					}
+				}
+				stack = append(stack, b.constants[in.a])
+			}
+		case opVar:
+			{
+				var value Value
+				if in.a >= int32(len(variables)) {
+					value = ctx.GetVariable(b.names[in.a])
+				} else {
+					if read&(1<<in.a) == 0 {
+						variables[in.a] = ctx.GetVariable(b.names[in.a])
+						read |= 1 << in.a
+					}
+					value = variables[in.a]
+				}
+				value, err := b.resolve(value, in.b)
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, value)
+			}
+		case opLocal:
+			{
+				value, err := b.resolve(env.local(in.a, in.b), in.c)
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, value)
+			}
+		case opPop:
+			{
+				stack = stack[:len(stack)-1]
+			}
+		case opJump:
+			{
+				pc = int(in.a) - 1
+			}
+		case opJumpIfFalse:
+			{
+				condition := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if !ToBool(condition) {
+					pc = int(in.a) - 1
+				}
+			}
+		case opJumpIfNotNull:
+			{
+				if stack[len(stack)-1] != nil {
+					pc = int(in.a) - 1
+					continue
+				}
+				stack = stack[:len(stack)-1]
+			}
+		case opJumpIfNull:
+			{
+				if stack[len(stack)-1] == nil {
+					pc = int(in.a) - 1
+				}
+			}
+		case opLogical:
+			{
+				left := stack[len(stack)-1]
+				decisive := in.b == 1
+				if operands {
+					if ToBool(left) == decisive {
+						pc = int(in.a) - 1
+						continue
+					}
+					stack = stack[:len(stack)-1]
+					continue
+				}
+				if left != nil && ToBool(left) == decisive {
+					stack[len(stack)-1] = BoolValue(decisive)
+					pc = int(in.a) - 1
+				}
+			}
+		case opLogicalEnd:
+			{
+				if operands {
+					continue
+				}
+				right := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				stack[len(stack)-1] = logical(in.b == 1, stack[len(stack)-1], right)
+			}
+		case opBinary:
+			{
+				right := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if value, ok := fastBinary(in.c, stack[len(stack)-1], right); ok {
+					stack[len(stack)-1] = value
+					continue
+				}
+				value, err := b.binary(in.a, stack[len(stack)-1], right, e)
+				if err != nil {
+					return nil, err
+				}
+				stack[len(stack)-1] = value
+			}
+		case opBinaryConst:
+			{
+				right := b.constants[in.b]
+				if value, ok := fastBinary(in.c, stack[len(stack)-1], right); ok {
+					stack[len(stack)-1] = value
+					continue
+				}
+				value, err := b.binary(in.a, stack[len(stack)-1], right, e)
+				if err != nil {
+					return nil, err
+				}
+				stack[len(stack)-1] = value
+			}
+		case opUnary:
+			{
+				value, err := b.nodes[in.a].(*UnaryOpNode).apply(stack[len(stack)-1])
+				if err != nil {
+					return nil, err
+				}
+				stack[len(stack)-1] = value
+			}
+		case opInSet:
+			{
+				stack[len(stack)-1] = b.nodes[in.a].(*InSetNode).apply(stack[len(stack)-1])
+			}
+		case opField:
+			{
+				value, err := b.resolve(Field(stack[len(stack)-1], b.names[in.a]), in.b)
+				if err != nil {
+					return nil, err
+				}
+				stack[len(stack)-1] = value
+			}
+		case opIndex:
+			{
+				index := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				value, err := b.nodes[in.a].(*IndexAccessNode).apply(stack[len(stack)-1], index)
+				if err != nil {
+					return nil, err
+				}
+				stack[len(stack)-1] = value
+			}
+		case opFunction:
+			{
+				n := b.nodes[in.b].(*FunctionCallNode)
+				fn := function(ctx, n.Name, e)
+				if fn == nil {
+					var value Value
+					if in.c > 0 {
+						value = env.local((in.c-1)>>16, (in.c-1)&0xffff)
+					} else {
+						value = ctx.GetVariable(n.Name)
+					}
+					if value, ok := value.(FunctionValue); ok {
+						fn = Function(value)
+					}
+				}
+				if fn == nil {
+					stack = append(stack, BoolValue(false))
+					pc = int(in.a) - 1
+					continue
+				}
+				stack = append(stack, fn)
+			}
+		case opMethod:
+			{
+				n := b.nodes[in.b].(*FunctionCallNode)
+				value := stack[len(stack)-1]
+				namespace, ok := value.(Context)
+				if !ok {
+					return nil, evalErrorf(n.Span, "unexpected identifier %v", value)
+				}
+				fn := function(namespace, n.Name, e)
+				if fn == nil {
+					stack[len(stack)-1] = BoolValue(false)
+					pc = int(in.a) - 1
+					continue
+				}
+				stack[len(stack)-1] = fn
+			}
+		case opCall:
+			{
+				if arguments == nil {
+					arguments = make([]Value, b.arguments)
+				}
+				base := len(stack) - int(in.a)
+				args := arguments[in.c : in.c+in.a : in.c+in.a]
+				copy(args, stack[base:])
+				n := b.nodes[in.b].(*FunctionCallNode)
+				if err := e.cancelled(n.Span); err != nil {
+					return nil, err
+				}
+				value, err := stack[base-1].(Function)(args)
+				if err != nil {
+					return nil, n.callError(err)
+				}
+				if err := e.size(value, n.Span); err != nil {
+					return nil, err
+				}
+				stack = stack[:base]
+				stack[base-1] = value
+			}
+		case opList:
+			{
+				base := len(stack) - int(in.a)
+				list := make(ListValue, in.a)
+				copy(list, stack[base:])
+				if err := e.size(list, b.nodes[in.b].(*ListNode).Span); err != nil {
+					return nil, err
+				}
+				stack = append(stack[:base], list)
+			}
+		case opTemplate:
+			{
+				base := len(stack) - int(in.a)
+				var sb strings.Builder
+				for _, part := range stack[base:] {
+					if err := writeTemplatePart(&sb, part, e.bounds()); err != nil {
+						return nil, evalError(b.nodes[in.b].(*TemplateNode).Span, err)
+					}
+				}
+				value := StringValue(sb.String())
+				if err := e.size(value, b.nodes[in.b].(*TemplateNode).Span); err != nil {
+					return nil, err
+				}
+				stack = append(stack[:base], value)
+			}
+		case opMap:
+			{
+				stack = append(stack, make(MapValue, in.a))
+			}
+		case opMapSet:
+			{
+				key, value := stack[len(stack)-1], stack[len(stack)-2]
+				stack = stack[:len(stack)-2]
+				n := b.nodes[in.a].(*MapNode)
+				if err := n.set(stack[len(stack)-1].(MapValue), key, value); err != nil {
+					return nil, err
+				}
+				if err := e.size(stack[len(stack)-1], n.Span); err != nil {
+					return nil, err
+				}
+			}
+		case opMapSpread:
+			{
+				value := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				n := b.nodes[in.a].(*MapNode)
+				if err := n.spread(stack[len(stack)-1].(MapValue), value); err != nil {
+					return nil, err
+				}
+				if err := e.size(stack[len(stack)-1], n.Span); err != nil {
+					return nil, err
+				}
+			}
+		case opRange:
+			{
+				base := len(stack) - 3
+				value := RangeValue{Begin: stack[base], End: stack[base+1], Step: stack[base+2]}
+				stack = append(stack[:base], value)
+			}
+		case opCaseMatch:
+			{
+				condition := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if !equal(stack[len(stack)-1], condition) {
+					pc = int(in.a) - 1
+					continue
+				}
+				stack = stack[:len(stack)-1]
+			}
+		case opLambda:
+			{
+				stack = append(stack, b.lambdas[in.a].function(ctx, env, e, operands))
+			}
+		case opEval:
+			{
+				d := b.deferred[in.a]
+				value, err := d.node.Evaluate(d.context(ctx, env, e))
+				if err != nil {
+					return nil, err
+				}
+				stack = append(stack, value)
+			}
+		}
+	}
+	return stack[len(stack)-1], nil
+}
+
+// binary applies the BinaryOpNode nodes[node] to left and right, and
+// checks the size of the result.
+func (b *Bytecode) binary(node int32, left, right Value, e *evaluation) (Value, error) {
+	n := b.nodes[node].(*BinaryOpNode)
+	value, err := n.apply(left, right, e.bounds())
+	if err != nil {
+		return nil, err
+	}
+	if err := e.size(value, n.Span); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// resolve loads value if it is Lazy and node is not 0, attributing errors
+// to nodes[node-1].
+func (b *Bytecode) resolve(value Value, node int32) (Value, error) {
+	if node == 0 {
+		return value, nil
+	}
+	if _, ok := value.(Lazy); !ok {
+		return value, nil
+	}
+	switch n := b.nodes[node-1].(type) {
+	case *VariableNode:
+		{
+			return resolve(value, n.Span)
+		}
+	default:
+		{
+			return resolve(value, n.(*FieldAccessNode).Span)
+		}
+	}
+}
+
+// fastBinary applies the operators marked by the compiler without going
+// through BinaryOpNode.apply: equality to any operands, and arithmetic and
+// ordering to numbers.
+func fastBinary(op int32, left, right Value) (Value, bool) {
+	switch op {
+	case opcodeNone:
+		{
+			return nil, false
+		}
+	case opcodeEqual:
+		{
+			return BoolValue(equal(left, right)), true
+		}
+	case opcodeNotEqual:
+		{
+			return BoolValue(!equal(left, right)), true
+		}
+	}
+	l, ok := left.(NumberValue)
+	if !ok {
+		return nil, false
+	}
+	r, ok := right.(NumberValue)
+	if !ok {
+		return nil, false
+	}
+	switch op {
+	case opcodeAdd:
+		{
+			return l + r, true
+		}
+	case opcodeSub:
+		{
+			return l - r, true
+		}
+	case opcodeMul:
+		{
+			return l * r, true
+		}
+	case opcodeDiv:
+		{
+			return l / r, true
+		}
+	case opcodeLess:
+		{
+			return BoolValue(l < r), true
+		}
+	case opcodeLessEqual:
+		{
+			return BoolValue(l <= r), true
+		}
+	case opcodeGreater:
+		{
+			return BoolValue(l > r), true
+		}
+	case opcodeGreaterEqual:
+		{
+			return BoolValue(l >= r), true
+		}
+	}
+	return nil, false
+}
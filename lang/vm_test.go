@@ -0,0 +1,346 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// customNode is a node from outside the parser, which the compiler leaves
+// to the tree walker.
+type customNode struct {
+	name string
+}
+
+func (n *customNode) Evaluate(ctx Context) (Value, error) {
+	return ListValue{StringValue(n.name), ctx.GetVariable(n.name)}, nil
+}
+
+func vmContext() *MockContext {
+	ctx := NewMockContext()
+	ctx.SetVariable("user", MapValue{
+		"name":  StringValue("john"),
+		"age":   NumberValue(30),
+		"roles": ListValue{StringValue("admin"), StringValue("dev")},
+	})
+	ctx.SetVariable("items", ListValue{
+		MapValue{"id": NumberValue(1), "price": NumberValue(10)},
+		MapValue{"id": NumberValue(2), "price": NumberValue(20)},
+	})
+	ctx.SetVariable("x", NumberValue(2))
+	ctx.SetVariable("s", StringValue("hello"))
+	ctx.SetVariable("flag", BoolValue(true))
+	ctx.SetVariable("double", FunctionValue(func(args []Value) (Value, error) {
+		return NumberValue(ToNumber(args[0]) * 2), nil
+	}))
+	ctx.SetFunction("upper", upper)
+	ctx.SetFunction("count", func(args []Value) (Value, error) {
+		return NumberValue(len(args)), nil
+	})
+	ctx.SetFunction("map", func(args []Value) (Value, error) {
+		list, _ := args[0].(ListValue)
+		fn, ok := args[1].(FunctionValue)
+		if !ok {
+			return nil, fmt.Errorf("map: expected function")
+		}
+		out := make(ListValue, len(list))
+		for i, item := range list {
+			value, err := fn([]Value{item, NumberValue(i)})
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+	})
+	ctx.SetFunction("call", func(args []Value) (Value, error) {
+		return args[0].(FunctionValue)(args[1:])
+	})
+	ctx.SetFunction("fail", func(args []Value) (Value, error) {
+		return nil, errors.New("fail: always fails")
+	})
+	strs := NewMockContext()
+	strs.SetFunction("upper", upper)
+	ctx.SetVariable("string", strs)
+	return ctx
+}
+
+var vmExpressions = []string{
+	"1 + 2 * 3 - 4 / 2",
+	"7 % 3 + 7 // 2 + 2 ** 3",
+	"'a' + 1 + true",
+	"[1, 2] + [3]",
+	"x > 1 and x < 3",
+	"x = 2 or fail()",
+	"null and false",
+	"null or true",
+	"null and true",
+	"false or null",
+	"flag and s",
+	"s or 0",
+	"null ?? x",
+	"x ?? fail()",
+	"not flag",
+	"not null",
+	"-x",
+	"-null",
+	"x is null",
+	"missing is not null",
+	"user.name",
+	"user.roles[1]",
+	"user['age']",
+	"user.missing.deeper",
+	"user?.name",
+	"missing?.name",
+	"missing?.[0]",
+	"missing?.items[0].id",
+	"missing?.[0].id[1]",
+	"missing?.ns.upper(s)[0]",
+	"user?.missing[0]",
+	"null?.items[0]",
+	"[null?.items[0], 1]",
+	"items[0]?.id.x[0]",
+	"items.price",
+	"items['id']",
+	"items[?]",
+	"missing?.[?]",
+	"items[1:]",
+	"s[1:3]",
+	"s[::-1]",
+	"[1, 2, 3, 4][::2]",
+	"user.roles[5]",
+	"user[true]",
+	"items[true]",
+	"s[0]",
+	"missing[0]",
+	"[1, 2][0:1:0]",
+	"x in [1, 2, 3]",
+	"'admin' in user.roles",
+	"x not in [1]",
+	"x in null",
+	"x < null",
+	"upper(s)",
+	"string.upper(s)",
+	"string.missing(s)",
+	"missing(fail())",
+	"missing?.upper(s)",
+	"missing.upper(s)",
+	"s.upper()",
+	"double(x)",
+	"count()",
+	"count(1, 2, 3)",
+	"fail(1)",
+	"string.upper(fail())",
+	"map(items, i => i.price * 2)",
+	"map(items, (i, n) => n + x)",
+	"map([1, 2], a => map([10, 20], b => a + b))",
+	"map([1, 2], a => map([10], a => a))",
+	"map([1, 2], double => double(3))",
+	"map([1, 2], upper => upper(3))",
+	"map([1, 2], string => string.upper('a'))",
+	"map([1, 2], (a, a) => a)",
+	"call(a => a, 5)",
+	"call(() => x)",
+	"call((a, b) => b, 1)",
+	"call(f => f(2), a => a * 10)",
+	"call(a => b => a + b, 1)",
+	"map([1, 0], a => 10 % a)",
+	"map([1], a => fail(a))",
+	"map(items, i => { id: i.id, p: i.price })",
+	"[1, x, [s], null]",
+	"`${s} is ${x + 1} ${null}`",
+	"{a: 1, 'b': x, [3]: s, [true]: 1}",
+	"{...user, name: 'jane'}",
+	"{...null, a: 1}",
+	"{...x}",
+	"{[x]: 1}",
+	"[1, 2, 3, 4, 5, 6][1:5:2]",
+	"s[:]",
+	"s[x:]",
+	"x > 1 ? 'big' : 'small'",
+	"missing ? 1 : 2",
+	"case when x > 5 then 'a' when x > 1 then 'b' else 'c' end",
+	"case when false then 1 end",
+	"case x when 1 then 'one' when 2 then 'two' end",
+	"case s when 'x' then 1 else 2 end",
+	"case x when fail() then 1 end",
+	"x => x",
+}
+
+func TestBytecode(t *testing.T) {
+	for _, operands := range []bool{false, true} {
+		for _, input := range vmExpressions {
+			t.Run(fmt.Sprintf("%s/%v", input, operands), func(t *testing.T) {
+				ast, err := ParseExpression(input)
+				if err != nil {
+					t.Fatalf("parse error: %v", err)
+				}
+				var ctx Context = vmContext()
+				if operands {
+					ctx = operandResultContext{ctx.(*MockContext)}
+				}
+
+				expected, expectedErr := ast.Evaluate(ctx)
+				actual, actualErr := Compile(ast).Evaluate(ctx)
+				assertSameResult(t, expected, expectedErr, actual, actualErr)
+
+				optimized := Compile(Optimize(ast, optimizeEnv{operands: operands}))
+				actual, actualErr = optimized.Evaluate(ctx)
+				assertSameResult(t, expected, expectedErr, actual, actualErr)
+			})
+		}
+	}
+}
+
+func assertSameResult(t *testing.T, expected Value, expectedErr error, actual Value, actualErr error) {
+	t.Helper()
+	if !reflect.DeepEqual(expectedErr, actualErr) {
+		t.Fatalf("expected error %#v, got %#v", expectedErr, actualErr)
+	}
+	if _, ok := expected.(FunctionValue); ok {
+		if _, ok := actual.(FunctionValue); !ok {
+			t.Errorf("expected a function, got %v", actual)
+		}
+		return
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestBytecodeDeferred(t *testing.T) {
+	ctx := vmContext()
+	nodes := []ExprNode{
+		&customNode{name: "x"},
+		&BinaryOpNode{Left: &customNode{name: "x"}, Right: &LiteralNode{Value: NumberValue(1)}, Operator: "??"},
+		&BinaryOpNode{Left: &LiteralNode{Value: NumberValue(1)}, Right: &LiteralNode{Value: NumberValue(1)}, Operator: "^"},
+		&UnaryOpNode{Operand: &LiteralNode{Value: NumberValue(1)}, Operator: "~"},
+		&BadNode{Span: Span{Start: 1, End: 2}},
+	}
+	for _, node := range nodes {
+		expected, expectedErr := node.Evaluate(ctx)
+		actual, actualErr := Compile(node).Evaluate(ctx)
+		assertSameResult(t, expected, expectedErr, actual, actualErr)
+	}
+
+	// Lambda parameters are in scope of a custom node in a lambda body
+	node := &FunctionCallNode{Name: "map", Args: []ExprNode{
+		&LiteralNode{Value: ListValue{NumberValue(1)}},
+		&LambdaNode{Params: []string{"x"}, Body: &LambdaNode{Params: []string{"s"}, Body: &customNode{name: "x"}}},
+	}}
+	result, err := Compile(node).Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("evaluation error: %v", err)
+	}
+	inner := result.(ListValue)[0].(FunctionValue)
+	actual, err := inner([]Value{StringValue("ignored")})
+	if err != nil {
+		t.Fatalf("evaluation error: %v", err)
+	}
+	if expected := (ListValue{StringValue("x"), NumberValue(1)}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestBytecodeString(t *testing.T) {
+	expected := strings.Join([]string{
+		"0000 var x",
+		"0001 logical and 0007",
+		"0002 function map 0006",
+		"0003 var items",
+		"0004 lambda 0",
+		"0005 call 2",
+		"0006 logical_end and",
+		"0007 jump_if_not_null 0009",
+		"0008 const 'none'",
+		"lambda 0 (1 params):",
+		"    0000 local 0 0 ref",
+		"    0001 field price",
+		"    0002 binary_const * 2",
+		"",
+	}, "\n")
+	program := Compile(mustParse(t, "x and map(items, i => i.price * 2) ?? 'none'"))
+	if actual := program.String(); actual != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, actual)
+	}
+}
+
+func TestBytecodeConcurrent(t *testing.T) {
+	program := Compile(mustParse(t, "map(items, i => i.price * x + count(i.id, x))"))
+	expected := ListValue{NumberValue(22), NumberValue(42)}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := vmContext()
+			for j := 0; j < 200; j++ {
+				result, err := program.Evaluate(ctx)
+				if err != nil {
+					t.Errorf("evaluation error: %v", err)
+					return
+				}
+				if !reflect.DeepEqual(result, expected) {
+					t.Errorf("expected %v, got %v", expected, result)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkBytecode(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		input string
+	}{
+		{"arithmetic", "(x + 1) * 2 - x / 4 > 3"},
+		{"access", "user.age >= 18 and user.name != '' and 'admin' in user.roles"},
+		{"calls", "upper(s) + string.upper(user.name) + count(x, x, x)"},
+		{"lambda", "map(items, i => i.price * x)"},
+		{"build", "{id: x, tags: [s, user.name], label: `${s}-${x}`}"},
+	}
+	ctx := vmContext()
+	for _, bm := range benchmarks {
+		ast, err := ParseExpression(bm.input)
+		if err != nil {
+			b.Fatalf("parse error: %v", err)
+		}
+		program := Compile(ast)
+		b.Run(bm.name+"/tree", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ast.Evaluate(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bm.name+"/bytecode", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := program.Evaluate(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
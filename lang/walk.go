@@ -0,0 +1,364 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+type (
+	// Visitor is called by Walk for each node. If Visit returns a non-nil
+	// Visitor, Walk visits the children of the node with it and then calls
+	// its Visit with nil.
+	Visitor interface {
+		Visit(node ExprNode) Visitor
+	}
+	inspector func(ExprNode) bool
+)
+
+// Walk traverses the tree rooted at node in depth-first order, starting
+// with v.Visit(node). Absent children, such as a missing else branch, are
+// not visited.
+func Walk(node ExprNode, v Visitor) {
+	if node == nil {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(child, v)
+	}
+	v.Visit(nil)
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// f for each node. The children of a node are visited only if f returns
+// true for it.
+func Inspect(node ExprNode, f func(ExprNode) bool) {
+	Walk(node, inspector(f))
+}
+
+func (f inspector) Visit(node ExprNode) Visitor {
+	if node != nil && f(node) {
+		return f
+	}
+	return nil
+}
+
+// Children returns the direct children of node in source order, leaving
+// out absent ones.
+func Children(node ExprNode) []ExprNode {
+	var out []ExprNode
+	add := func(nodes ...ExprNode) {
+		for _, node := range nodes {
+			if node != nil {
+				out = append(out, node)
+			}
+		}
+	}
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		{
+			add(n.Left, n.Right)
+		}
+	case *UnaryOpNode:
+		{
+			add(n.Operand)
+		}
+	case *FieldAccessNode:
+		{
+			add(n.Object)
+		}
+	case *IndexAccessNode:
+		{
+			add(n.Object, n.Index)
+		}
+	case *FunctionCallNode:
+		{
+			add(n.Namespace)
+			add(n.Args...)
+		}
+	case *ListNode:
+		{
+			add(n.Elements...)
+		}
+	case *TemplateNode:
+		{
+			add(n.Parts...)
+		}
+	case *MapNode:
+		{
+			for _, entry := range n.Entries {
+				add(entry.Key, entry.Value)
+			}
+		}
+	case *RangeNode:
+		{
+			add(n.Begin, n.End, n.Step)
+		}
+	case *ConditionalNode:
+		{
+			add(n.Condition, n.Then, n.Else)
+		}
+	case *CaseNode:
+		{
+			add(n.Subject)
+			for _, when := range n.Whens {
+				add(when.Condition, when.Result)
+			}
+			add(n.Else)
+		}
+	case *LambdaNode:
+		{
+			add(n.Body)
+		}
+	case *InSetNode:
+		{
+			add(n.Operand)
+		}
+	}
+	return out
+}
+
+// Rewrite returns a copy of the tree rooted at node in which every node
+// has been replaced by the result of f. Children are rewritten before
+// their parent, so f sees a node whose children were already rewritten.
+// Nodes whose subtree f leaves unchanged are shared with the original
+// tree, which itself is never modified.
+func Rewrite(node ExprNode, f func(ExprNode) ExprNode) ExprNode {
+	if node == nil {
+		return nil
+	}
+	return f(mapChildren(node, func(child ExprNode) ExprNode {
+		return Rewrite(child, f)
+	}))
+}
+
+// mapChildren returns node with each of its direct children replaced by the
+// result of f. The node is copied if any child changed and returned as is
+// otherwise.
+func mapChildren(node ExprNode, f func(ExprNode) ExprNode) ExprNode {
+	changed := false
+	rewrite := func(child ExprNode) ExprNode {
+		if child == nil {
+			return nil
+		}
+		out := f(child)
+		if out != child {
+			changed = true
+		}
+		return out
+	}
+	rewriteAll := func(children []ExprNode) []ExprNode {
+		out := make([]ExprNode, len(children))
+		for i, child := range children {
+			out[i] = rewrite(child)
+		}
+		return out
+	}
+
+	switch n := node.(type) {
+	case *BinaryOpNode:
+		{
+			out := *n
+			out.Left, out.Right = rewrite(n.Left), rewrite(n.Right)
+			if changed {
+				return &out
+			}
+		}
+	case *UnaryOpNode:
+		{
+			out := *n
+			out.Operand = rewrite(n.Operand)
+			if changed {
+				return &out
+			}
+		}
+	case *FieldAccessNode:
+		{
+			out := *n
+			out.Object = rewrite(n.Object)
+			if changed {
+				return &out
+			}
+		}
+	case *IndexAccessNode:
+		{
+			out := *n
+			out.Object, out.Index = rewrite(n.Object), rewrite(n.Index)
+			if changed {
+				return &out
+			}
+		}
+	case *FunctionCallNode:
+		{
+			out := *n
+			out.Namespace, out.Args = rewrite(n.Namespace), rewriteAll(n.Args)
+			if changed {
+				return &out
+			}
+		}
+	case *ListNode:
+		{
+			out := *n
+			out.Elements = rewriteAll(n.Elements)
+			if changed {
+				return &out
+			}
+		}
+	case *TemplateNode:
+		{
+			out := *n
+			out.Parts = rewriteAll(n.Parts)
+			if changed {
+				return &out
+			}
+		}
+	case *MapNode:
+		{
+			out := *n
+			out.Entries = make([]MapEntry, len(n.Entries))
+			for i, entry := range n.Entries {
+				out.Entries[i] = MapEntry{Key: rewrite(entry.Key), Value: rewrite(entry.Value), Spread: entry.Spread}
+			}
+			if changed {
+				return &out
+			}
+		}
+	case *RangeNode:
+		{
+			out := *n
+			out.Begin, out.End, out.Step = rewrite(n.Begin), rewrite(n.End), rewrite(n.Step)
+			if changed {
+				return &out
+			}
+		}
+	case *ConditionalNode:
+		{
+			out := *n
+			out.Condition, out.Then, out.Else = rewrite(n.Condition), rewrite(n.Then), rewrite(n.Else)
+			if changed {
+				return &out
+			}
+		}
+	case *CaseNode:
+		{
+			out := *n
+			out.Subject = rewrite(n.Subject)
+			out.Whens = make([]WhenClause, len(n.Whens))
+			for i, when := range n.Whens {
+				out.Whens[i] = WhenClause{Condition: rewrite(when.Condition), Result: rewrite(when.Result)}
+			}
+			out.Else = rewrite(n.Else)
+			if changed {
+				return &out
+			}
+		}
+	case *LambdaNode:
+		{
+			out := *n
+			out.Body = rewrite(n.Body)
+			if changed {
+				return &out
+			}
+		}
+	case *InSetNode:
+		{
+			out := *n
+			out.Operand = rewrite(n.Operand)
+			if changed {
+				return &out
+			}
+		}
+	}
+	return node
+}
+
+// Variables returns the names of the variables node reads, in order of
+// first use. Lambda parameters and the namespaces of function calls such
+// as `string` in `string.upper(x)` are not included.
+func Variables(node ExprNode) []string {
+	var out []string
+	seen := make(map[string]bool)
+	walkScoped(node, nil, func(node ExprNode, params map[string]bool) {
+		if n, ok := node.(*VariableNode); ok && !params[n.Name] && !seen[n.Name] {
+			seen[n.Name] = true
+			out = append(out, n.Name)
+		}
+	})
+	return out
+}
+
+// FunctionCalls returns the names of the functions node calls, in order of
+// first use. Calls through a namespace variable are named `namespace.name`,
+// as in `string.upper`. Calls of lambda parameters, or through them, are
+// not included.
+func FunctionCalls(node ExprNode) []string {
+	var out []string
+	seen := make(map[string]bool)
+	walkScoped(node, nil, func(node ExprNode, params map[string]bool) {
+		n, ok := node.(*FunctionCallNode)
+		if !ok || (n.Namespace == nil && params[n.Name]) {
+			return
+		}
+		if namespace, ok := n.Namespace.(*VariableNode); ok && params[namespace.Name] {
+			return
+		}
+		name := n.Name
+		if n.Namespace != nil {
+			name = Format(n.Namespace) + "." + n.Name
+		}
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	})
+	return out
+}
+
+// walkScoped calls f for every node with the lambda parameters in scope at
+// that node. Variables that only name the namespace of a call are skipped.
+func walkScoped(node ExprNode, params map[string]bool, f func(ExprNode, map[string]bool)) {
+	if node == nil {
+		return
+	}
+	f(node, params)
+	switch n := node.(type) {
+	case *FunctionCallNode:
+		{
+			if _, ok := n.Namespace.(*VariableNode); !ok {
+				walkScoped(n.Namespace, params, f)
+			}
+			for _, arg := range n.Args {
+				walkScoped(arg, params, f)
+			}
+		}
+	case *LambdaNode:
+		{
+			scope := make(map[string]bool, len(params)+len(n.Params))
+			for name := range params {
+				scope[name] = true
+			}
+			for _, name := range n.Params {
+				scope[name] = true
+			}
+			walkScoped(n.Body, scope, f)
+		}
+	default:
+		{
+			for _, child := range Children(node) {
+				walkScoped(child, params, f)
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lang
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type recorder struct {
+	visited []string
+	depth   int
+}
+
+func (r *recorder) Visit(node ExprNode) Visitor {
+	if node == nil {
+		r.depth--
+		return nil
+	}
+	r.visited = append(r.visited, fmt.Sprintf("%d:%s", r.depth, Format(node)))
+	r.depth++
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	ast, err := ParseExpression("f(a, [b]) ? {k: c} : d[1:]")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	r := &recorder{}
+	Walk(ast, r)
+	expected := []string{
+		"0:f(a, [b]) ? {k: c} : d[1:]",
+		"1:f(a, [b])",
+		"2:a",
+		"2:[b]",
+		"3:b",
+		"1:{k: c}",
+		"2:'k'",
+		"2:c",
+		"1:d[1:]",
+		"2:d",
+		"2:1:",
+		"3:1",
+	}
+	if !reflect.DeepEqual(r.visited, expected) {
+		t.Errorf("expected %v, got %v", expected, r.visited)
+	}
+	if r.depth != 0 {
+		t.Errorf("expected every visit to be closed, depth is %d", r.depth)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	ast, err := ParseExpression("a + f(b, x => x + c) + d")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var names []string
+	Inspect(ast, func(node ExprNode) bool {
+		if n, ok := node.(*VariableNode); ok {
+			names = append(names, n.Name)
+		}
+		// Skip the arguments of calls
+		_, call := node.(*FunctionCallNode)
+		return !call
+	})
+	if expected := []string{"a", "d"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestChildren(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"a", nil},
+		{"-a", []string{"a"}},
+		{"ns.f(a, b)", []string{"ns", "a", "b"}},
+		{"{a: 1, ...b}", []string{"'a'", "1", "b"}},
+		{"case x when 1 then 2 else 3 end", []string{"x", "1", "2", "3"}},
+		{"case when a then 2 end", []string{"a", "2"}},
+		{"`a${b}`", []string{"'a'", "b"}},
+		{"(a, b) => a", []string{"a"}},
+		{"a?.[?]", []string{"a", "?"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ast, err := ParseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			var children []string
+			for _, child := range Children(ast) {
+				children = append(children, Format(child))
+			}
+			if !reflect.DeepEqual(children, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, children)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	input := "str.up(name) + list.map(items, x => str.up(x.name)) + other"
+	ast, err := ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	// Rename the deprecated str.up to string.upper
+	rewritten := Rewrite(ast, func(node ExprNode) ExprNode {
+		call, ok := node.(*FunctionCallNode)
+		if !ok {
+			return node
+		}
+		if namespace, ok := call.Namespace.(*VariableNode); ok && namespace.Name == "str" && call.Name == "up" {
+			out := *call
+			out.Namespace = &VariableNode{Name: "string"}
+			out.Name = "upper"
+			return &out
+		}
+		return node
+	})
+
+	expected := "string.upper(name) + list.map(items, x => string.upper(x.name)) + other"
+	if actual := Format(rewritten); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if actual := Format(ast); actual != input {
+		t.Errorf("expected original to be unchanged, got %q", actual)
+	}
+
+	// Unchanged subtrees are shared
+	if rewritten.(*BinaryOpNode).Right != ast.(*BinaryOpNode).Right {
+		t.Error("expected unchanged operand to be shared")
+	}
+	if identity := Rewrite(ast, func(node ExprNode) ExprNode { return node }); identity != ast {
+		t.Error("expected identity rewrite to return the original tree")
+	}
+}
+
+func TestRewriteEveryNode(t *testing.T) {
+	input := "-a.b[c] + f(d, ns.g()) ?? [e, `t${f2}`, {k: g, [h]: i, ...j}][l:m:n] + (c2 ? t : case s when w then r else o end) + (p => p + q)"
+	ast, err := ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	// Prefix every variable, which forces every ancestor to be copied
+	rewritten := Rewrite(ast, func(node ExprNode) ExprNode {
+		if n, ok := node.(*VariableNode); ok {
+			return &VariableNode{Name: "v_" + n.Name}
+		}
+		return node
+	})
+	expected := "-v_a.b[v_c] + f(v_d, v_ns.g()) ?? [v_e, `t${v_f2}`, {k: v_g, [v_h]: v_i, ...v_j}][v_l:v_m:v_n] + (v_c2 ? v_t : case v_s when v_w then v_r else v_o end) + (p => v_p + v_q)"
+	if actual := Format(rewritten); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+	if actual := Format(ast); actual != Format(mustParse(t, input)) {
+		t.Errorf("expected original to be unchanged, got %q", actual)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"1 + 2", nil},
+		{"user.age > threshold and user.name != ''", []string{"user", "threshold"}},
+		{"string.upper(name)", []string{"name"}},
+		{"list.filter(items, x => x.price > limit)", []string{"items", "limit"}},
+		{"f(x => y => x + y + z) + x", []string{"z", "x"}},
+		{"`${a}` + {[b]: c}['k'] + d[e:]", []string{"a", "b", "c", "d", "e"}},
+		{"case s when w then r else o end", []string{"s", "w", "r", "o"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ast := mustParse(t, tt.input)
+			if actual := Variables(ast); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFunctionCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"a + b", nil},
+		{"add(1, 2) + add(3, 4)", []string{"add"}},
+		{"string.upper(name) + string.lower(string.trim(name))", []string{"string.upper", "string.lower", "string.trim"}},
+		{"list.map(items, f => f(1)) + f(2)", []string{"list.map", "f"}},
+		{"list.map(items, c => c.get('k'))", []string{"list.map"}},
+		{"user.settings?.get('theme')", []string{"user.settings.get"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ast := mustParse(t, tt.input)
+			if actual := FunctionCalls(ast); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func mustParse(t *testing.T, input string) ExprNode {
+	t.Helper()
+	ast, err := ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	return ast
+}
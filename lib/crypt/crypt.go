@@ -704,11 +704,182 @@ var encodingFunctions = []func() (string, lang.Function){
 	hashVerify,
 }
 
+var encodingSignatures = map[string]lib.Descriptor{
+	"base64Encode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Encodes a string to Base64 format.",
+	},
+	"base64Decode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Decodes a Base64 string to its original form.",
+	},
+	"base64UrlEncode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Encodes a string to URL-safe Base64 format.",
+	},
+	"base64UrlDecode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Decodes a URL-safe Base64 string to its original form.",
+	},
+	"hexEncode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Encodes a string to hexadecimal format.",
+	},
+	"hexDecode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Decodes a hexadecimal string to its original form.",
+	},
+	"base32Encode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Encodes a string to Base32 format.",
+	},
+	"base32Decode": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Decodes a Base32 string to its original form.",
+	},
+	"hashMd5": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates an MD5 hash of the input string.",
+	},
+	"hashSha1": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates a SHA-1 hash of the input string.",
+	},
+	"hashSha224": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates a SHA-224 hash of the input string.",
+	},
+	"hashSha256": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates a SHA-256 hash of the input string.",
+	},
+	"hashSha384": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates a SHA-384 hash of the input string.",
+	},
+	"hashSha512": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates a SHA-512 hash of the input string.",
+	},
+	"hashCrc32": {
+		Params: []lib.Param{lib.Arg("data", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Generates a CRC32 checksum of the input string.",
+	},
+	"hmacMd5": {
+		Params: []lib.Param{lib.Arg("key", lang.StringType), lib.Arg("message", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates an HMAC-MD5 signature.",
+	},
+	"hmacSha1": {
+		Params: []lib.Param{lib.Arg("key", lang.StringType), lib.Arg("message", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates an HMAC-SHA1 signature.",
+	},
+	"hmacSha256": {
+		Params: []lib.Param{lib.Arg("key", lang.StringType), lib.Arg("message", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates an HMAC-SHA256 signature.",
+	},
+	"hmacSha512": {
+		Params: []lib.Param{lib.Arg("key", lang.StringType), lib.Arg("message", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates an HMAC-SHA512 signature.",
+	},
+	"toBinary": {
+		Params: []lib.Param{lib.Arg("input", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a number or string to binary representation.",
+	},
+	"fromBinary": {
+		Params: []lib.Param{lib.Arg("binary", lang.StringType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Converts binary string back to number or string.",
+	},
+	"toOctal": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a number to octal representation.",
+	},
+	"fromOctal": {
+		Params: []lib.Param{lib.Arg("octal", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts octal string back to number.",
+	},
+	"toAscii": {
+		Params: []lib.Param{lib.Arg("text", lang.StringType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Converts a string to ASCII code array.",
+	},
+	"fromAscii": {
+		Params: []lib.Param{lib.Arg("codes", lang.ListType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts ASCII code array back to string.",
+	},
+	"htmlEscape": {
+		Params: []lib.Param{lib.Arg("text", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Escapes HTML special characters in a string.",
+	},
+	"htmlUnescape": {
+		Params: []lib.Param{lib.Arg("text", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Unescapes HTML entities in a string.",
+	},
+	"hashVerify": {
+		Params: []lib.Param{lib.Arg("input", lang.StringType), lib.Arg("expectedHash", lang.StringType), lib.Arg("algorithm", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Verifies if an input matches the expected hash using specified algorithm.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(encodingFunctions, encodingSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range encodingFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package lib holds the helpers shared by the built-in function libraries.
+// Every library function is safe for concurrent use: none of them keep
+// state between calls or modify their arguments.
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+type (
+	// Param describes a single function parameter. Optional parameters may
+	// only follow required ones.
+	Param struct {
+		Name     string
+		Type     lang.Type
+		Optional bool
+	}
+	// Descriptor describes a library function. When Variadic is set the last
+	// parameter may repeat. Pure functions always return the same result for
+	// the same arguments and have no side effects. Functions that block, or
+	// that check the lang.Limits of the evaluation, set ContextFunction too,
+	// which is called instead of Function when the evaluation has a
+	// context.Context.
+	Descriptor struct {
+		Name            string
+		Params          []Param
+		Variadic        bool
+		Result          lang.Type
+		Doc             string
+		Pure            bool
+		Function        lang.Function
+		ContextFunction lang.ContextFunction
+	}
+)
+
+func Arg(name string, typ lang.Type) Param {
+	return Param{Name: name, Type: typ}
+}
+
+func OptionalArg(name string, typ lang.Type) Param {
+	return Param{Name: name, Type: typ, Optional: true}
+}
+
+func (d *Descriptor) MinArgs() int {
+	count := 0
+	for _, param := range d.Params {
+		if !param.Optional {
+			count++
+		}
+	}
+	return count
+}
+
+// MaxArgs returns the maximum number of arguments, or -1 for variadic
+// functions.
+func (d *Descriptor) MaxArgs() int {
+	if d.Variadic {
+		return -1
+	}
+	return len(d.Params)
+}
+
+func (d *Descriptor) Signature() *lang.Signature {
+	params := make([]lang.Type, len(d.Params))
+	for i, param := range d.Params {
+		params[i] = param.Type
+	}
+	return &lang.Signature{Params: params, Variadic: d.Variadic, Result: d.Result}
+}
+
+// Validate checks the number and types of args against the descriptor.
+// Arguments are accepted when they convert to the parameter type the way
+// ToString, ToNumber and ToBool do, and null is always accepted.
+func (d *Descriptor) Validate(args []lang.Value) error {
+	min, max := d.MinArgs(), d.MaxArgs()
+	switch {
+	case max < 0 && len(args) < min:
+		{
+			return ArgumentErrorMin(d.Name, min)
+		}
+	case max >= 0 && min == max && len(args) != min:
+		{
+			return ArgumentError(d.Name, min)
+		}
+	case max >= 0 && (len(args) < min || len(args) > max):
+		{
+			return RangeError(d.Name, min, max)
+		}
+	}
+
+	for i, arg := range args {
+		param := d.param(i)
+		if !Accepts(param.Type, arg) {
+			return ArgumenErrorType(d.Name, i+1, param.Type.String(), arg)
+		}
+	}
+	return nil
+}
+
+// param returns the parameter of the i-th argument, repeating the last one
+// for variadic functions.
+func (d *Descriptor) param(i int) Param {
+	if i < len(d.Params) {
+		return d.Params[i]
+	}
+	return d.Params[len(d.Params)-1]
+}
+
+// maps returns args with the lang.MapObjects passed to map parameters
+// converted to maps, so that functions only have to handle MapValues. args
+// is only copied if an argument is converted.
+func (d *Descriptor) maps(args []lang.Value) []lang.Value {
+	var out []lang.Value
+	for i, arg := range args {
+		object, ok := arg.(lang.MapObject)
+		if !ok || d.param(i).Type != lang.MapType {
+			continue
+		}
+		if out == nil {
+			out = append([]lang.Value(nil), args...)
+		}
+		out[i] = object.Map()
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// Validated returns the function wrapped with Validate. lang.MapObjects
+// passed to map parameters reach the function as maps.
+func (d *Descriptor) Validated() lang.Function {
+	return func(args []lang.Value) (lang.Value, error) {
+		if err := d.Validate(args); err != nil {
+			return nil, err
+		}
+		return d.Function(d.maps(args))
+	}
+}
+
+// ValidatedContext returns the context function wrapped with Validate, or
+// nil if the function does not take a context.
+func (d *Descriptor) ValidatedContext() lang.ContextFunction {
+	if d.ContextFunction == nil {
+		return nil
+	}
+	return func(ctx context.Context, args []lang.Value) (lang.Value, error) {
+		if err := d.Validate(args); err != nil {
+			return nil, err
+		}
+		return d.ContextFunction(ctx, d.maps(args))
+	}
+}
+
+// Accepts reports whether value can be passed where typ is expected.
+func Accepts(typ lang.Type, value lang.Value) bool {
+	if value == nil {
+		return true
+	}
+	switch typ {
+	case lang.StringType:
+		{
+			_, err := ToString(value)
+			return err == nil
+		}
+	case lang.NumberType:
+		{
+			_, err := ToNumber(value)
+			return err == nil
+		}
+	case lang.BoolType:
+		{
+			_, err := ToBool(value)
+			return err == nil
+		}
+	case lang.AnyType:
+		{
+			return true
+		}
+	default:
+		{
+			return lang.TypeOf(value) == typ
+		}
+	}
+}
+
+// Describe builds descriptors for a package's functions from their
+// signatures, which are keyed by function name. The result is sorted by name.
+func Describe(functions []func() (string, lang.Function), signatures map[string]Descriptor) []*Descriptor {
+	out := make([]*Descriptor, 0, len(functions))
+	for _, value := range functions {
+		name, fn := value()
+		sig, ok := signatures[name]
+		if !ok {
+			panic(fmt.Sprintf("%s: missing signature", name))
+		}
+		sig.Name = name
+		sig.Function = fn
+		out = append(out, &sig)
+	}
+	if len(out) != len(signatures) {
+		for name := range signatures {
+			if !described(out, name) {
+				panic(fmt.Sprintf("%s: signature without function", name))
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func described(descriptors []*Descriptor, name string) bool {
+	for _, descriptor := range descriptors {
+		if descriptor.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDescriptors reports the descriptors that have no doc or that declare
+// a required parameter after an optional one.
+func CheckDescriptors(descriptors []*Descriptor) error {
+	var errs []error
+	for _, descriptor := range descriptors {
+		if descriptor.Doc == "" {
+			errs = append(errs, fmt.Errorf("%s: missing doc", descriptor.Name))
+		}
+		optional := false
+		for _, param := range descriptor.Params {
+			if !param.Optional && optional {
+				errs = append(errs, fmt.Errorf("%s: required parameter %s follows an optional one", descriptor.Name, param.Name))
+			}
+			optional = optional || param.Optional
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Export returns the validated functions of descriptors keyed by name.
+func Export(descriptors []*Descriptor) map[string]lang.Function {
+	out := make(map[string]lang.Function)
+	for _, descriptor := range descriptors {
+		out[descriptor.Name] = descriptor.Validated()
+	}
+	return out
+}
+
+// ExportContext returns the validated context functions of the descriptors
+// that have one, keyed by name.
+func ExportContext(descriptors []*Descriptor) map[string]lang.ContextFunction {
+	out := make(map[string]lang.ContextFunction)
+	for _, descriptor := range descriptors {
+		if fn := descriptor.ValidatedContext(); fn != nil {
+			out[descriptor.Name] = fn
+		}
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+func echo(args []lang.Value) (lang.Value, error) {
+	return lang.NumberValue(len(args)), nil
+}
+
+func TestDescriptorArity(t *testing.T) {
+	tests := []struct {
+		name       string
+		descriptor Descriptor
+		min        int
+		max        int
+	}{
+		{"no params", Descriptor{}, 0, 0},
+		{"fixed", Descriptor{Params: []Param{Arg("a", lang.AnyType), Arg("b", lang.AnyType)}}, 2, 2},
+		{"optional", Descriptor{Params: []Param{Arg("a", lang.AnyType), OptionalArg("b", lang.AnyType)}}, 1, 2},
+		{"variadic", Descriptor{Params: []Param{Arg("a", lang.AnyType)}, Variadic: true}, 1, -1},
+		{"optional variadic", Descriptor{Params: []Param{OptionalArg("a", lang.AnyType)}, Variadic: true}, 0, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if min := tt.descriptor.MinArgs(); min != tt.min {
+				t.Errorf("Expected min %d, got %d", tt.min, min)
+			}
+			if max := tt.descriptor.MaxArgs(); max != tt.max {
+				t.Errorf("Expected max %d, got %d", tt.max, max)
+			}
+		})
+	}
+}
+
+func TestDescriptorValidate(t *testing.T) {
+	fixed := &Descriptor{Name: "fixed", Params: []Param{Arg("value", lang.NumberType)}}
+	ranged := &Descriptor{Name: "ranged", Params: []Param{Arg("list", lang.ListType), OptionalArg("count", lang.NumberType)}}
+	variadic := &Descriptor{Name: "variadic", Params: []Param{Arg("values", lang.StringType)}, Variadic: true}
+
+	tests := []struct {
+		name       string
+		descriptor *Descriptor
+		args       []lang.Value
+		wantErr    bool
+	}{
+		{"exact count", fixed, []lang.Value{lang.NumberValue(1)}, false},
+		{"too few", fixed, []lang.Value{}, true},
+		{"too many", fixed, []lang.Value{lang.NumberValue(1), lang.NumberValue(2)}, true},
+		{"convertible string", fixed, []lang.Value{lang.StringValue("42")}, false},
+		{"non numeric string", fixed, []lang.Value{lang.StringValue("abc")}, true},
+		{"null", fixed, []lang.Value{nil}, false},
+		{"optional omitted", ranged, []lang.Value{lang.ListValue{}}, false},
+		{"optional given", ranged, []lang.Value{lang.ListValue{}, lang.NumberValue(1)}, false},
+		{"wrong list", ranged, []lang.Value{lang.MapValue{}}, true},
+		{"above range", ranged, []lang.Value{lang.ListValue{}, lang.NumberValue(1), lang.NumberValue(2)}, true},
+		{"variadic", variadic, []lang.Value{lang.StringValue("a"), lang.StringValue("b"), lang.NumberValue(1)}, false},
+		{"variadic too few", variadic, []lang.Value{}, true},
+		{"variadic wrong type", variadic, []lang.Value{lang.StringValue("a"), lang.ListValue{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.descriptor.Validate(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDescriptorValidated(t *testing.T) {
+	descriptor := &Descriptor{Name: "echo", Params: []Param{Arg("value", lang.AnyType)}, Function: echo}
+	fn := descriptor.Validated()
+
+	result, err := fn([]lang.Value{lang.StringValue("a")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != lang.NumberValue(1) {
+		t.Errorf("Expected 1, got %v", result)
+	}
+
+	if _, err := fn(nil); err == nil {
+		t.Error("Expected error for missing argument")
+	}
+}
+
+func TestDescriptorValidatedContext(t *testing.T) {
+	type key struct{}
+	descriptor := &Descriptor{Name: "tag", Params: []Param{Arg("value", lang.AnyType)}, Function: echo}
+	if descriptor.ValidatedContext() != nil {
+		t.Error("Expected no context function")
+	}
+
+	descriptor.ContextFunction = func(ctx context.Context, args []lang.Value) (lang.Value, error) {
+		return ctx.Value(key{}).(lang.Value), nil
+	}
+	fn := descriptor.ValidatedContext()
+	result, err := fn(context.WithValue(context.Background(), key{}, lang.StringValue("tagged")), []lang.Value{nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != lang.StringValue("tagged") {
+		t.Errorf("Expected 'tagged', got %v", result)
+	}
+	if _, err := fn(context.Background(), nil); err == nil {
+		t.Error("Expected error for missing argument")
+	}
+
+	exported := ExportContext([]*Descriptor{descriptor, {Name: "plain", Function: echo}})
+	if len(exported) != 1 || exported["tag"] == nil {
+		t.Errorf("Expected only the context function to be exported, got %v", exported)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	functions := []func() (string, lang.Function){
+		func() (string, lang.Function) { return "b", echo },
+		func() (string, lang.Function) { return "a", echo },
+	}
+	signatures := map[string]Descriptor{
+		"a": {Result: lang.NumberType, Pure: true, Doc: "a"},
+		"b": {Result: lang.NumberType, Doc: "b"},
+	}
+
+	descriptors := Describe(functions, signatures)
+	if len(descriptors) != 2 || descriptors[0].Name != "a" || descriptors[1].Name != "b" {
+		t.Fatalf("Expected descriptors sorted by name, got %v", descriptors)
+	}
+	if descriptors[0].Function == nil || !descriptors[0].Pure || descriptors[1].Pure {
+		t.Error("Expected descriptors to carry their function and signature")
+	}
+
+	exported := Export(descriptors)
+	if len(exported) != 2 {
+		t.Errorf("Expected 2 exported functions, got %d", len(exported))
+	}
+
+	for _, signatures := range []map[string]Descriptor{
+		{"a": {}},
+		{"a": {}, "b": {}, "c": {}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for signatures %v", signatures)
+				}
+			}()
+			Describe(functions, signatures)
+		}()
+	}
+}
+
+func TestCheckDescriptors(t *testing.T) {
+	valid := &Descriptor{Name: "a", Doc: "a", Params: []Param{Arg("x", lang.AnyType), OptionalArg("y", lang.AnyType)}}
+	if err := CheckDescriptors([]*Descriptor{valid}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	undocumented := &Descriptor{Name: "b"}
+	misordered := &Descriptor{Name: "c", Doc: "c", Params: []Param{OptionalArg("x", lang.AnyType), Arg("y", lang.AnyType)}}
+	err := CheckDescriptors([]*Descriptor{valid, undocumented, misordered})
+	if err == nil || err.Error() != "b: missing doc\nc: required parameter y follows an optional one" {
+		t.Errorf("Expected errors for b and c, got %v", err)
+	}
+}
@@ -41,6 +41,10 @@ func MapError(name string, value lang.Value) error {
 	return fmt.Errorf("%s: expected map, got %T", name, value)
 }
 
+func FunctionError(name string, value lang.Value) error {
+	return fmt.Errorf("%s: expected function, got %T", name, value)
+}
+
 func RangeError(name string, min, max int) error {
 	return fmt.Errorf("%s: expected %d to %d arguments", name, min, max)
 }
@@ -642,11 +642,188 @@ var httpFunctions = []func() (string, lang.Function){
 	urlFn,
 }
 
+var httpSignatures = map[string]lib.Descriptor{
+	"header": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType), lib.Arg("headerName", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves a specific HTTP header value from the request context.",
+	},
+	"headers": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Retrieves all HTTP headers from the request context.",
+	},
+	"method": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the HTTP method from the request context.",
+	},
+	"path": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the request path from the request context.",
+	},
+	"query": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Retrieves all query parameters from the request context.",
+	},
+	"queryParam": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType), lib.Arg("paramName", lang.StringType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Retrieves a specific query parameter value.",
+	},
+	"body": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   false,
+		Doc:    "Retrieves the request body from the request context.",
+	},
+	"status": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Retrieves the HTTP status code from the request context.",
+	},
+	"ip": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the client IP address, checking various headers and context fields.",
+	},
+	"userAgent": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the User-Agent header from the request.",
+	},
+	"contentType": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the Content-Type header, excluding parameters.",
+	},
+	"contentLength": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Retrieves the Content-Length header as a number.",
+	},
+	"host": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the Host header from the request.",
+	},
+	"scheme": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the URL scheme (protocol) from the request.",
+	},
+	"port": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the port number from the request.",
+	},
+	"cookies": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Retrieves all cookies from the request.",
+	},
+	"cookie": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType), lib.Arg("cookieName", lang.StringType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Retrieves a specific cookie value.",
+	},
+	"referer": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the Referer header from the request.",
+	},
+	"authorization": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the Authorization header from the request.",
+	},
+	"accept": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Retrieves the Accept header from the request.",
+	},
+	"trailer": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType), lib.Arg("headerName", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Gets a specific trailer value by name.",
+	},
+	"trailers": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Gets all trailers as a map of name to list of values.",
+	},
+	"routeValues": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Extracts route parameters by matching the request path against the route pattern.",
+	},
+	"pattern": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Gets the route pattern that matched the request.",
+	},
+	"proto": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Gets the protocol version string, e.g. HTTP/1.1.",
+	},
+	"protoMajor": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the major protocol version number.",
+	},
+	"protoMinor": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the minor protocol version number.",
+	},
+	"transferEncoding": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Gets the transfer encodings of the request.",
+	},
+	"url": {
+		Params: []lib.Param{lib.Arg("request", lang.AnyType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Gets the parsed request URL as a map.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(httpFunctions, httpSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range httpFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -16,7 +16,9 @@
 package ip
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"net"
 	"strconv"
 
@@ -330,46 +332,59 @@ func cidrHostCount() (string, lang.Function) {
 func cidrSubnets() (string, lang.Function) {
 	name := "cidrSubnets"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 2 {
-			return nil, lib.ArgumentError(name, 2)
-		}
-		cidrStr, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, fmt.Errorf("%s: cidr %w", name, err)
-		}
-		newPrefixLen, err := lib.ToNumber(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: prefix length %w", name, err)
-		}
-
-		_, network, err := net.ParseCIDR(string(cidrStr))
-		if err != nil {
-			return nil, fmt.Errorf("%s: invalid CIDR '%s': %w", name, string(cidrStr), err)
-		}
+		return cidrSubnetsContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		prefixLen := int(newPrefixLen)
-		ones, bits := network.Mask.Size()
-		if prefixLen <= ones || prefixLen > bits {
-			return nil, fmt.Errorf("%s: new prefix length %d must be between %d and %d", name, prefixLen, ones+1, bits)
-		}
-		if bits != 32 {
-			return nil, fmt.Errorf("%s: only IPv4 subnets are supported", name)
-		}
+// cidrSubnetsContext splits a network into subnets, failing before it
+// allocates more subnets than the limits of ctx allow.
+func cidrSubnetsContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "cidrSubnets"
+	if len(args) != 2 {
+		return nil, lib.ArgumentError(name, 2)
+	}
+	cidrStr, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: cidr %w", name, err)
+	}
+	newPrefixLen, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: prefix length %w", name, err)
+	}
 
-		subnets := make(lang.ListValue, 0)
-		subnetCount := 1 << (prefixLen - ones)
-		hostSize := 1 << (32 - prefixLen)
+	_, network, err := net.ParseCIDR(string(cidrStr))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid CIDR '%s': %w", name, string(cidrStr), err)
+	}
 
-		baseIP := ipv4ToInt(network.IP.To4())
-		for i := 0; i < subnetCount; i++ {
-			subnetIP := intToIPv4(baseIP + uint32(i*hostSize))
-			subnet := subnetIP.String() + "/" + strconv.Itoa(prefixLen)
-			subnets = append(subnets, lang.StringValue(subnet))
-		}
+	prefixLen := int(newPrefixLen)
+	ones, bits := network.Mask.Size()
+	if prefixLen <= ones || prefixLen > bits {
+		return nil, fmt.Errorf("%s: new prefix length %d must be between %d and %d", name, prefixLen, ones+1, bits)
+	}
+	if bits != 32 {
+		return nil, fmt.Errorf("%s: only IPv4 subnets are supported", name)
+	}
 
-		return subnets, nil
+	if err := lib.CheckCollection(ctx, name, math.Exp2(float64(prefixLen-ones))); err != nil {
+		return nil, err
 	}
-	return name, fn
+	subnets := make(lang.ListValue, 0)
+	subnetCount := 1 << (prefixLen - ones)
+	hostSize := 1 << (32 - prefixLen)
+
+	baseIP := ipv4ToInt(network.IP.To4())
+	for i := 0; i < subnetCount; i++ {
+		if err := lib.Cancelled(ctx, name, i); err != nil {
+			return nil, err
+		}
+		subnetIP := intToIPv4(baseIP + uint32(i*hostSize))
+		subnet := subnetIP.String() + "/" + strconv.Itoa(prefixLen)
+		subnets = append(subnets, lang.StringValue(subnet))
+	}
+
+	return subnets, nil
 }
 
 func expandIPv6() (string, lang.Function) {
@@ -567,11 +582,135 @@ var ipFunctions = []func() (string, lang.Function){
 	isRFC1918,
 }
 
+var ipSignatures = map[string]lib.Descriptor{
+	"isValidIP": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is a valid IP address (IPv4 or IPv6).",
+	},
+	"isIPv4": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is a valid IPv4 address.",
+	},
+	"isIPv6": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is a valid IPv6 address.",
+	},
+	"isPrivateIP": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IP address is in a private address range.",
+	},
+	"isLoopbackIP": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IP address is a loopback address.",
+	},
+	"isMulticastIP": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IP address is a multicast address.",
+	},
+	"isLinkLocalIP": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IP address is a link-local address.",
+	},
+	"cidrMatch": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType), lib.Arg("cidr", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IP address falls within a CIDR range.",
+	},
+	"cidrContains": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType), lib.Arg("cidr", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Alias for `cidrMatch()` - checks if IP is contained in CIDR range.",
+	},
+	"IPInRange": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType), lib.Arg("startIP", lang.StringType), lib.Arg("endIP", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IP address falls within a specified range.",
+	},
+	"cidrNetwork": {
+		Params: []lib.Param{lib.Arg("cidr", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts the network address from a CIDR notation.",
+	},
+	"cidrBroadcast": {
+		Params: []lib.Param{lib.Arg("cidr", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Calculates the broadcast address for an IPv4 CIDR range.",
+	},
+	"cidrHostCount": {
+		Params: []lib.Param{lib.Arg("cidr", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Calculates the number of host addresses in a CIDR range.",
+	},
+	"cidrSubnets": {
+		Params:          []lib.Param{lib.Arg("cidr", lang.StringType), lib.Arg("newPrefixLength", lang.NumberType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Splits a CIDR range into smaller subnets.",
+		ContextFunction: cidrSubnetsContext,
+	},
+	"expandIPv6": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Expands an IPv6 address to its full form.",
+	},
+	"compressIPv6": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Compresses an IPv6 address to its shortest form.",
+	},
+	"IPToInt": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts an IPv4 address to its integer representation.",
+	},
+	"intToIP": {
+		Params: []lib.Param{lib.Arg("integer", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts an integer to its IPv4 address representation.",
+	},
+	"reverseIP": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Generates the reverse DNS notation for an IP address.",
+	},
+	"isRfc1918": {
+		Params: []lib.Param{lib.Arg("ip", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an IPv4 address is in RFC 1918 private address space.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(ipFunctions, ipSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range ipFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
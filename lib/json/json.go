@@ -16,6 +16,7 @@
 package json
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -48,32 +49,42 @@ func parse() (string, lang.Function) {
 func sstring() (string, lang.Function) {
 	name := "string"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 1 || len(args) > 2 {
-			return nil, fmt.Errorf("%s: expected 1 or 2 arguments (data, pretty?)", name)
-		}
-		indent := ""
-		if len(args) == 2 {
-			pretty, err := lib.ToBool(args[1])
-			if err != nil {
-				return nil, fmt.Errorf("%s: pretty flag %w", name, err)
-			}
-			if pretty {
-				indent = "  "
-			}
-		}
-		var jsonBytes []byte
-		var err error
-		if indent != "" {
-			jsonBytes, err = json.MarshalIndent(convertValueToJSON(args[0]), "", indent)
-		} else {
-			jsonBytes, err = json.Marshal(convertValueToJSON(args[0]))
-		}
+		return stringContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// stringContext marshals a value, failing before it marshals a value
+// larger than the limits of ctx allow.
+func stringContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "string"
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("%s: expected 1 or 2 arguments (data, pretty?)", name)
+	}
+	indent := ""
+	if len(args) == 2 {
+		pretty, err := lib.ToBool(args[1])
 		if err != nil {
-			return nil, fmt.Errorf("%s: marshalling failed: %w", name, err)
+			return nil, fmt.Errorf("%s: pretty flag %w", name, err)
+		}
+		if pretty {
+			indent = "  "
 		}
-		return lang.StringValue(string(jsonBytes)), nil
 	}
-	return name, fn
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, args[0])); err != nil {
+		return nil, err
+	}
+	var jsonBytes []byte
+	var err error
+	if indent != "" {
+		jsonBytes, err = json.MarshalIndent(convertValueToJSON(args[0]), "", indent)
+	} else {
+		jsonBytes, err = json.Marshal(convertValueToJSON(args[0]))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: marshalling failed: %w", name, err)
+	}
+	return lang.StringValue(string(jsonBytes)), nil
 }
 
 func valid() (string, lang.Function) {
@@ -541,6 +552,11 @@ func convertValueToJSON(v lang.Value) interface{} {
 			result[k] = convertValueToJSON(item)
 		}
 		return result
+	case json.Marshaler:
+		// Values that marshal themselves, such as a Go map that contains itself
+		return val
+	case lang.MapObject:
+		return convertValueToJSON(val.Map())
 	default:
 		return nil
 	}
@@ -561,11 +577,88 @@ var jsonFunctions = []func() (string, lang.Function){
 	ttype,
 }
 
+var jsonSignatures = map[string]lib.Descriptor{
+	"parse": {
+		Params: []lib.Param{lib.Arg("jsonString", lang.StringType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Parses a JSON string into a structured value.",
+	},
+	"string": {
+		Params:          []lib.Param{lib.Arg("data", lang.AnyType), lib.OptionalArg("pretty", lang.BoolType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Converts data to a JSON string representation.",
+		ContextFunction: stringContext,
+	},
+	"valid": {
+		Params: []lib.Param{lib.Arg("jsonString", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is valid JSON.",
+	},
+	"get": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType), lib.Arg("path", lang.StringType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Retrieves a value from JSON data using dot notation path.",
+	},
+	"set": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType), lib.Arg("path", lang.StringType), lib.Arg("value", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Sets a value in JSON data using dot notation path.",
+	},
+	"delete": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType), lib.Arg("path", lang.StringType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Removes a value from JSON data using dot notation path.",
+	},
+	"has": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType), lib.Arg("path", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a path exists in JSON data.",
+	},
+	"keys": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Retrieves all keys from a JSON object.",
+	},
+	"values": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Retrieves all values from a JSON object or array.",
+	},
+	"length": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the length/size of JSON data.",
+	},
+	"merge": {
+		Params:   []lib.Param{lib.Arg("object1", lang.AnyType), lib.Arg("object2", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.MapType,
+		Pure:     true,
+		Doc:      "Merges multiple JSON objects into one (shallow merge).",
+	},
+	"type": {
+		Params: []lib.Param{lib.Arg("data", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Determines the JSON type of data.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(jsonFunctions, jsonSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range jsonFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package lib
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+// CheckString fails with an error wrapping lang.ErrLimitExceeded if the
+// function name is about to build a string of n bytes and the lang.Limits
+// of ctx do not allow it. Sizes are floats so that they can be computed
+// from arguments without overflowing.
+func CheckString(ctx context.Context, name string, n float64) error {
+	if err := lang.LimitsFrom(ctx).CheckString(size(n)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// CheckCollection is CheckString for a list or map of n elements.
+func CheckCollection(ctx context.Context, name string, n float64) error {
+	if err := lang.LimitsFrom(ctx).CheckCollection(size(n)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Elements counts the elements of a list holding values, nested ones
+// included, as far as the lang.Limits of ctx need them counted. See
+// lang.Elements.
+func Elements(ctx context.Context, values ...lang.Value) float64 {
+	return float64(lang.Elements(lang.LimitsFrom(ctx).MaxCollectionSize, values...))
+}
+
+// Cancelled fails with the cause of ctx being done, for functions that loop
+// long enough to outlast the evaluation calling them. It only looks at ctx
+// on every 1024th iteration i, so loops can call it on every one.
+func Cancelled(ctx context.Context, name string, i int) error {
+	if i%1024 != 0 || ctx.Err() == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", name, context.Cause(ctx))
+}
+
+func size(n float64) int {
+	switch {
+	case math.IsNaN(n) || n <= 0:
+		{
+			return 0
+		}
+	case n >= math.MaxInt:
+		{
+			return math.MaxInt
+		}
+	default:
+		{
+			return int(n)
+		}
+	}
+}
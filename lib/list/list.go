@@ -16,9 +16,11 @@
 package list
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"math"
+	"math/rand/v2"
 	"sort"
 	"strconv"
 
@@ -86,107 +88,147 @@ func get() (string, lang.Function) {
 func set() (string, lang.Function) {
 	name := "set"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[0])
-		}
-		indexNum, err := lib.ToNumber(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: index %w", name, err)
-		}
-		index := int(indexNum)
-		value := args[2]
-		if index < 0 {
-			index = len(list) + index
-		}
-		if index < 0 || index >= len(list) {
-			return nil, fmt.Errorf("%s: index %d out of bounds (list length: %d)", name, index, len(list))
-		}
-		result := make(lang.ListValue, len(list))
-		copy(result, list)
-		result[index] = value
-		return result, nil
+		return setContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// setContext sets an element of a list, failing before it allocates a list
+// larger than the limits of ctx allow.
+func setContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "set"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	indexNum, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: index %w", name, err)
+	}
+	index := int(indexNum)
+	value := args[2]
+	if index < 0 {
+		index = len(list) + index
+	}
+	if index < 0 || index >= len(list) {
+		return nil, fmt.Errorf("%s: index %d out of bounds (list length: %d)", name, index, len(list))
+	}
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, list...)+lib.Elements(ctx, value)); err != nil {
+		return nil, err
+	}
+	result := make(lang.ListValue, len(list))
+	copy(result, list)
+	result[index] = value
+	return result, nil
+}
+
 func aappend() (string, lang.Function) {
 	name := "append"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 2 {
-			return nil, errors.New("append: expected at least 2 arguments (list, ...values)")
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[0])
-		}
-		result := make(lang.ListValue, len(list))
-		copy(result, list)
-		for i := 1; i < len(args); i++ {
-			result = append(result, args[i])
-		}
-		return result, nil
+		return appendContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// appendContext appends values to a list, failing before it allocates a
+// list larger than the limits of ctx allow.
+func appendContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "append"
+	if len(args) < 2 {
+		return nil, errors.New("append: expected at least 2 arguments (list, ...values)")
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, list...)+lib.Elements(ctx, args[1:]...)); err != nil {
+		return nil, err
+	}
+	result := make(lang.ListValue, len(list))
+	copy(result, list)
+	for i := 1; i < len(args); i++ {
+		result = append(result, args[i])
+	}
+	return result, nil
+}
+
 func prepend() (string, lang.Function) {
 	name := "prepend"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 2 {
-			return nil, errors.New("prepend: expected at least 2 arguments (list, ...values)")
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[0])
-		}
-		var result lang.ListValue
-		for i := 1; i < len(args); i++ {
-			result = append(result, args[i])
-		}
-		result = append(result, list...)
-		return result, nil
+		return prependContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// prependContext prepends values to a list, failing before it allocates a
+// list larger than the limits of ctx allow.
+func prependContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "prepend"
+	if len(args) < 2 {
+		return nil, errors.New("prepend: expected at least 2 arguments (list, ...values)")
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, list...)+lib.Elements(ctx, args[1:]...)); err != nil {
+		return nil, err
+	}
+	var result lang.ListValue
+	for i := 1; i < len(args); i++ {
+		result = append(result, args[i])
+	}
+	result = append(result, list...)
+	return result, nil
+}
+
 func insert() (string, lang.Function) {
 	name := "insert"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[0])
-		}
-		indexNum, err := lib.ToNumber(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: index %w", name, err)
-		}
-		index := int(indexNum)
-		value := args[2]
-		if index < 0 {
-			index = len(list) + index + 1
-		}
-		if index < 0 {
-			index = 0
-		}
-		if index > len(list) {
-			index = len(list)
-		}
-		result := make(lang.ListValue, 0, len(list)+1)
-		result = append(result, list[:index]...)
-		result = append(result, value)
-		result = append(result, list[index:]...)
-		return result, nil
+		return insertContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// insertContext inserts a value into a list, failing before it allocates a
+// list larger than the limits of ctx allow.
+func insertContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "insert"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	indexNum, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: index %w", name, err)
+	}
+	index := int(indexNum)
+	value := args[2]
+	if index < 0 {
+		index = len(list) + index + 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index > len(list) {
+		index = len(list)
+	}
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, list...)+lib.Elements(ctx, value)); err != nil {
+		return nil, err
+	}
+	result := make(lang.ListValue, 0, len(list)+1)
+	result = append(result, list[:index]...)
+	result = append(result, value)
+	result = append(result, list[index:]...)
+	return result, nil
+}
+
 func remove() (string, lang.Function) {
 	name := "remove"
 	fn := func(args []lang.Value) (lang.Value, error) {
@@ -219,19 +261,33 @@ func remove() (string, lang.Function) {
 func concat() (string, lang.Function) {
 	name := "concat"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		var result lang.ListValue
-		for i, arg := range args {
-			if list, ok := arg.(lang.ListValue); ok {
-				result = append(result, list...)
-			} else {
-				return nil, fmt.Errorf("%s: argument %d expected list, got %T", name, i+1, arg)
-			}
-		}
-		return result, nil
+		return concatContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// concatContext concatenates lists, failing before it allocates a list
+// larger than the limits of ctx allow.
+func concatContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "concat"
+	elements := 0.0
+	for i, arg := range args {
+		list, ok := arg.(lang.ListValue)
+		if !ok {
+			return nil, fmt.Errorf("%s: argument %d expected list, got %T", name, i+1, arg)
+		}
+		elements += lib.Elements(ctx, list...)
+	}
+	if err := lib.CheckCollection(ctx, name, elements); err != nil {
+		return nil, err
+	}
+	var result lang.ListValue
+	for _, arg := range args {
+		result = append(result, arg.(lang.ListValue)...)
+	}
+	return result, nil
+}
+
 func first() (string, lang.Function) {
 	name := "first"
 	fn := func(args []lang.Value) (lang.Value, error) {
@@ -515,7 +571,7 @@ func shuffle() (string, lang.Function) {
 		result := make(lang.ListValue, len(list))
 		copy(result, list)
 		for i := len(result) - 1; i > 0; i-- {
-			j := rand.Intn(i + 1)
+			j := rand.IntN(i + 1)
 			result[i], result[j] = result[j], result[i]
 		}
 		return result, nil
@@ -526,51 +582,71 @@ func shuffle() (string, lang.Function) {
 func unique() (string, lang.Function) {
 	name := "unique"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 1 {
-			return nil, lib.ArgumentError(name, 1)
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[0])
+		return uniqueContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// uniqueContext removes duplicates from a list, stopping once ctx is done.
+func uniqueContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "unique"
+	if len(args) != 1 {
+		return nil, lib.ArgumentError(name, 1)
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	seen := make(map[string]bool)
+	var result lang.ListValue
+	for i, item := range list {
+		if err := lib.Cancelled(ctx, name, i); err != nil {
+			return nil, err
 		}
-		seen := make(map[string]bool)
-		var result lang.ListValue
-		for _, item := range list {
-			key := valueToString(item)
-			if !seen[key] {
-				seen[key] = true
-				result = append(result, item)
-			}
+		key := valueToString(item)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, item)
 		}
-		return result, nil
 	}
-	return name, fn
+	return result, nil
 }
 
 func flatten() (string, lang.Function) {
 	name := "flatten"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 1 || len(args) > 2 {
-			return nil, errors.New("flatten: expected 1 or 2 arguments (list, depth?)")
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[0])
+		return flattenContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// flattenContext flattens a list, failing before it allocates a list
+// larger than the limits of ctx allow. The flattened list holds no more
+// elements than list does counting nested ones, so list is checked.
+func flattenContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "flatten"
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("flatten: expected 1 or 2 arguments (list, depth?)")
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	depth := 1
+	if len(args) == 2 {
+		depthNum, err := lib.ToNumber(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: depth %w", name, err)
 		}
-		depth := 1
-		if len(args) == 2 {
-			depthNum, err := lib.ToNumber(args[1])
-			if err != nil {
-				return nil, fmt.Errorf("%s: depth %w", name, err)
-			}
-			depth = int(depthNum)
-			if depth < 0 {
-				return nil, errors.New("flatten: depth must be non-negative")
-			}
+		depth = int(depthNum)
+		if depth < 0 {
+			return nil, errors.New("flatten: depth must be non-negative")
 		}
-		return flattenList(list, depth), nil
 	}
-	return name, fn
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, list...)); err != nil {
+		return nil, err
+	}
+	return flattenList(list, depth), nil
 }
 
 func flattenList(list lang.ListValue, depth int) lang.ListValue {
@@ -688,47 +764,192 @@ func count() (string, lang.Function) {
 func rrange() (string, lang.Function) {
 	name := "range"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 1 || len(args) > 3 {
-			return nil, errors.New("range: expected 1, 2, or 3 arguments (end) or (start, end) or (start, end, step)")
+		return rrangeContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// rrangeContext builds a range of numbers, failing before it allocates a
+// list longer than the limits of ctx allow.
+func rrangeContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "range"
+	if len(args) < 1 || len(args) > 3 {
+		return nil, errors.New("range: expected 1, 2, or 3 arguments (end) or (start, end) or (start, end, step)")
+	}
+	var start, end, step float64
+	var err error
+	if len(args) == 1 {
+		start = 0
+		if end, err = lib.ToNumber(args[0]); err != nil {
+			return nil, fmt.Errorf("range: end %w", err)
 		}
-		var start, end, step float64
-		var err error
-		if len(args) == 1 {
-			start = 0
-			if end, err = lib.ToNumber(args[0]); err != nil {
-				return nil, fmt.Errorf("range: end %w", err)
+		step = 1
+	} else if len(args) == 2 {
+		if start, err = lib.ToNumber(args[0]); err != nil {
+			return nil, fmt.Errorf("range: start %w", err)
+		}
+		if end, err = lib.ToNumber(args[1]); err != nil {
+			return nil, fmt.Errorf("range: end %w", err)
+		}
+		step = 1
+	} else {
+		if start, err = lib.ToNumber(args[0]); err != nil {
+			return nil, fmt.Errorf("range: start %w", err)
+		}
+		if end, err = lib.ToNumber(args[1]); err != nil {
+			return nil, fmt.Errorf("range: end %w", err)
+		}
+		if step, err = lib.ToNumber(args[2]); err != nil {
+			return nil, fmt.Errorf("range: step %w", err)
+		}
+	}
+	if step == 0 {
+		return nil, errors.New("range: step cannot be zero")
+	}
+	if err := lib.CheckCollection(ctx, name, math.Ceil((end-start)/step)); err != nil {
+		return nil, err
+	}
+	var result lang.ListValue
+	if step > 0 {
+		for i := start; i < end; i += step {
+			if err := lib.Cancelled(ctx, name, len(result)); err != nil {
+				return nil, err
 			}
-			step = 1
-		} else if len(args) == 2 {
-			if start, err = lib.ToNumber(args[0]); err != nil {
-				return nil, fmt.Errorf("range: start %w", err)
+			result = append(result, lang.NumberValue(i))
+		}
+	} else {
+		for i := start; i > end; i += step {
+			if err := lib.Cancelled(ctx, name, len(result)); err != nil {
+				return nil, err
 			}
-			if end, err = lib.ToNumber(args[1]); err != nil {
-				return nil, fmt.Errorf("range: end %w", err)
+			result = append(result, lang.NumberValue(i))
+		}
+	}
+	return result, nil
+}
+
+func repeat() (string, lang.Function) {
+	name := "repeat"
+	fn := func(args []lang.Value) (lang.Value, error) {
+		return repeatContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// repeatContext repeats a value, failing before it allocates a list longer
+// than the limits of ctx allow.
+func repeatContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "repeat"
+	if len(args) != 2 {
+		return nil, lib.ArgumentError(name, 2)
+	}
+	value := args[0]
+	countNum, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: count %w", name, err)
+	}
+	if err := lib.CheckCollection(ctx, name, countNum*lib.Elements(ctx, value)); err != nil {
+		return nil, err
+	}
+	count := int(countNum)
+	if count < 0 {
+		return nil, errors.New("repeat: count must be non-negative")
+	}
+	if count == 0 {
+		return lang.ListValue{}, nil
+	}
+	result := make(lang.ListValue, count)
+	for i := 0; i < count; i++ {
+		if err := lib.Cancelled(ctx, name, i); err != nil {
+			return nil, err
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+func zip() (string, lang.Function) {
+	name := "zip"
+	fn := func(args []lang.Value) (lang.Value, error) {
+		return zipContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// zipContext zips lists into tuples, failing before it allocates a list
+// larger than the limits of ctx allow.
+func zipContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "zip"
+	if len(args) == 0 {
+		return nil, errors.New("zip: expected at least 1 argument")
+	}
+	var lists []lang.ListValue
+	minLen := -1
+	for i, arg := range args {
+		if list, ok := arg.(lang.ListValue); ok {
+			lists = append(lists, list)
+			if minLen == -1 || len(list) < minLen {
+				minLen = len(list)
 			}
-			step = 1
 		} else {
-			if start, err = lib.ToNumber(args[0]); err != nil {
-				return nil, fmt.Errorf("range: start %w", err)
-			}
-			if end, err = lib.ToNumber(args[1]); err != nil {
-				return nil, fmt.Errorf("range: end %w", err)
-			}
-			if step, err = lib.ToNumber(args[2]); err != nil {
-				return nil, fmt.Errorf("range: step %w", err)
-			}
+			return nil, fmt.Errorf("%s: argument %d expected list, got %T", name, i+1, arg)
 		}
-		if step == 0 {
-			return nil, errors.New("range: step cannot be zero")
+	}
+	if minLen <= 0 {
+		return lang.ListValue{}, nil
+	}
+	elements := float64(minLen)
+	for _, list := range lists {
+		elements += lib.Elements(ctx, list[:minLen]...)
+	}
+	if err := lib.CheckCollection(ctx, name, elements); err != nil {
+		return nil, err
+	}
+	result := make(lang.ListValue, minLen)
+	for i := 0; i < minLen; i++ {
+		if err := lib.Cancelled(ctx, name, i); err != nil {
+			return nil, err
+		}
+		tuple := make(lang.ListValue, len(lists))
+		for j, list := range lists {
+			tuple[j] = list[i]
+		}
+		result[i] = tuple
+	}
+	return result, nil
+}
+
+func filter() (string, lang.Function) {
+	name := "filter"
+	fn := func(args []lang.Value) (lang.Value, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, errors.New("filter: expected 1 or 2 arguments (list, predicate?)")
+		}
+		list, ok := args[0].(lang.ListValue)
+		if !ok {
+			return nil, lib.ListError(name, args[0])
 		}
 		var result lang.ListValue
-		if step > 0 {
-			for i := start; i < end; i += step {
-				result = append(result, lang.NumberValue(i))
+		if len(args) == 1 {
+			for _, item := range list {
+				if item != nil && !isNullValue(item) {
+					result = append(result, item)
+				}
 			}
-		} else {
-			for i := start; i > end; i += step {
-				result = append(result, lang.NumberValue(i))
+			return result, nil
+		}
+		predicate, ok := args[1].(lang.FunctionValue)
+		if !ok {
+			return nil, lib.FunctionError(name, args[1])
+		}
+		result = lang.ListValue{}
+		for i, item := range list {
+			keep, err := predicate([]lang.Value{item, lang.NumberValue(i)})
+			if err != nil {
+				return nil, err
+			}
+			if lang.ToBool(keep) {
+				result = append(result, item)
 			}
 		}
 		return result, nil
@@ -736,105 +957,248 @@ func rrange() (string, lang.Function) {
 	return name, fn
 }
 
-func repeat() (string, lang.Function) {
-	name := "repeat"
+func mmap() (string, lang.Function) {
+	name := "map"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 2 {
-			return nil, lib.ArgumentError(name, 2)
-		}
-		value := args[0]
-		countNum, err := lib.ToNumber(args[1])
+		return mapContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// mapContext maps a list, failing as soon as the result grows larger than
+// the limits of ctx allow.
+func mapContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "map"
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errors.New("map: expected 1 or 2 arguments (list, mapper?)")
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[0])
+	}
+	result := make(lang.ListValue, len(list))
+	if len(args) == 1 {
+		copy(result, list)
+		return result, nil
+	}
+	mapper, ok := args[1].(lang.FunctionValue)
+	if !ok {
+		return nil, lib.FunctionError(name, args[1])
+	}
+	elements := 0.0
+	for i, item := range list {
+		value, err := mapper([]lang.Value{item, lang.NumberValue(i)})
 		if err != nil {
-			return nil, fmt.Errorf("%s: count %w", name, err)
+			return nil, err
 		}
-		count := int(countNum)
-		if count < 0 {
-			return nil, errors.New("repeat: count must be non-negative")
+		elements += lib.Elements(ctx, value)
+		if err := lib.CheckCollection(ctx, name, elements); err != nil {
+			return nil, err
 		}
-		if count == 0 {
-			return lang.ListValue{}, nil
+		result[i] = value
+	}
+	return result, nil
+}
+
+func reduce() (string, lang.Function) {
+	name := "reduce"
+	fn := func(args []lang.Value) (lang.Value, error) {
+		if len(args) < 2 || len(args) > 3 {
+			return nil, errors.New("reduce: expected 2 or 3 arguments (list, reducer, initial?)")
 		}
-		result := make(lang.ListValue, count)
-		for i := 0; i < count; i++ {
-			result[i] = value
+		list, ok := args[0].(lang.ListValue)
+		if !ok {
+			return nil, lib.ListError(name, args[0])
 		}
-		return result, nil
+		reducer, ok := args[1].(lang.FunctionValue)
+		if !ok {
+			return nil, lib.FunctionError(name, args[1])
+		}
+		start := 0
+		var accumulator lang.Value
+		if len(args) == 3 {
+			accumulator = args[2]
+		} else {
+			if len(list) == 0 {
+				return nil, errors.New("reduce: list is empty and no initial value was given")
+			}
+			accumulator = list[0]
+			start = 1
+		}
+		for i := start; i < len(list); i++ {
+			value, err := reducer([]lang.Value{accumulator, list[i], lang.NumberValue(i)})
+			if err != nil {
+				return nil, err
+			}
+			accumulator = value
+		}
+		return accumulator, nil
 	}
 	return name, fn
 }
 
-func zip() (string, lang.Function) {
-	name := "zip"
+func aany() (string, lang.Function) {
+	name := "any"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) == 0 {
-			return nil, errors.New("zip: expected at least 1 argument")
+		list, predicate, err := listAndPredicate(name, args)
+		if err != nil {
+			return nil, err
 		}
-		var lists []lang.ListValue
-		minLen := -1
-		for i, arg := range args {
-			if list, ok := arg.(lang.ListValue); ok {
-				lists = append(lists, list)
-				if minLen == -1 || len(list) < minLen {
-					minLen = len(list)
-				}
-			} else {
-				return nil, fmt.Errorf("%s: argument %d expected list, got %T", name, i+1, arg)
+		for i, item := range list {
+			ok, err := predicate(item, i)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return lang.BoolValue(true), nil
 			}
 		}
-		if minLen <= 0 {
-			return lang.ListValue{}, nil
+		return lang.BoolValue(false), nil
+	}
+	return name, fn
+}
+
+func aall() (string, lang.Function) {
+	name := "all"
+	fn := func(args []lang.Value) (lang.Value, error) {
+		list, predicate, err := listAndPredicate(name, args)
+		if err != nil {
+			return nil, err
 		}
-		result := make(lang.ListValue, minLen)
-		for i := 0; i < minLen; i++ {
-			tuple := make(lang.ListValue, len(lists))
-			for j, list := range lists {
-				tuple[j] = list[i]
+		for i, item := range list {
+			ok, err := predicate(item, i)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return lang.BoolValue(false), nil
 			}
-			result[i] = tuple
 		}
-		return result, nil
+		return lang.BoolValue(true), nil
 	}
 	return name, fn
 }
 
-func filter() (string, lang.Function) {
-	name := "filter"
+func find() (string, lang.Function) {
+	name := "find"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 1 {
-			return nil, lib.ArgumentError(name, 1)
+		if len(args) < 2 || len(args) > 3 {
+			return nil, errors.New("find: expected 2 or 3 arguments (list, predicate, default?)")
+		}
+		list, predicate, err := listAndPredicate(name, args[:2])
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range list {
+			ok, err := predicate(item, i)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return item, nil
+			}
+		}
+		if len(args) == 3 {
+			return args[2], nil
+		}
+		return nil, nil
+	}
+	return name, fn
+}
+
+func sortBy() (string, lang.Function) {
+	name := "sortBy"
+	fn := func(args []lang.Value) (lang.Value, error) {
+		if len(args) != 2 {
+			return nil, lib.ArgumentError(name, 2)
 		}
 		list, ok := args[0].(lang.ListValue)
 		if !ok {
 			return nil, lib.ListError(name, args[0])
 		}
-		var result lang.ListValue
-		for _, item := range list {
-			if item != nil && !isNullValue(item) {
-				result = append(result, item)
+		selector, ok := args[1].(lang.FunctionValue)
+		if !ok {
+			return nil, lib.FunctionError(name, args[1])
+		}
+		keys := make([]lang.Value, len(list))
+		order := make([]int, len(list))
+		for i, item := range list {
+			key, err := selector([]lang.Value{item, lang.NumberValue(i)})
+			if err != nil {
+				return nil, err
 			}
+			keys[i] = key
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return compareValues(keys[order[i]], keys[order[j]]) < 0
+		})
+		result := make(lang.ListValue, len(list))
+		for i, idx := range order {
+			result[i] = list[idx]
 		}
 		return result, nil
 	}
 	return name, fn
 }
 
-func mmap() (string, lang.Function) {
-	name := "map"
+func groupBy() (string, lang.Function) {
+	name := "groupBy"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 1 {
-			return nil, lib.ArgumentError(name, 1)
+		if len(args) != 2 {
+			return nil, lib.ArgumentError(name, 2)
 		}
 		list, ok := args[0].(lang.ListValue)
 		if !ok {
 			return nil, lib.ListError(name, args[0])
 		}
-		result := make(lang.ListValue, len(list))
-		copy(result, list)
+		selector, ok := args[1].(lang.FunctionValue)
+		if !ok {
+			return nil, lib.FunctionError(name, args[1])
+		}
+		result := make(lang.MapValue)
+		for i, item := range list {
+			key, err := selector([]lang.Value{item, lang.NumberValue(i)})
+			if err != nil {
+				return nil, err
+			}
+			group := valueToString(key)
+			values, _ := result[group].(lang.ListValue)
+			result[group] = append(values, item)
+		}
 		return result, nil
 	}
 	return name, fn
 }
 
+// listAndPredicate validates (list, predicate?) arguments. Without a
+// predicate the truthiness of each element is used.
+func listAndPredicate(name string, args []lang.Value) (lang.ListValue, func(lang.Value, int) (bool, error), error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, nil, lib.ArgumentErrorRange(name, 1, 2)
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, nil, lib.ListError(name, args[0])
+	}
+	if len(args) == 1 {
+		return list, func(item lang.Value, _ int) (bool, error) {
+			return lang.ToBool(item), nil
+		}, nil
+	}
+	fn, ok := args[1].(lang.FunctionValue)
+	if !ok {
+		return nil, nil, lib.FunctionError(name, args[1])
+	}
+	return list, func(item lang.Value, index int) (bool, error) {
+		value, err := fn([]lang.Value{item, lang.NumberValue(index)})
+		if err != nil {
+			return false, err
+		}
+		return lang.ToBool(value), nil
+	}, nil
+}
+
 func compareValues(a, b lang.Value) int {
 	aNum, aErr := lib.ToNumber(a)
 	bNum, bErr := lib.ToNumber(b)
@@ -929,13 +1293,271 @@ var listFunctions = []func() (string, lang.Function){
 	zip,
 	filter,
 	mmap,
+	reduce,
+	aany,
+	aall,
+	find,
+	sortBy,
+	groupBy,
+}
+
+var listSignatures = map[string]lib.Descriptor{
+	"length": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the number of elements in a list.",
+	},
+	"isEmpty": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a list contains no elements.",
+	},
+	"get": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("index", lang.NumberType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Retrieves an element at a specific index.",
+	},
+	"set": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("index", lang.NumberType), lib.Arg("value", lang.AnyType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a new list with an element set at a specific index.",
+		ContextFunction: setContext,
+	},
+	"append": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("values", lang.AnyType)},
+		Variadic:        true,
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a new list with values added to the end.",
+		ContextFunction: appendContext,
+	},
+	"prepend": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("values", lang.AnyType)},
+		Variadic:        true,
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a new list with values added to the beginning.",
+		ContextFunction: prependContext,
+	},
+	"insert": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("index", lang.NumberType), lib.Arg("value", lang.AnyType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a new list with a value inserted at a specific index.",
+		ContextFunction: insertContext,
+	},
+	"remove": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("index", lang.NumberType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Creates a new list with an element removed at a specific index.",
+	},
+	"concat": {
+		Params:          []lib.Param{lib.OptionalArg("lists", lang.ListType)},
+		Variadic:        true,
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Combines multiple lists into one.",
+		ContextFunction: concatContext,
+	},
+	"first": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Gets the first element of a list.",
+	},
+	"last": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Gets the last element of a list.",
+	},
+	"head": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Alias for `first()` - gets the first element.",
+	},
+	"tail": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Gets all elements except the first.",
+	},
+	"rest": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Alias for `tail()` - gets all elements except the first.",
+	},
+	"init": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Gets all elements except the last.",
+	},
+	"slice": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("start", lang.NumberType), lib.OptionalArg("end", lang.NumberType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Extracts a section of a list.",
+	},
+	"take": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("count", lang.NumberType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Gets the first N elements.",
+	},
+	"drop": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("count", lang.NumberType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Gets all elements except the first N.",
+	},
+	"reverse": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Creates a new list with elements in reverse order.",
+	},
+	"sort": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Creates a new sorted list in ascending order.",
+	},
+	"sortDesc": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Creates a new sorted list in descending order.",
+	},
+	"shuffle": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType)},
+		Result: lang.ListType,
+		Pure:   false,
+		Doc:    "Creates a new list with elements in random order.",
+	},
+	"unique": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a new list with duplicate values removed.",
+		ContextFunction: uniqueContext,
+	},
+	"flatten": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("depth", lang.NumberType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Flattens nested lists up to specified depth.",
+		ContextFunction: flattenContext,
+	},
+	"contains": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a list contains a specific value.",
+	},
+	"indexOf": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("value", lang.AnyType), lib.OptionalArg("start", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Finds the first index of a value in a list.",
+	},
+	"lastIndexOf": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("value", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Finds the last index of a value in a list.",
+	},
+	"count": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("value", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Counts occurrences of a value in a list.",
+	},
+	"range": {
+		Params:          []lib.Param{lib.Arg("start", lang.NumberType), lib.OptionalArg("end", lang.NumberType), lib.OptionalArg("step", lang.NumberType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a list of numbers from start (default 0) up to end (exclusive). With a single argument it is the end.",
+		ContextFunction: rrangeContext,
+	},
+	"repeat": {
+		Params:          []lib.Param{lib.Arg("value", lang.AnyType), lib.Arg("count", lang.NumberType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a list with a value repeated N times.",
+		ContextFunction: repeatContext,
+	},
+	"zip": {
+		Params:          []lib.Param{lib.Arg("lists", lang.ListType)},
+		Variadic:        true,
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Combines multiple lists element-wise into tuples.",
+		ContextFunction: zipContext,
+	},
+	"filter": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("predicate", lang.FunctionType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Keeps the elements for which the predicate returns a truthy value. Without a predicate, removes null and falsy values.",
+	},
+	"map": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("mapper", lang.FunctionType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Applies a function to every element. Without a mapper, returns a copy of the list.",
+		ContextFunction: mapContext,
+	},
+	"reduce": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("reducer", lang.FunctionType), lib.OptionalArg("initial", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Folds the list into a single value.",
+	},
+	"any": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("predicate", lang.FunctionType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks whether at least one element matches. Without a predicate, checks for a truthy element.",
+	},
+	"all": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.OptionalArg("predicate", lang.FunctionType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks whether every element matches. Without a predicate, checks that every element is truthy.",
+	},
+	"find": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("predicate", lang.FunctionType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns the first element that matches.",
+	},
+	"sortBy": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("selector", lang.FunctionType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Stable sort by the key returned from the selector.",
+	},
+	"groupBy": {
+		Params: []lib.Param{lib.Arg("list", lang.ListType), lib.Arg("selector", lang.FunctionType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Groups elements by the key returned from the selector.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(listFunctions, listSignatures)
 }
 
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range listFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -16,7 +16,10 @@
 package list
 
 import (
+	"context"
+	"errors"
 	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 
@@ -1083,6 +1086,166 @@ func TestMap(t *testing.T) {
 	}
 }
 
+func TestFilterWithPredicate(t *testing.T) {
+	_, fn := filter()
+	testList := lang.ListValue{lang.NumberValue(1), lang.NumberValue(2), lang.NumberValue(3), lang.NumberValue(4)}
+	even := lang.FunctionValue(func(args []lang.Value) (lang.Value, error) {
+		return lang.BoolValue(int(args[0].(lang.NumberValue))%2 == 0), nil
+	})
+
+	result, err := fn([]lang.Value{testList, even})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	expected := lang.ListValue{lang.NumberValue(2), lang.NumberValue(4)}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	if _, err := fn([]lang.Value{testList, lang.StringValue("not a function")}); err == nil {
+		t.Errorf("Expected error for non-function predicate")
+	}
+}
+
+func TestMapWithFunction(t *testing.T) {
+	_, fn := mmap()
+	testList := lang.ListValue{lang.NumberValue(1), lang.NumberValue(2), lang.NumberValue(3)}
+	double := lang.FunctionValue(func(args []lang.Value) (lang.Value, error) {
+		return args[0].(lang.NumberValue) * 2, nil
+	})
+
+	result, err := fn([]lang.Value{testList, double})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+		return
+	}
+	expected := lang.ListValue{lang.NumberValue(2), lang.NumberValue(4), lang.NumberValue(6)}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	_, fn := reduce()
+	sum := lang.FunctionValue(func(args []lang.Value) (lang.Value, error) {
+		return args[0].(lang.NumberValue) + args[1].(lang.NumberValue), nil
+	})
+
+	tests := []struct {
+		name     string
+		args     []lang.Value
+		expected lang.Value
+		hasError bool
+	}{
+		{"with initial", []lang.Value{lang.ListValue{lang.NumberValue(1), lang.NumberValue(2)}, sum, lang.NumberValue(10)}, lang.NumberValue(13), false},
+		{"without initial", []lang.Value{lang.ListValue{lang.NumberValue(1), lang.NumberValue(2), lang.NumberValue(3)}, sum}, lang.NumberValue(6), false},
+		{"empty with initial", []lang.Value{lang.ListValue{}, sum, lang.NumberValue(0)}, lang.NumberValue(0), false},
+		{"empty without initial", []lang.Value{lang.ListValue{}, sum}, nil, true},
+		{"non-function reducer", []lang.Value{lang.ListValue{}, lang.NumberValue(1)}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn(tt.args)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAnyAllFind(t *testing.T) {
+	_, anyFn := aany()
+	_, allFn := aall()
+	_, findFn := find()
+	testList := lang.ListValue{lang.NumberValue(50), lang.NumberValue(150), lang.NumberValue(200)}
+	over := func(limit float64) lang.FunctionValue {
+		return func(args []lang.Value) (lang.Value, error) {
+			return lang.BoolValue(float64(args[0].(lang.NumberValue)) > limit), nil
+		}
+	}
+
+	tests := []struct {
+		name     string
+		fn       lang.Function
+		args     []lang.Value
+		expected lang.Value
+	}{
+		{"any match", anyFn, []lang.Value{testList, over(100)}, lang.BoolValue(true)},
+		{"any no match", anyFn, []lang.Value{testList, over(500)}, lang.BoolValue(false)},
+		{"any truthy", anyFn, []lang.Value{lang.ListValue{lang.BoolValue(false), lang.NumberValue(1)}}, lang.BoolValue(true)},
+		{"any empty", anyFn, []lang.Value{lang.ListValue{}, over(0)}, lang.BoolValue(false)},
+		{"all match", allFn, []lang.Value{testList, over(10)}, lang.BoolValue(true)},
+		{"all no match", allFn, []lang.Value{testList, over(100)}, lang.BoolValue(false)},
+		{"all empty", allFn, []lang.Value{lang.ListValue{}, over(0)}, lang.BoolValue(true)},
+		{"find match", findFn, []lang.Value{testList, over(100)}, lang.NumberValue(150)},
+		{"find no match", findFn, []lang.Value{testList, over(500)}, nil},
+		{"find default", findFn, []lang.Value{testList, over(500), lang.StringValue("none")}, lang.StringValue("none")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.fn(tt.args)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSortByAndGroupBy(t *testing.T) {
+	_, sortByFn := sortBy()
+	_, groupByFn := groupBy()
+	people := lang.ListValue{
+		lang.MapValue{"name": lang.StringValue("carol"), "age": lang.NumberValue(40)},
+		lang.MapValue{"name": lang.StringValue("alice"), "age": lang.NumberValue(30)},
+		lang.MapValue{"name": lang.StringValue("bob"), "age": lang.NumberValue(30)},
+	}
+	age := lang.FunctionValue(func(args []lang.Value) (lang.Value, error) {
+		return args[0].(lang.MapValue)["age"], nil
+	})
+
+	result, err := sortByFn([]lang.Value{people, age})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sorted := result.(lang.ListValue)
+	names := []string{"alice", "bob", "carol"}
+	for i, name := range names {
+		if sorted[i].(lang.MapValue)["name"] != lang.StringValue(name) {
+			t.Errorf("Expected %s at position %d, got %v", name, i, sorted[i])
+		}
+	}
+
+	result, err = groupByFn([]lang.Value{people, age})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	groups := result.(lang.MapValue)
+	if len(groups["30"].(lang.ListValue)) != 2 || len(groups["40"].(lang.ListValue)) != 1 {
+		t.Errorf("Unexpected groups %v", groups)
+	}
+
+	if _, err := sortByFn([]lang.Value{people, lang.NumberValue(1)}); err == nil {
+		t.Errorf("Expected error for non-function selector")
+	}
+}
+
 func TestHelperFunctions(t *testing.T) {
 	t.Run("compareValues", func(t *testing.T) {
 		tests := []struct {
@@ -1256,7 +1419,8 @@ func TestExport(t *testing.T) {
 		"remove", "concat", "first", "last", "head", "tail", "rest", "init",
 		"slice", "take", "drop", "reverse", "sort", "sortDesc", "shuffle",
 		"unique", "flatten", "contains", "indexOf", "lastIndexOf", "count",
-		"range", "repeat", "zip", "filter", "map",
+		"range", "repeat", "zip", "filter", "map", "reduce", "any", "all",
+		"find", "sortBy", "groupBy",
 	}
 
 	if len(functions) != len(expectedFunctions) {
@@ -1316,3 +1480,72 @@ func BenchmarkContains(b *testing.B) {
 func float64Ptr(f float64) *float64 {
 	return &f
 }
+
+func TestLimits(t *testing.T) {
+	limited := lang.WithLimits(context.Background(), lang.Limits{MaxCollectionSize: 6})
+	pair := lang.ListValue{lang.NumberValue(1), lang.NumberValue(2)}
+
+	tests := []struct {
+		name  string
+		fn    lang.ContextFunction
+		args  []lang.Value
+		fails bool
+	}{
+		{"range", rrangeContext, []lang.Value{lang.NumberValue(6)}, false},
+		{"range too long", rrangeContext, []lang.Value{lang.NumberValue(0), lang.NumberValue(1e9)}, true},
+		{"range with step", rrangeContext, []lang.Value{lang.NumberValue(0), lang.NumberValue(10), lang.NumberValue(2)}, false},
+		{"range descending", rrangeContext, []lang.Value{lang.NumberValue(10), lang.NumberValue(0), lang.NumberValue(-1)}, true},
+		{"repeat", repeatContext, []lang.Value{lang.NumberValue(1), lang.NumberValue(6)}, false},
+		{"repeat too long", repeatContext, []lang.Value{lang.NumberValue(1), lang.NumberValue(1e300)}, true},
+		{"repeat nested", repeatContext, []lang.Value{pair, lang.NumberValue(2)}, false},
+		{"repeat nested too large", repeatContext, []lang.Value{pair, lang.NumberValue(3)}, true},
+		{"flatten", flattenContext, []lang.Value{lang.ListValue{pair, pair}}, false},
+		{"flatten too large", flattenContext, []lang.Value{lang.ListValue{pair, pair, pair}}, true},
+		{"concat", concatContext, []lang.Value{pair, pair}, false},
+		{"concat nested too large", concatContext, []lang.Value{lang.ListValue{pair, pair}, pair}, true},
+		{"append too large", appendContext, []lang.Value{pair, pair, pair}, true},
+		{"prepend", prependContext, []lang.Value{pair, pair}, false},
+		{"insert too large", insertContext, []lang.Value{lang.ListValue{pair, pair}, lang.NumberValue(0), pair}, true},
+		{"set too large", setContext, []lang.Value{lang.ListValue{pair, lang.NumberValue(1)}, lang.NumberValue(1), pair}, true},
+		{"zip too large", zipContext, []lang.Value{pair, pair, pair}, true},
+		{"map too large", mapContext, []lang.Value{lang.ListValue{nil, nil, nil}, lang.FunctionValue(func(args []lang.Value) (lang.Value, error) { return pair, nil })}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.fn(limited, tt.args)
+			if tt.fails != errors.Is(err, lang.ErrLimitExceeded) {
+				t.Errorf("Expected the limit to be exceeded: %v, got %v", tt.fails, err)
+			}
+			if !tt.fails && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	long := lang.ListValue(make([]lang.Value, 2048))
+
+	tests := []struct {
+		name string
+		fn   lang.ContextFunction
+		args []lang.Value
+	}{
+		{"range", rrangeContext, []lang.Value{lang.NumberValue(1e9)}},
+		{"range descending", rrangeContext, []lang.Value{lang.NumberValue(0), lang.NumberValue(-1e9), lang.NumberValue(-1)}},
+		{"repeat", repeatContext, []lang.Value{lang.NumberValue(1), lang.NumberValue(1e8)}},
+		{"unique", uniqueContext, []lang.Value{long}},
+		{"zip", zipContext, []lang.Value{long, long}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.fn(cancelled, tt.args); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected the function to be cancelled, got %v", err)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@
 package maps
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strconv"
@@ -167,31 +168,42 @@ func get() (string, lang.Function) {
 func set() (string, lang.Function) {
 	name := "set"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		m, ok := args[0].(lang.MapValue)
-		if !ok {
-			return nil, lib.MapError(name, args[0])
-		}
+		return setContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		keyStr, err := lib.ToString(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: key %w", name, err)
-		}
-		key := string(keyStr)
-		value := args[2]
+// setContext sets a key of a map, failing before it allocates a map larger
+// than the limits of ctx allow.
+func setContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "set"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	m, ok := args[0].(lang.MapValue)
+	if !ok {
+		return nil, lib.MapError(name, args[0])
+	}
 
-		// Create a new map with the updated value
-		result := make(lang.MapValue, len(m)+1)
-		for k, v := range m {
-			result[k] = v
-		}
-		result[key] = value
+	keyStr, err := lib.ToString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: key %w", name, err)
+	}
+	key := string(keyStr)
+	value := args[2]
 
-		return result, nil
+	if err := lib.CheckCollection(ctx, name, entries(ctx, m)+lib.Elements(ctx, value)); err != nil {
+		return nil, err
 	}
-	return name, fn
+
+	// Create a new map with the updated value
+	result := make(lang.MapValue, len(m)+1)
+	for k, v := range m {
+		result[k] = v
+	}
+	result[key] = value
+
+	return result, nil
 }
 
 func remove() (string, lang.Function) {
@@ -227,55 +239,84 @@ func remove() (string, lang.Function) {
 func merge() (string, lang.Function) {
 	name := "merge"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) == 0 {
-			return nil, fmt.Errorf("%s: expected at least 1 argument", name)
-		}
+		return mergeContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		result := make(lang.MapValue)
+// mergeContext merges maps, failing as soon as the result grows larger than
+// the limits of ctx allow.
+func mergeContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "merge"
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s: expected at least 1 argument", name)
+	}
 
-		for i, arg := range args {
-			if m, ok := arg.(lang.MapValue); ok {
-				for key, value := range m {
-					result[key] = value
-				}
-			} else {
-				return nil, fmt.Errorf("%s: argument %d expected map, got %T", name, i+1, arg)
+	result := make(lang.MapValue)
+
+	for i, arg := range args {
+		if m, ok := arg.(lang.MapValue); ok {
+			for key, value := range m {
+				result[key] = value
 			}
+			if err := lib.CheckCollection(ctx, name, entries(ctx, result)); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("%s: argument %d expected map, got %T", name, i+1, arg)
 		}
-
-		return result, nil
 	}
-	return name, fn
+
+	return result, nil
 }
 
 func mergeDeep() (string, lang.Function) {
 	name := "mergeDeep"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) == 0 {
-			return nil, fmt.Errorf("%s: expected at least 1 argument", name)
-		}
+		return mergeDeepContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		result := make(lang.MapValue)
-		if first, ok := args[0].(lang.MapValue); ok {
-			// Deep copy first map
-			for k, v := range first {
-				result[k] = deepCopyValue(v)
+// mergeDeepContext deeply merges maps, failing before it copies a map or
+// grows the result larger than the limits of ctx allow.
+func mergeDeepContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "mergeDeep"
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s: expected at least 1 argument", name)
+	}
+
+	// Every map is copied, so each is checked before it is
+	for _, arg := range args {
+		if m, ok := arg.(lang.MapValue); ok {
+			if err := lib.CheckCollection(ctx, name, entries(ctx, m)); err != nil {
+				return nil, err
 			}
-		} else {
-			return nil, fmt.Errorf("%s: argument 1 expected map, got %T", name, args[0])
 		}
+	}
 
-		for i := 1; i < len(args); i++ {
-			if m, ok := args[i].(lang.MapValue); ok {
-				result = deepMergeMaps(result, m)
-			} else {
-				return nil, fmt.Errorf("%s: argument %d expected map, got %T", name, i+1, args[i])
-			}
+	result := make(lang.MapValue)
+	if first, ok := args[0].(lang.MapValue); ok {
+		// Deep copy first map
+		for k, v := range first {
+			result[k] = deepCopyValue(v)
 		}
+	} else {
+		return nil, fmt.Errorf("%s: argument 1 expected map, got %T", name, args[0])
+	}
 
-		return result, nil
+	for i := 1; i < len(args); i++ {
+		if m, ok := args[i].(lang.MapValue); ok {
+			result = deepMergeMaps(result, m)
+			if err := lib.CheckCollection(ctx, name, entries(ctx, result)); err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("%s: argument %d expected map, got %T", name, i+1, args[i])
+		}
 	}
-	return name, fn
+
+	return result, nil
 }
 
 func deepMergeMaps(dest, src lang.MapValue) lang.MapValue {
@@ -509,36 +550,47 @@ func toList() (string, lang.Function) {
 func fromList() (string, lang.Function) {
 	name := "fromList"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 1 {
-			return nil, lib.ArgumentError(name, 1)
-		}
-		list, ok := args[0].(lang.ListValue)
-		if !ok {
-			return nil, fmt.Errorf("%s: expected list, got %T", name, args[0])
-		}
+		return fromListContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		result := make(lang.MapValue)
+// fromListContext builds a map from key-value pairs, failing before it
+// allocates a map larger than the limits of ctx allow.
+func fromListContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "fromList"
+	if len(args) != 1 {
+		return nil, lib.ArgumentError(name, 1)
+	}
+	list, ok := args[0].(lang.ListValue)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected list, got %T", name, args[0])
+	}
 
-		for i, item := range list {
-			if pair, ok := item.(lang.ListValue); ok {
-				if len(pair) < 2 {
-					return nil, fmt.Errorf("%s: pair %d must have at least 2 elements, got %d", name, i, len(pair))
-				}
-				keyStr, err := lib.ToString(pair[0])
-				if err != nil {
-					return nil, fmt.Errorf("%s: pair %d key %w", name, i, err)
-				}
-				key := string(keyStr)
-				value := pair[1]
-				result[key] = value
-			} else {
-				return nil, fmt.Errorf("%s: item %d expected list (key-value pair), got %T", name, i, item)
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, list...)); err != nil {
+		return nil, err
+	}
+
+	result := make(lang.MapValue)
+
+	for i, item := range list {
+		if pair, ok := item.(lang.ListValue); ok {
+			if len(pair) < 2 {
+				return nil, fmt.Errorf("%s: pair %d must have at least 2 elements, got %d", name, i, len(pair))
 			}
+			keyStr, err := lib.ToString(pair[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s: pair %d key %w", name, i, err)
+			}
+			key := string(keyStr)
+			value := pair[1]
+			result[key] = value
+		} else {
+			return nil, fmt.Errorf("%s: item %d expected list (key-value pair), got %T", name, i, item)
 		}
-
-		return result, nil
 	}
-	return name, fn
+
+	return result, nil
 }
 
 func toQueryString() (string, lang.Function) {
@@ -682,57 +734,68 @@ func getPath() (string, lang.Function) {
 func setPath() (string, lang.Function) {
 	name := "setPath"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		m, ok := args[0].(lang.MapValue)
-		if !ok {
-			return nil, lib.MapError(name, args[0])
-		}
+		return setPathContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		pathStr, err := lib.ToString(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: path %w", name, err)
-		}
-		path := string(pathStr)
-		value := args[2]
+// setPathContext sets a nested key of a copy of a map, failing before it
+// copies a map larger than the limits of ctx allow.
+func setPathContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "setPath"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	m, ok := args[0].(lang.MapValue)
+	if !ok {
+		return nil, lib.MapError(name, args[0])
+	}
 
-		if path == "" {
-			return nil, fmt.Errorf("%s: path cannot be empty", name)
-		}
+	pathStr, err := lib.ToString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: path %w", name, err)
+	}
+	path := string(pathStr)
+	value := args[2]
 
-		// Deep copy the original map
-		result := deepCopyValue(m).(lang.MapValue)
+	if path == "" {
+		return nil, fmt.Errorf("%s: path cannot be empty", name)
+	}
 
-		parts := strings.Split(path, ".")
-		current := result
-
-		// Navigate to the parent of the target
-		for i := 0; i < len(parts)-1; i++ {
-			part := parts[i]
-			if next, exists := current[part]; exists {
-				if nextMap, ok := next.(lang.MapValue); ok {
-					current = nextMap
-				} else {
-					// Replace with a new map
-					newMap := make(lang.MapValue)
-					current[part] = newMap
-					current = newMap
-				}
+	if err := lib.CheckCollection(ctx, name, entries(ctx, m)+lib.Elements(ctx, value)); err != nil {
+		return nil, err
+	}
+
+	// Deep copy the original map
+	result := deepCopyValue(m).(lang.MapValue)
+
+	parts := strings.Split(path, ".")
+	current := result
+
+	// Navigate to the parent of the target
+	for i := 0; i < len(parts)-1; i++ {
+		part := parts[i]
+		if next, exists := current[part]; exists {
+			if nextMap, ok := next.(lang.MapValue); ok {
+				current = nextMap
 			} else {
-				// Create new map
+				// Replace with a new map
 				newMap := make(lang.MapValue)
 				current[part] = newMap
 				current = newMap
 			}
+		} else {
+			// Create new map
+			newMap := make(lang.MapValue)
+			current[part] = newMap
+			current = newMap
 		}
+	}
 
-		// Set the final value
-		current[parts[len(parts)-1]] = value
+	// Set the final value
+	current[parts[len(parts)-1]] = value
 
-		return result, nil
-	}
-	return name, fn
+	return result, nil
 }
 
 func hasPath() (string, lang.Function) {
@@ -777,62 +840,81 @@ func hasPath() (string, lang.Function) {
 
 func deletePath() (string, lang.Function) {
 	name := "deletePath"
-	_, Delete := remove()
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 2 {
-			return nil, lib.ArgumentError(name, 2)
-		}
-		m, ok := args[0].(lang.MapValue)
-		if !ok {
-			return nil, lib.MapError(name, args[0])
-		}
+		return deletePathContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		pathStr, err := lib.ToString(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: path %w", name, err)
-		}
-		path := string(pathStr)
+// deletePathContext deletes a nested key from a copy of a map, failing
+// before it copies a map larger than the limits of ctx allow.
+func deletePathContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "deletePath"
+	_, Delete := remove()
+	if len(args) != 2 {
+		return nil, lib.ArgumentError(name, 2)
+	}
+	m, ok := args[0].(lang.MapValue)
+	if !ok {
+		return nil, lib.MapError(name, args[0])
+	}
 
-		if path == "" {
-			return nil, fmt.Errorf("%s: path cannot be empty", name)
-		}
+	pathStr, err := lib.ToString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: path %w", name, err)
+	}
+	path := string(pathStr)
 
-		parts := strings.Split(path, ".")
+	if path == "" {
+		return nil, fmt.Errorf("%s: path cannot be empty", name)
+	}
 
-		if len(parts) == 1 {
-			// Simple key deletion
-			return Delete(args)
-		}
+	parts := strings.Split(path, ".")
 
-		// Deep copy the original map
-		result := deepCopyValue(m).(lang.MapValue)
+	if len(parts) == 1 {
+		// Simple key deletion
+		return Delete(args)
+	}
 
-		// Navigate to the parent of the target
-		current := result
-		for i := 0; i < len(parts)-1; i++ {
-			part := parts[i]
-			if next, exists := current[part]; exists {
-				if nextMap, ok := next.(lang.MapValue); ok {
-					current = nextMap
-				} else {
-					// Path doesn't exist
-					return result, nil
-				}
+	if err := lib.CheckCollection(ctx, name, entries(ctx, m)); err != nil {
+		return nil, err
+	}
+
+	// Deep copy the original map
+	result := deepCopyValue(m).(lang.MapValue)
+
+	// Navigate to the parent of the target
+	current := result
+	for i := 0; i < len(parts)-1; i++ {
+		part := parts[i]
+		if next, exists := current[part]; exists {
+			if nextMap, ok := next.(lang.MapValue); ok {
+				current = nextMap
 			} else {
 				// Path doesn't exist
 				return result, nil
 			}
+		} else {
+			// Path doesn't exist
+			return result, nil
 		}
+	}
 
-		// Delete the final key
-		delete(current, parts[len(parts)-1])
+	// Delete the final key
+	delete(current, parts[len(parts)-1])
 
-		return result, nil
-	}
-	return name, fn
+	return result, nil
 }
 
 // Helper functions
+
+// entries counts the entries of m, nested elements included, as far as the
+// limits of ctx need them counted.
+func entries(ctx context.Context, m lang.MapValue) float64 {
+	max := lang.LimitsFrom(ctx).MaxCollectionSize
+	return float64(lang.Elements(max+1, m) - 1)
+}
+
 func valueToStringForMap(v lang.Value) string {
 	switch val := v.(type) {
 	case lang.StringValue:
@@ -905,11 +987,160 @@ var mapFunctions = []func() (string, lang.Function){
 	deletePath,
 }
 
+var mapSignatures = map[string]lib.Descriptor{
+	"keys": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Returns all keys from a map as a sorted list.",
+	},
+	"values": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Returns all values from a map in key-sorted order.",
+	},
+	"size": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the number of key-value pairs in a map.",
+	},
+	"isEmpty": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a map contains no key-value pairs.",
+	},
+	"has": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("key", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a map contains a specific key.",
+	},
+	"get": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("key", lang.StringType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Retrieves a value for a specific key.",
+	},
+	"set": {
+		Params:          []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("key", lang.StringType), lib.Arg("value", lang.AnyType)},
+		Result:          lang.MapType,
+		Pure:            true,
+		Doc:             "Creates a new map with a key-value pair added or updated.",
+		ContextFunction: setContext,
+	},
+	"delete": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("key", lang.StringType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Creates a new map with a specific key removed.",
+	},
+	"merge": {
+		Params:          []lib.Param{lib.Arg("maps", lang.MapType)},
+		Variadic:        true,
+		Result:          lang.MapType,
+		Pure:            true,
+		Doc:             "Combines multiple maps into one (shallow merge).",
+		ContextFunction: mergeContext,
+	},
+	"mergeDeep": {
+		Params:          []lib.Param{lib.Arg("maps", lang.MapType)},
+		Variadic:        true,
+		Result:          lang.MapType,
+		Pure:            true,
+		Doc:             "Performs deep merge of multiple maps, recursively merging nested objects.",
+		ContextFunction: mergeDeepContext,
+	},
+	"invert": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Creates a new map with keys and values swapped.",
+	},
+	"filter": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Creates a new map with null/falsy values removed.",
+	},
+	"filterKeys": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("keys", lang.ListType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Creates a new map containing only specified keys.",
+	},
+	"omitKeys": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("keys", lang.ListType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Creates a new map excluding specified keys.",
+	},
+	"rename": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("renameMap", lang.MapType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Creates a new map with keys renamed according to a mapping.",
+	},
+	"toList": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Converts a map to a list of key-value pairs.",
+	},
+	"fromList": {
+		Params:          []lib.Param{lib.Arg("list", lang.ListType)},
+		Result:          lang.MapType,
+		Pure:            true,
+		Doc:             "Creates a map from a list of key-value pairs.",
+		ContextFunction: fromListContext,
+	},
+	"toQueryString": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a map to a URL query string.",
+	},
+	"fromQueryString": {
+		Params: []lib.Param{lib.Arg("queryString", lang.StringType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Parses a URL query string into a map.",
+	},
+	"getPath": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("path", lang.StringType), lib.OptionalArg("default", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Retrieves a value using dot notation path.",
+	},
+	"setPath": {
+		Params:          []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("path", lang.StringType), lib.Arg("value", lang.AnyType)},
+		Result:          lang.MapType,
+		Pure:            true,
+		Doc:             "Sets a value using dot notation path, creating intermediate objects as needed.",
+		ContextFunction: setPathContext,
+	},
+	"hasPath": {
+		Params: []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("path", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a dot notation path exists in the map.",
+	},
+	"deletePath": {
+		Params:          []lib.Param{lib.Arg("map", lang.MapType), lib.Arg("path", lang.StringType)},
+		Result:          lang.MapType,
+		Pure:            true,
+		Doc:             "Removes a value at a dot notation path.",
+		ContextFunction: deletePathContext,
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(mapFunctions, mapSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range mapFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -16,6 +16,8 @@
 package maps
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -1232,3 +1234,40 @@ func containsMiddle(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLimits(t *testing.T) {
+	limited := lang.WithLimits(context.Background(), lang.Limits{MaxCollectionSize: 2})
+	a := lang.MapValue{"a": lang.NumberValue(1)}
+	b := lang.MapValue{"b": lang.NumberValue(2)}
+	c := lang.MapValue{"c": lang.NumberValue(3)}
+
+	tests := []struct {
+		name  string
+		fn    lang.ContextFunction
+		args  []lang.Value
+		fails bool
+	}{
+		{"merge", mergeContext, []lang.Value{a, b, a}, false},
+		{"merge too large", mergeContext, []lang.Value{a, b, c}, true},
+		{"mergeDeep", mergeDeepContext, []lang.Value{a, b, b}, false},
+		{"mergeDeep too large", mergeDeepContext, []lang.Value{a, b, c}, true},
+		{"mergeDeep nested too large", mergeDeepContext, []lang.Value{lang.MapValue{"a": lang.ListValue{nil, nil}}}, true},
+		{"set", setContext, []lang.Value{a, lang.StringValue("b"), lang.NumberValue(2)}, false},
+		{"set nested too large", setContext, []lang.Value{a, lang.StringValue("b"), lang.ListValue{nil}}, true},
+		{"setPath too large", setPathContext, []lang.Value{a, lang.StringValue("b.c"), lang.ListValue{nil}}, true},
+		{"deletePath too large", deletePathContext, []lang.Value{lang.MapValue{"a": b, "b": b}, lang.StringValue("a.b")}, true},
+		{"fromList too large", fromListContext, []lang.Value{lang.ListValue{lang.ListValue{lang.StringValue("a"), nil}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.fn(limited, tt.args)
+			if tt.fails != errors.Is(err, lang.ErrLimitExceeded) {
+				t.Errorf("Expected the limit to be exceeded: %v, got %v", tt.fails, err)
+			}
+			if !tt.fails && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -19,9 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 	"sort"
-	"time"
 
 	"github.com/vedadiyan/exql/lang"
 	"github.com/vedadiyan/exql/lib"
@@ -738,7 +737,7 @@ func Random() (string, lang.Function) {
 			if max <= 0 {
 				return lang.NumberValue(0), nil
 			}
-			return lang.NumberValue(float64(rand.Intn(max))), nil
+			return lang.NumberValue(float64(rand.IntN(max))), nil
 		}
 		if len(args) == 2 {
 			minVal, err := lib.ToNumber(args[0])
@@ -754,26 +753,27 @@ func Random() (string, lang.Function) {
 			if max <= min {
 				return lang.NumberValue(float64(min)), nil
 			}
-			return lang.NumberValue(float64(rand.Intn(max-min) + min)), nil
+			return lang.NumberValue(float64(rand.IntN(max-min) + min)), nil
 		}
 		return nil, lib.ArgumentErrorMultiRange(name, []int{0, 1, 2})
 	}
 	return name, fn
 }
 
+// RandomSeed validates its optional seed and otherwise does nothing.
+// Random numbers come from the goroutine-safe generator of math/rand/v2,
+// which cannot be seeded, because a seed shared by every evaluation in the
+// process would let concurrent evaluations interfere with each other.
+//
+// Deprecated: seeding is no longer supported.
 func RandomSeed() (string, lang.Function) {
 	name := "randomSeed"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) == 0 {
-			rand.Seed(time.Now().UnixNano())
-		} else if len(args) == 1 {
-			seedVal, err := lib.ToNumber(args[0])
-			if err != nil {
+		if len(args) == 1 {
+			if _, err := lib.ToNumber(args[0]); err != nil {
 				return nil, fmt.Errorf("%s: %w", name, err)
 			}
-			seed := int64(seedVal)
-			rand.Seed(seed)
-		} else {
+		} else if len(args) != 0 {
 			return nil, lib.ArgumentErrorRange(name, 0, 1)
 		}
 		return lang.BoolValue(true), nil
@@ -1026,11 +1026,306 @@ var mathFunctions = []func() (string, lang.Function){
 	Phi,
 }
 
+var mathSignatures = map[string]lib.Descriptor{
+	"abs": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the absolute value of a number.",
+	},
+	"sign": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the sign of a number.",
+	},
+	"max": {
+		Params:   []lib.Param{lib.Arg("numbers", lang.NumberType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Returns the largest of the given numbers.",
+	},
+	"min": {
+		Params:   []lib.Param{lib.Arg("numbers", lang.NumberType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Returns the smallest of the given numbers.",
+	},
+	"clamp": {
+		Params: []lib.Param{lib.Arg("value", lang.NumberType), lib.Arg("min", lang.NumberType), lib.Arg("max", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Constrains a value within a range.",
+	},
+	"ceil": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Rounds a number up to the nearest integer.",
+	},
+	"floor": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Rounds a number down to the nearest integer.",
+	},
+	"round": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType), lib.OptionalArg("precision", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Rounds a number to the nearest integer or specified decimal places.",
+	},
+	"trunc": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Truncates the decimal part of a number.",
+	},
+	"pow": {
+		Params: []lib.Param{lib.Arg("base", lang.NumberType), lib.Arg("exponent", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Raises a number to a power.",
+	},
+	"sqrt": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the square root of a number.",
+	},
+	"cbrt": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the cube root of a number.",
+	},
+	"exp": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns e raised to the power of the given number.",
+	},
+	"exp2": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns 2 raised to the power of the given number.",
+	},
+	"log": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the natural logarithm (base e) of a number.",
+	},
+	"log10": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the base-10 logarithm of a number.",
+	},
+	"log2": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the base-2 logarithm of a number.",
+	},
+	"sin": {
+		Params: []lib.Param{lib.Arg("radians", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the sine of an angle in radians.",
+	},
+	"cos": {
+		Params: []lib.Param{lib.Arg("radians", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the cosine of an angle in radians.",
+	},
+	"tan": {
+		Params: []lib.Param{lib.Arg("radians", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the tangent of an angle in radians.",
+	},
+	"asin": {
+		Params: []lib.Param{lib.Arg("value", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the arcsine of a value in radians.",
+	},
+	"acos": {
+		Params: []lib.Param{lib.Arg("value", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the arccosine of a value in radians.",
+	},
+	"atan": {
+		Params: []lib.Param{lib.Arg("value", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the arctangent of a value in radians.",
+	},
+	"atan2": {
+		Params: []lib.Param{lib.Arg("y", lang.NumberType), lib.Arg("x", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the angle from the X-axis to a point (x,y) in radians.",
+	},
+	"sinh": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the hyperbolic sine of a number.",
+	},
+	"cosh": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the hyperbolic cosine of a number.",
+	},
+	"tanh": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the hyperbolic tangent of a number.",
+	},
+	"radians": {
+		Params: []lib.Param{lib.Arg("degrees", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts degrees to radians.",
+	},
+	"degrees": {
+		Params: []lib.Param{lib.Arg("radians", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts radians to degrees.",
+	},
+	"sum": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the sum of numbers or arrays.",
+	},
+	"mean": {
+		Params:   []lib.Param{lib.Arg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the arithmetic mean (average).",
+	},
+	"median": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the median (middle value).",
+	},
+	"mode": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Finds the most frequently occurring value.",
+	},
+	"variance": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the sample variance.",
+	},
+	"stddev": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the sample standard deviation.",
+	},
+	"random": {
+		Params: []lib.Param{lib.OptionalArg("min", lang.NumberType), lib.OptionalArg("max", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Generates a random number, an integer when bounds are given.",
+	},
+	"randomSeed": {
+		Params: []lib.Param{lib.OptionalArg("seed", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   false,
+		Doc:    "Deprecated: has no effect, random numbers cannot be seeded.",
+	},
+	"randomFloat": {
+		Params: []lib.Param{lib.OptionalArg("min", lang.NumberType), lib.OptionalArg("max", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Generates a random floating-point number.",
+	},
+	"isNan": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is NaN (Not a Number).",
+	},
+	"isInf": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is infinite.",
+	},
+	"isFinite": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is finite (not NaN or infinite).",
+	},
+	"gcd": {
+		Params:   []lib.Param{lib.Arg("a", lang.NumberType), lib.Arg("b", lang.NumberType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the Greatest Common Divisor.",
+	},
+	"lcm": {
+		Params:   []lib.Param{lib.Arg("a", lang.NumberType), lib.Arg("b", lang.NumberType)},
+		Variadic: true,
+		Result:   lang.NumberType,
+		Pure:     true,
+		Doc:      "Calculates the Least Common Multiple.",
+	},
+	"factorial": {
+		Params: []lib.Param{lib.Arg("number", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Calculates the factorial of a number.",
+	},
+	"pi": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the value of π (pi).",
+	},
+	"e": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the value of e (Euler's number).",
+	},
+	"phi": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the value of φ (golden ratio).",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(mathFunctions, mathSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range mathFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
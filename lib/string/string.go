@@ -16,6 +16,7 @@
 package string
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -60,42 +61,64 @@ func size() (string, lang.Function) {
 func voncat() (string, lang.Function) {
 	name := "concat"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		var result string
-		for i, arg := range args {
-			str, err := lib.ToString(arg)
-			if err != nil {
-				return nil, fmt.Errorf("%s: argument %d %w", name, i, err)
-			}
-			result += string(str)
-		}
-		return lang.StringValue(result), nil
+		return concatContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// concatContext concatenates strings, failing before it allocates a result
+// longer than the limits of ctx allow.
+func concatContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "concat"
+	parts := make([]string, len(args))
+	length := 0.0
+	for i, arg := range args {
+		str, err := lib.ToString(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d %w", name, i, err)
+		}
+		parts[i] = string(str)
+		length += float64(len(str))
+	}
+	if err := lib.CheckString(ctx, name, length); err != nil {
+		return nil, err
+	}
+	return lang.StringValue(strings.Join(parts, "")), nil
+}
+
 func repeat() (string, lang.Function) {
 	name := "repeat"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 2 {
-			return nil, lib.ArgumentError(name, 2)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, fmt.Errorf("%s: string %w", name, err)
-		}
-		countVal, err := lib.ToNumber(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: count %w", name, err)
-		}
-		count := int(countVal)
-		if count < 0 {
-			count = 0
-		}
-		return lang.StringValue(strings.Repeat(string(str), count)), nil
+		return repeatContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// repeatContext repeats a string, failing before it allocates a result
+// longer than the limits of ctx allow.
+func repeatContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "repeat"
+	if len(args) != 2 {
+		return nil, lib.ArgumentError(name, 2)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: string %w", name, err)
+	}
+	countVal, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: count %w", name, err)
+	}
+	count := int(countVal)
+	if count < 0 {
+		count = 0
+	}
+	if err := lib.CheckString(ctx, name, float64(len(str))*countVal); err != nil {
+		return nil, err
+	}
+	return lang.StringValue(strings.Repeat(string(str), count)), nil
+}
+
 func reverse() (string, lang.Function) {
 	name := "reverse"
 	fn := func(args []lang.Value) (lang.Value, error) {
@@ -276,126 +299,156 @@ func trimRight() (string, lang.Function) {
 func padLeft() (string, lang.Function) {
 	name := "padLeft"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 2 || len(args) > 3 {
-			return nil, fmt.Errorf("%s: expected 2 or 3 arguments", name)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, lib.StringError(name, args[0])
-		}
-		totalLenVal, err := lib.ToNumber(args[1])
+		return padLeftContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// padLeftContext pads a string on the left, failing before it allocates a
+// result longer than the limits of ctx allow.
+func padLeftContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "padLeft"
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("%s: expected 2 or 3 arguments", name)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, lib.StringError(name, args[0])
+	}
+	totalLenVal, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: total length %w", name, err)
+	}
+	totalLen := int(totalLenVal)
+	padChar := " "
+	if len(args) == 3 {
+		padCharVal, err := lib.ToString(args[2])
 		if err != nil {
-			return nil, fmt.Errorf("%s: total length %w", name, err)
+			return nil, fmt.Errorf("%s: pad character %w", name, err)
 		}
-		totalLen := int(totalLenVal)
-		padChar := " "
-		if len(args) == 3 {
-			padCharVal, err := lib.ToString(args[2])
-			if err != nil {
-				return nil, fmt.Errorf("%s: pad character %w", name, err)
-			}
-			padChar = string(padCharVal)
-			if len(padChar) == 0 {
-				padChar = " "
-			}
+		padChar = string(padCharVal)
+		if len(padChar) == 0 {
+			padChar = " "
 		}
+	}
 
-		s := string(str)
-		currentLen := utf8.RuneCountInString(s)
-		if totalLen <= currentLen {
-			return lang.StringValue(s), nil
-		}
+	s := string(str)
+	currentLen := utf8.RuneCountInString(s)
+	if totalLen <= currentLen {
+		return lang.StringValue(s), nil
+	}
 
-		padLen := totalLen - currentLen
-		padding := strings.Repeat(padChar, padLen)
-		return lang.StringValue(padding + s), nil
+	padLen := totalLen - currentLen
+	if err := lib.CheckString(ctx, name, float64(len(s))+float64(padLen)*float64(len(padChar))); err != nil {
+		return nil, err
 	}
-	return name, fn
+	padding := strings.Repeat(padChar, padLen)
+	return lang.StringValue(padding + s), nil
 }
 
 func padRight() (string, lang.Function) {
 	name := "padRight"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 2 || len(args) > 3 {
-			return nil, fmt.Errorf("%s: expected 2 or 3 arguments", name)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, lib.StringError(name, args[0])
-		}
-		totalLenVal, err := lib.ToNumber(args[1])
+		return padRightContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// padRightContext pads a string on the right, failing before it allocates a
+// result longer than the limits of ctx allow.
+func padRightContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "padRight"
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("%s: expected 2 or 3 arguments", name)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, lib.StringError(name, args[0])
+	}
+	totalLenVal, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: total length %w", name, err)
+	}
+	totalLen := int(totalLenVal)
+	padChar := " "
+	if len(args) == 3 {
+		padCharVal, err := lib.ToString(args[2])
 		if err != nil {
-			return nil, fmt.Errorf("%s: total length %w", name, err)
+			return nil, fmt.Errorf("%s: pad character %w", name, err)
 		}
-		totalLen := int(totalLenVal)
-		padChar := " "
-		if len(args) == 3 {
-			padCharVal, err := lib.ToString(args[2])
-			if err != nil {
-				return nil, fmt.Errorf("%s: pad character %w", name, err)
-			}
-			padChar = string(padCharVal)
-			if len(padChar) == 0 {
-				padChar = " "
-			}
+		padChar = string(padCharVal)
+		if len(padChar) == 0 {
+			padChar = " "
 		}
+	}
 
-		s := string(str)
-		currentLen := utf8.RuneCountInString(s)
-		if totalLen <= currentLen {
-			return lang.StringValue(s), nil
-		}
+	s := string(str)
+	currentLen := utf8.RuneCountInString(s)
+	if totalLen <= currentLen {
+		return lang.StringValue(s), nil
+	}
 
-		padLen := totalLen - currentLen
-		padding := strings.Repeat(padChar, padLen)
-		return lang.StringValue(s + padding), nil
+	padLen := totalLen - currentLen
+	if err := lib.CheckString(ctx, name, float64(len(s))+float64(padLen)*float64(len(padChar))); err != nil {
+		return nil, err
 	}
-	return name, fn
+	padding := strings.Repeat(padChar, padLen)
+	return lang.StringValue(s + padding), nil
 }
 
 func padCenter() (string, lang.Function) {
 	name := "padCenter"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 2 || len(args) > 3 {
-			return nil, fmt.Errorf("%s: expected 2 or 3 arguments", name)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, lib.StringError(name, args[0])
-		}
-		totalLenVal, err := lib.ToNumber(args[1])
+		return padCenterContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// padCenterContext pads a string on both sides, failing before it allocates
+// a result longer than the limits of ctx allow.
+func padCenterContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "padCenter"
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("%s: expected 2 or 3 arguments", name)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, lib.StringError(name, args[0])
+	}
+	totalLenVal, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: total length %w", name, err)
+	}
+	totalLen := int(totalLenVal)
+	padChar := " "
+	if len(args) == 3 {
+		padCharVal, err := lib.ToString(args[2])
 		if err != nil {
-			return nil, fmt.Errorf("%s: total length %w", name, err)
+			return nil, fmt.Errorf("%s: pad character %w", name, err)
 		}
-		totalLen := int(totalLenVal)
-		padChar := " "
-		if len(args) == 3 {
-			padCharVal, err := lib.ToString(args[2])
-			if err != nil {
-				return nil, fmt.Errorf("%s: pad character %w", name, err)
-			}
-			padChar = string(padCharVal)
-			if len(padChar) == 0 {
-				padChar = " "
-			}
+		padChar = string(padCharVal)
+		if len(padChar) == 0 {
+			padChar = " "
 		}
+	}
 
-		s := string(str)
-		currentLen := utf8.RuneCountInString(s)
-		if totalLen <= currentLen {
-			return lang.StringValue(s), nil
-		}
+	s := string(str)
+	currentLen := utf8.RuneCountInString(s)
+	if totalLen <= currentLen {
+		return lang.StringValue(s), nil
+	}
 
-		padLen := totalLen - currentLen
-		leftPad := padLen / 2
-		rightPad := padLen - leftPad
+	padLen := totalLen - currentLen
+	if err := lib.CheckString(ctx, name, float64(len(s))+float64(padLen)*float64(len(padChar))); err != nil {
+		return nil, err
+	}
+	leftPad := padLen / 2
+	rightPad := padLen - leftPad
 
-		leftPadding := strings.Repeat(padChar, leftPad)
-		rightPadding := strings.Repeat(padChar, rightPad)
+	leftPadding := strings.Repeat(padChar, leftPad)
+	rightPadding := strings.Repeat(padChar, rightPad)
 
-		return lang.StringValue(leftPadding + s + rightPadding), nil
-	}
-	return name, fn
+	return lang.StringValue(leftPadding + s + rightPadding), nil
 }
 
 func substr() (string, lang.Function) {
@@ -637,59 +690,89 @@ func lastIndexOf() (string, lang.Function) {
 func replace() (string, lang.Function) {
 	name := "replace"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) < 3 || len(args) > 4 {
-			return nil, fmt.Errorf("%s: expected 3 or 4 arguments", name)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, lib.StringError(name, args[0])
-		}
-		old, err := lib.ToString(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: old %w", name, err)
-		}
-		new, err := lib.ToString(args[2])
+		return replaceContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// replaceContext replaces occurrences of a substring, failing before it
+// allocates a result longer than the limits of ctx allow.
+func replaceContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "replace"
+	if len(args) < 3 || len(args) > 4 {
+		return nil, fmt.Errorf("%s: expected 3 or 4 arguments", name)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, lib.StringError(name, args[0])
+	}
+	old, err := lib.ToString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: old %w", name, err)
+	}
+	new, err := lib.ToString(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s: new %w", name, err)
+	}
+	n := -1
+
+	if len(args) == 4 {
+		nVal, err := lib.ToNumber(args[3])
 		if err != nil {
-			return nil, fmt.Errorf("%s: new %w", name, err)
+			return nil, fmt.Errorf("%s: count %w", name, err)
 		}
-		n := -1
+		n = int(nVal)
+	}
 
-		if len(args) == 4 {
-			nVal, err := lib.ToNumber(args[3])
-			if err != nil {
-				return nil, fmt.Errorf("%s: count %w", name, err)
-			}
-			n = int(nVal)
-		}
+	if err := lib.CheckString(ctx, name, replacedLength(string(str), string(old), string(new), n)); err != nil {
+		return nil, err
+	}
+	return lang.StringValue(strings.Replace(string(str), string(old), string(new), n)), nil
+}
 
-		return lang.StringValue(strings.Replace(string(str), string(old), string(new), n)), nil
+// replacedLength returns the length of str with the first n occurrences of
+// old, or all of them if n is negative, replaced by new.
+func replacedLength(str, old, new string, n int) float64 {
+	count := strings.Count(str, old)
+	if n >= 0 && n < count {
+		count = n
 	}
-	return name, fn
+	return float64(len(str)) + float64(count)*float64(len(new)-len(old))
 }
 
 func replaceAll() (string, lang.Function) {
 	name := "replaceAll"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, lib.StringError(name, args[0])
-		}
-		old, err := lib.ToString(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: old %w", name, err)
-		}
-		new, err := lib.ToString(args[2])
-		if err != nil {
-			return nil, fmt.Errorf("%s: new %w", name, err)
-		}
-		return lang.StringValue(strings.ReplaceAll(string(str), string(old), string(new))), nil
+		return replaceAllContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// replaceAllContext replaces all occurrences of a substring, failing before
+// it allocates a result longer than the limits of ctx allow.
+func replaceAllContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "replaceAll"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, lib.StringError(name, args[0])
+	}
+	old, err := lib.ToString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: old %w", name, err)
+	}
+	new, err := lib.ToString(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s: new %w", name, err)
+	}
+	if err := lib.CheckString(ctx, name, replacedLength(string(str), string(old), string(new), -1)); err != nil {
+		return nil, err
+	}
+	return lang.StringValue(strings.ReplaceAll(string(str), string(old), string(new))), nil
+}
+
 func split() (string, lang.Function) {
 	name := "split"
 	fn := func(args []lang.Value) (lang.Value, error) {
@@ -733,29 +816,41 @@ func split() (string, lang.Function) {
 func join() (string, lang.Function) {
 	name := "join"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 2 {
-			return nil, lib.ArgumentError(name, 2)
-		}
-		sep, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, fmt.Errorf("%s: separator %w", name, err)
-		}
-		list, ok := args[1].(lang.ListValue)
-		if !ok {
-			return nil, lib.ListError(name, args[1])
-		}
+		return joinContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		parts := make([]string, len(list))
-		for i, item := range list {
-			str, err := lib.ToString(item)
-			if err != nil {
-				return nil, fmt.Errorf("%s: list item %d %w", name, i, err)
-			}
-			parts[i] = string(str)
+// joinContext joins a list of strings, failing before it allocates a result
+// longer than the limits of ctx allow.
+func joinContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "join"
+	if len(args) != 2 {
+		return nil, lib.ArgumentError(name, 2)
+	}
+	sep, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: separator %w", name, err)
+	}
+	list, ok := args[1].(lang.ListValue)
+	if !ok {
+		return nil, lib.ListError(name, args[1])
+	}
+
+	parts := make([]string, len(list))
+	length := float64(len(sep)) * float64(len(list)-1)
+	for i, item := range list {
+		str, err := lib.ToString(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: list item %d %w", name, i, err)
 		}
-		return lang.StringValue(strings.Join(parts, string(sep))), nil
+		parts[i] = string(str)
+		length += float64(len(str))
 	}
-	return name, fn
+	if err := lib.CheckString(ctx, name, length); err != nil {
+		return nil, err
+	}
+	return lang.StringValue(strings.Join(parts, string(sep))), nil
 }
 
 func lines() (string, lang.Function) {
@@ -864,30 +959,48 @@ func findAll() (string, lang.Function) {
 func replaceRegex() (string, lang.Function) {
 	name := "replaceRegex"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		str, err := lib.ToString(args[0])
-		if err != nil {
-			return nil, lib.StringError(name, args[0])
-		}
-		pattern, err := lib.ToString(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: pattern %w", name, err)
-		}
-		replacement, err := lib.ToString(args[2])
-		if err != nil {
-			return nil, fmt.Errorf("%s: replacement %w", name, err)
-		}
+		return replaceRegexContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		re, err := regexp.Compile(string(pattern))
-		if err != nil {
-			return nil, fmt.Errorf("%s: invalid regex pattern: %w", name, err)
-		}
+// replaceRegexContext replaces the matches of a pattern, failing as soon as
+// the result grows longer than the limits of ctx allow.
+func replaceRegexContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "replaceRegex"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	str, err := lib.ToString(args[0])
+	if err != nil {
+		return nil, lib.StringError(name, args[0])
+	}
+	pattern, err := lib.ToString(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: pattern %w", name, err)
+	}
+	replacement, err := lib.ToString(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s: replacement %w", name, err)
+	}
 
-		return lang.StringValue(re.ReplaceAllString(string(str), string(replacement))), nil
+	re, err := regexp.Compile(string(pattern))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid regex pattern: %w", name, err)
 	}
-	return name, fn
+
+	var result []byte
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(string(str), -1) {
+		result = append(result, str[last:match[0]]...)
+		result = re.ExpandString(result, string(replacement), string(str), match)
+		if err := lib.CheckString(ctx, name, float64(len(result))); err != nil {
+			return nil, err
+		}
+		last = match[1]
+	}
+	result = append(result, str[last:]...)
+	return lang.StringValue(result), nil
 }
 
 func charAt() (string, lang.Function) {
@@ -1159,11 +1272,283 @@ var stringFunctions = []func() (string, lang.Function){
 	toNumber,
 }
 
+var stringSignatures = map[string]lib.Descriptor{
+	"len": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the number of Unicode characters in a string.",
+	},
+	"size": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the number of bytes in a string.",
+	},
+	"concat": {
+		Params:          []lib.Param{lib.OptionalArg("strings", lang.AnyType)},
+		Variadic:        true,
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Concatenates multiple strings together.",
+		ContextFunction: concatContext,
+	},
+	"repeat": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("count", lang.NumberType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Repeats a string a specified number of times.",
+		ContextFunction: repeatContext,
+	},
+	"reverse": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Reverses the order of characters in a string.",
+	},
+	"upper": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a string to uppercase.",
+	},
+	"lower": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a string to lowercase.",
+	},
+	"title": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a string to title case (first letter of each word capitalized).",
+	},
+	"capitalize": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Capitalizes the first letter and lowercases the rest.",
+	},
+	"swapCase": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Swaps the case of each character.",
+	},
+	"trim": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.OptionalArg("cutset", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Removes whitespace or specified characters from both ends.",
+	},
+	"trimLeft": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.OptionalArg("cutset", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Removes whitespace or specified characters from the left end.",
+	},
+	"trimRight": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.OptionalArg("cutset", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Removes whitespace or specified characters from the right end.",
+	},
+	"padLeft": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("totalLength", lang.NumberType), lib.OptionalArg("padChar", lang.StringType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Pads a string on the left to reach a target length.",
+		ContextFunction: padLeftContext,
+	},
+	"padRight": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("totalLength", lang.NumberType), lib.OptionalArg("padChar", lang.StringType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Pads a string on the right to reach a target length.",
+		ContextFunction: padRightContext,
+	},
+	"padCenter": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("totalLength", lang.NumberType), lib.OptionalArg("padChar", lang.StringType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Centers a string by padding both sides to reach a target length.",
+		ContextFunction: padCenterContext,
+	},
+	"substr": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("start", lang.NumberType), lib.OptionalArg("length", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts a substring starting at a position.",
+	},
+	"left": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("count", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Gets the leftmost N characters.",
+	},
+	"right": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("count", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Gets the rightmost N characters.",
+	},
+	"contains": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("substring", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains a substring.",
+	},
+	"startswith": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("prefix", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string starts with a prefix.",
+	},
+	"endswith": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("suffix", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string ends with a suffix.",
+	},
+	"indexof": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("substring", lang.StringType), lib.OptionalArg("start", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Finds the first index of a substring.",
+	},
+	"lastIndexOf": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("substring", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Finds the last index of a substring.",
+	},
+	"replace": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("old", lang.StringType), lib.Arg("new", lang.StringType), lib.OptionalArg("count", lang.NumberType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Replaces occurrences of a substring.",
+		ContextFunction: replaceContext,
+	},
+	"replaceAll": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("old", lang.StringType), lib.Arg("new", lang.StringType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Replaces all occurrences of a substring.",
+		ContextFunction: replaceAllContext,
+	},
+	"split": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("separator", lang.StringType), lib.OptionalArg("count", lang.NumberType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Splits a string into an array by a separator.",
+	},
+	"join": {
+		Params:          []lib.Param{lib.Arg("separator", lang.StringType), lib.Arg("list", lang.ListType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Joins an array of strings with a separator.",
+		ContextFunction: joinContext,
+	},
+	"lines": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Splits a string into lines.",
+	},
+	"fields": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Splits a string by whitespace into words.",
+	},
+	"match": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("pattern", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Tests if a string matches a regular expression pattern.",
+	},
+	"findAll": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("pattern", lang.StringType), lib.OptionalArg("count", lang.NumberType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Finds all matches of a regular expression pattern.",
+	},
+	"replaceRegex": {
+		Params:          []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("pattern", lang.StringType), lib.Arg("replacement", lang.StringType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Replaces text matching a regular expression pattern.",
+		ContextFunction: replaceRegexContext,
+	},
+	"charAt": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("index", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Gets the character at a specific index.",
+	},
+	"charCode": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType), lib.Arg("index", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the Unicode code point of a character at an index.",
+	},
+	"fromCharCode": {
+		Params:   []lib.Param{lib.Arg("codes", lang.NumberType)},
+		Variadic: true,
+		Result:   lang.StringType,
+		Pure:     true,
+		Doc:      "Creates a string from Unicode code points.",
+	},
+	"isEmpty": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is empty or contains only whitespace.",
+	},
+	"isNumeric": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string represents a valid number.",
+	},
+	"isAlpha": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only alphabetic characters.",
+	},
+	"isAlphanumeric": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only alphanumeric characters.",
+	},
+	"isSpace": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only whitespace characters.",
+	},
+	"toString": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a value to its string representation.",
+	},
+	"toNumber": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts a string to a number.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(stringFunctions, stringSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range stringFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
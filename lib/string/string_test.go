@@ -16,6 +16,8 @@
 package string
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/vedadiyan/exql/lang"
@@ -1583,3 +1585,47 @@ func TestFields(t *testing.T) {
 		})
 	}
 }
+
+func TestLimits(t *testing.T) {
+	limited := lang.WithLimits(context.Background(), lang.Limits{MaxStringLength: 10})
+	list := lang.ListValue{lang.StringValue("aaaa"), lang.StringValue("bbbb"), lang.StringValue("cc")}
+
+	tests := []struct {
+		name  string
+		fn    lang.ContextFunction
+		args  []lang.Value
+		fails bool
+	}{
+		{"repeat", repeatContext, []lang.Value{lang.StringValue("ab"), lang.NumberValue(5)}, false},
+		{"repeat too long", repeatContext, []lang.Value{lang.StringValue("ab"), lang.NumberValue(6)}, true},
+		{"repeat overflowing", repeatContext, []lang.Value{lang.StringValue("ab"), lang.NumberValue(1e300)}, true},
+		{"padLeft too long", padLeftContext, []lang.Value{lang.StringValue("ab"), lang.NumberValue(11)}, true},
+		{"padRight", padRightContext, []lang.Value{lang.StringValue("ab"), lang.NumberValue(10)}, false},
+		{"padCenter too long", padCenterContext, []lang.Value{lang.StringValue("ab"), lang.NumberValue(10), lang.StringValue("xy")}, true},
+		{"replace too long", replaceContext, []lang.Value{lang.StringValue("aaa"), lang.StringValue("a"), lang.StringValue("xxxx")}, true},
+		{"replace count", replaceContext, []lang.Value{lang.StringValue("aaa"), lang.StringValue("a"), lang.StringValue("xxxx"), lang.NumberValue(1)}, false},
+		{"replaceAll too long", replaceAllContext, []lang.Value{lang.StringValue("aaaaa"), lang.StringValue("a"), lang.StringValue("bbb")}, true},
+		{"join too long", joinContext, []lang.Value{lang.StringValue(","), list}, true},
+		{"join", joinContext, []lang.Value{lang.StringValue(""), list}, false},
+		{"concat", concatContext, []lang.Value{lang.StringValue("aaaa"), lang.StringValue("bbbbbb")}, false},
+		{"concat too long", concatContext, append(list, lang.StringValue("d")), true},
+		{"replaceRegex", replaceRegexContext, []lang.Value{lang.StringValue("aaaaa"), lang.StringValue("a"), lang.StringValue("$0$0")}, false},
+		{"replaceRegex too long", replaceRegexContext, []lang.Value{lang.StringValue("aaaaa"), lang.StringValue("a"), lang.StringValue("$0$0$0")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.fn(limited, tt.args)
+			if tt.fails != errors.Is(err, lang.ErrLimitExceeded) {
+				t.Errorf("Expected the limit to be exceeded: %v, got %v", tt.fails, err)
+			}
+			if !tt.fails && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	if _, err := repeatContext(context.Background(), []lang.Value{lang.StringValue("ab"), lang.NumberValue(6)}); err != nil {
+		t.Errorf("Unexpected error without limits: %v", err)
+	}
+}
@@ -16,8 +16,10 @@
 package time
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -631,21 +633,37 @@ func toFromTimezone() (string, lang.Function) {
 func sleep() (string, lang.Function) {
 	name := "sleep"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 1 {
-			return nil, lib.ArgumentError(name, 1)
-		}
-		seconds, err := lib.ToNumber(args[0])
-		if err != nil {
-			return nil, fmt.Errorf("%s: seconds %w", name, err)
+		return sleepContext(context.Background(), args)
+	}
+	return name, fn
+}
+
+// sleepContext sleeps until the duration has passed or ctx is done, failing
+// with the cause of ctx being done.
+func sleepContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "sleep"
+	if len(args) != 1 {
+		return nil, lib.ArgumentError(name, 1)
+	}
+	seconds, err := lib.ToNumber(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: seconds %w", name, err)
+	}
+	if seconds < 0 {
+		return nil, errors.New("sleep: seconds cannot be negative")
+	}
+	timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		{
+			return lang.BoolValue(true), nil
 		}
-		if seconds < 0 {
-			return nil, errors.New("sleep: seconds cannot be negative")
+	case <-ctx.Done():
+		{
+			return nil, fmt.Errorf("%s: %w", name, context.Cause(ctx))
 		}
-		duration := time.Duration(seconds * float64(time.Second))
-		time.Sleep(duration)
-		return lang.BoolValue(true), nil
 	}
-	return name, fn
 }
 
 func validate() (string, lang.Function) {
@@ -675,36 +693,49 @@ func validate() (string, lang.Function) {
 func rrange() (string, lang.Function) {
 	name := "range"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 3 {
-			return nil, lib.ArgumentError(name, 3)
-		}
-		start, err := lib.ToNumber(args[0])
-		if err != nil {
-			return nil, fmt.Errorf("%s: start %w", name, err)
-		}
-		end, err := lib.ToNumber(args[1])
-		if err != nil {
-			return nil, fmt.Errorf("%s: end %w", name, err)
-		}
-		step, err := lib.ToNumber(args[2])
-		if err != nil {
-			return nil, fmt.Errorf("%s: step %w", name, err)
-		}
-		if step <= 0 {
-			return nil, errors.New("range: step must be positive")
-		}
-		if start > end {
-			return nil, errors.New("range: start must be less than or equal to end")
-		}
-		var result lang.ListValue
-		for current := start; current <= end; current += step {
-			result = append(result, lang.NumberValue(current))
-		}
-		return result, nil
+		return rangeContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// rangeContext builds a range of timestamps, failing before it allocates a
+// list longer than the limits of ctx allow.
+func rangeContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "range"
+	if len(args) != 3 {
+		return nil, lib.ArgumentError(name, 3)
+	}
+	start, err := lib.ToNumber(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: start %w", name, err)
+	}
+	end, err := lib.ToNumber(args[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: end %w", name, err)
+	}
+	step, err := lib.ToNumber(args[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s: step %w", name, err)
+	}
+	if step <= 0 {
+		return nil, errors.New("range: step must be positive")
+	}
+	if start > end {
+		return nil, errors.New("range: start must be less than or equal to end")
+	}
+	if err := lib.CheckCollection(ctx, name, math.Floor((end-start)/step)+1); err != nil {
+		return nil, err
+	}
+	var result lang.ListValue
+	for current := start; current <= end; current += step {
+		if err := lib.Cancelled(ctx, name, len(result)); err != nil {
+			return nil, err
+		}
+		result = append(result, lang.NumberValue(current))
+	}
+	return result, nil
+}
+
 func convertTimeLayout(layout string) string {
 	layout = strings.ReplaceAll(layout, "YYYY", "2006")
 	layout = strings.ReplaceAll(layout, "YY", "06")
@@ -783,11 +814,232 @@ var timeFunctions = []func() (string, lang.Function){
 	rrange,
 }
 
+var timeSignatures = map[string]lib.Descriptor{
+	"now": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Returns the current Unix timestamp in seconds.",
+	},
+	"nowMillis": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Returns the current Unix timestamp in milliseconds.",
+	},
+	"nowNanos": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Returns the current Unix timestamp in nanoseconds.",
+	},
+	"parse": {
+		Params: []lib.Param{lib.Arg("timeString", lang.StringType), lib.OptionalArg("layout", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Parses a time string into a Unix timestamp.",
+	},
+	"format": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.OptionalArg("layout", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Formats a Unix timestamp into a string.",
+	},
+	"add": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.Arg("seconds", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Adds seconds to a timestamp.",
+	},
+	"addDays": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.Arg("days", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Adds days to a timestamp.",
+	},
+	"addHours": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.Arg("hours", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Adds hours to a timestamp.",
+	},
+	"addMinutes": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.Arg("minutes", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Adds minutes to a timestamp.",
+	},
+	"diff": {
+		Params: []lib.Param{lib.Arg("timestamp1", lang.NumberType), lib.Arg("timestamp2", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Calculates the difference between two timestamps in seconds.",
+	},
+	"diffDays": {
+		Params: []lib.Param{lib.Arg("timestamp1", lang.NumberType), lib.Arg("timestamp2", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Calculates the difference between two timestamps in days.",
+	},
+	"diffHours": {
+		Params: []lib.Param{lib.Arg("timestamp1", lang.NumberType), lib.Arg("timestamp2", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Calculates the difference between two timestamps in hours.",
+	},
+	"diffMinutes": {
+		Params: []lib.Param{lib.Arg("timestamp1", lang.NumberType), lib.Arg("timestamp2", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Calculates the difference between two timestamps in minutes.",
+	},
+	"year": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the year from a timestamp.",
+	},
+	"month": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the month from a timestamp.",
+	},
+	"day": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the day of month from a timestamp.",
+	},
+	"hour": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the hour from a timestamp.",
+	},
+	"minute": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the minute from a timestamp.",
+	},
+	"second": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the second from a timestamp.",
+	},
+	"weekday": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the day of the week from a timestamp.",
+	},
+	"yearday": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the day of the year from a timestamp.",
+	},
+	"week": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the ISO week number from a timestamp.",
+	},
+	"startOfDay": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the timestamp for the start of the day (00:00:00).",
+	},
+	"endOfDay": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the timestamp for the end of the day (23:59:59).",
+	},
+	"startOfWeek": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the timestamp for the start of the week (Monday 00:00:00).",
+	},
+	"startOfMonth": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the timestamp for the start of the month (1st day 00:00:00).",
+	},
+	"startOfYear": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Gets the timestamp for the start of the year (January 1st 00:00:00).",
+	},
+	"isWeekend": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a timestamp falls on a weekend.",
+	},
+	"isLeapYear": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if the year of a timestamp is a leap year.",
+	},
+	"daysInMonth": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Returns the number of days in the month of a timestamp.",
+	},
+	"age": {
+		Params: []lib.Param{lib.Arg("birthTimestamp", lang.NumberType), lib.OptionalArg("currentTimestamp", lang.NumberType)},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Calculates age in years between two timestamps.",
+	},
+	"toTimezone": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.Arg("timezone", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts a UTC timestamp to a specific timezone.",
+	},
+	"fromTimezone": {
+		Params: []lib.Param{lib.Arg("timestamp", lang.NumberType), lib.Arg("timezone", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts a timestamp from a specific timezone to UTC.",
+	},
+	"sleep": {
+		Params:          []lib.Param{lib.Arg("seconds", lang.NumberType)},
+		Result:          lang.BoolType,
+		Pure:            false,
+		Doc:             "Pauses execution for a specified number of seconds, or until the evaluation is cancelled.",
+		ContextFunction: sleepContext,
+	},
+	"validate": {
+		Params: []lib.Param{lib.Arg("timeString", lang.StringType), lib.OptionalArg("layout", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string can be parsed as a valid time.",
+	},
+	"range": {
+		Params:          []lib.Param{lib.Arg("start", lang.NumberType), lib.Arg("end", lang.NumberType), lib.Arg("step", lang.NumberType)},
+		Result:          lang.ListType,
+		Pure:            true,
+		Doc:             "Creates a range of timestamps with specified step.",
+		ContextFunction: rangeContext,
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(timeFunctions, timeSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range timeFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
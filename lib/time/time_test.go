@@ -16,6 +16,8 @@
 package time
 
 import (
+	"context"
+	"errors"
 	"math"
 	"testing"
 	"time"
@@ -1046,6 +1048,32 @@ func TestTimezone(t *testing.T) {
 	}
 }
 
+func TestSleepContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := sleepContext(ctx, []lang.Value{lang.NumberValue(10)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the sleep to stop at the deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Sleep was not cancelled, took %v", elapsed)
+	}
+
+	cause := errors.New("too slow")
+	ctx, cancel = context.WithTimeoutCause(context.Background(), time.Millisecond, cause)
+	defer cancel()
+	if _, err := sleepContext(ctx, []lang.Value{lang.NumberValue(10)}); !errors.Is(err, cause) {
+		t.Errorf("Expected the sleep to fail with the cause of the deadline, got %v", err)
+	}
+
+	result, err := sleepContext(context.Background(), []lang.Value{lang.NumberValue(0.001)})
+	if err != nil || result != lang.BoolValue(true) {
+		t.Errorf("Expected true, got %v (%v)", result, err)
+	}
+}
+
 func TestSleep(t *testing.T) {
 	_, fn := sleep()
 
@@ -947,11 +947,278 @@ var typeFunctions = []func() (string, lang.Function){
 	areStrictEqual,
 }
 
+var typeSignatures = map[string]lib.Descriptor{
+	"type": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Returns the type of a value as a string.",
+	},
+	"isNull": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is null.",
+	},
+	"isDefined": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is defined (not null).",
+	},
+	"isEmpty": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is empty (null, empty string, empty collection, zero, false).",
+	},
+	"isNotEmpty": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is not empty (opposite of `isEmpty`).",
+	},
+	"isBool": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is a boolean.",
+	},
+	"isNumber": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is a number.",
+	},
+	"isString": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is a string.",
+	},
+	"isList": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is a list/array.",
+	},
+	"isMap": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is a map/object.",
+	},
+	"isArray": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Same as isList.",
+	},
+	"isObject": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Same as isMap.",
+	},
+	"isInteger": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is an integer (whole number).",
+	},
+	"isFloat": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value is a floating-point number.",
+	},
+	"isPositive": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number is positive (> 0).",
+	},
+	"isNegative": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number is negative (< 0).",
+	},
+	"isZero": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number equals zero.",
+	},
+	"isEven": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an integer is even.",
+	},
+	"isOdd": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if an integer is odd.",
+	},
+	"isNan": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number is NaN (Not a Number).",
+	},
+	"isInfinite": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number is infinite.",
+	},
+	"isFinite": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number is finite (not NaN or infinite).",
+	},
+	"isNumericString": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string represents a valid number.",
+	},
+	"isAlpha": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only alphabetic characters.",
+	},
+	"isAlphanumeric": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only alphanumeric characters.",
+	},
+	"isDigit": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only digit characters.",
+	},
+	"isLower": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is in lowercase and contains letters.",
+	},
+	"isUpper": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string is in uppercase and contains letters.",
+	},
+	"isWhitespace": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a string contains only whitespace characters.",
+	},
+	"isEmail": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string is a properly formatted email address.",
+	},
+	"isUrl": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string is a properly formatted URL.",
+	},
+	"isIpAddress": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string is a valid IP address (IPv4 or IPv6).",
+	},
+	"isUUID": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string is a properly formatted UUID.",
+	},
+	"isJSON": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string is valid JSON.",
+	},
+	"isBase64": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string is properly formatted Base64.",
+	},
+	"isHex": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Validates if a string contains only hexadecimal characters.",
+	},
+	"hasLength": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value has a length property (string, list, or map).",
+	},
+	"isInRange": {
+		Params: []lib.Param{lib.Arg("value", lang.NumberType), lib.Arg("min", lang.NumberType), lib.Arg("max", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a number falls within a specified range (inclusive).",
+	},
+	"isLengthInRange": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType), lib.Arg("min", lang.NumberType), lib.Arg("max", lang.NumberType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if the length of a string/list/map falls within a range.",
+	},
+	"canConvertToNumber": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value can be converted to a number.",
+	},
+	"canConvertToString": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value can be converted to a string.",
+	},
+	"canConvertToBool": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a value can be converted to a boolean.",
+	},
+	"areEqual": {
+		Params: []lib.Param{lib.Arg("value1", lang.AnyType), lib.Arg("value2", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Performs deep equality comparison between two values.",
+	},
+	"areStrictEqual": {
+		Params: []lib.Param{lib.Arg("value1", lang.AnyType), lib.Arg("value2", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Performs strict equality comparison (same type and value).",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(typeFunctions, typeSignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range typeFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -593,11 +593,117 @@ var functions = []func() (string, lang.Function){
 	clean,
 }
 
+var signatures = map[string]lib.Descriptor{
+	"parse": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Parses a URL string into its component parts.",
+	},
+	"encode": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "URL-encodes a string for safe use in URLs.",
+	},
+	"decode": {
+		Params: []lib.Param{lib.Arg("string", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "URL-decodes a string from URL encoding.",
+	},
+	"host": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts the hostname from a URL (without port).",
+	},
+	"port": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Extracts the port number from a URL.",
+	},
+	"path": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts the path component from a URL.",
+	},
+	"query": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.MapType,
+		Pure:   true,
+		Doc:    "Extracts and parses query parameters from a URL.",
+	},
+	"query_param": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType), lib.Arg("paramName", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts a specific query parameter value.",
+	},
+	"fragment": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts the fragment (hash) component from a URL.",
+	},
+	"scheme": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts the scheme/protocol from a URL.",
+	},
+	"user": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Extracts the username from a URL's user info.",
+	},
+	"build": {
+		Params: []lib.Param{lib.Arg("components", lang.MapType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Constructs a URL from component parts.",
+	},
+	"join": {
+		Params:   []lib.Param{lib.Arg("baseUrl", lang.StringType), lib.Arg("pathSegments", lang.StringType)},
+		Variadic: true,
+		Result:   lang.StringType,
+		Pure:     true,
+		Doc:      "Joins a base URL with additional path segments.",
+	},
+	"is_absolute": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Checks if a URL is absolute (has scheme).",
+	},
+	"path_segments": {
+		Params: []lib.Param{lib.Arg("urlOrPath", lang.StringType)},
+		Result: lang.ListType,
+		Pure:   true,
+		Doc:    "Splits a URL path into individual segments.",
+	},
+	"query_string": {
+		Params: []lib.Param{lib.Arg("params", lang.MapType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a map of parameters to a query string.",
+	},
+	"clean": {
+		Params: []lib.Param{lib.Arg("url", lang.StringType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Cleans and normalizes a URL path by resolving . and .. segments.",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(functions, signatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range functions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
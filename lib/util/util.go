@@ -16,8 +16,9 @@
 package util
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
+	"math/rand/v2"
 	"strconv"
 	"strings"
 	"time"
@@ -229,48 +230,78 @@ func choose() (string, lang.Function) {
 func debugPrint() (string, lang.Function) {
 	name := "debug"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		var parts []string
-		for _, arg := range args {
-			parts = append(parts, formatValueForDebug(arg))
-		}
+		return debugContext(context.Background(), args)
+	}
+	return name, fn
+}
 
-		message := strings.Join(parts, " ")
-		fmt.Println("[DEBUG]", message)
+// debugContext prints values, failing before it formats values larger than
+// the limits of ctx allow.
+func debugContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "debug"
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, args...)); err != nil {
+		return nil, err
+	}
+	var parts []string
+	for _, arg := range args {
+		parts = append(parts, formatValueForDebug(arg))
+	}
 
-		// Return the first argument (or nil if no args)
-		if len(args) > 0 {
-			return args[0], nil
-		}
-		return nil, nil
+	message := strings.Join(parts, " ")
+	fmt.Println("[DEBUG]", message)
+
+	// Return the first argument (or nil if no args)
+	if len(args) > 0 {
+		return args[0], nil
 	}
-	return name, fn
+	return nil, nil
 }
 
 func inspect() (string, lang.Function) {
 	name := "inspect"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		if len(args) != 1 {
-			return nil, lib.ArgumentError(name, 1)
-		}
-
-		return lang.StringValue(formatValueForInspect(args[0])), nil
+		return inspectContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// inspectContext describes a value, failing before it formats a value
+// larger than the limits of ctx allow.
+func inspectContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "inspect"
+	if len(args) != 1 {
+		return nil, lib.ArgumentError(name, 1)
+	}
+
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, args[0])); err != nil {
+		return nil, err
+	}
+	return lang.StringValue(formatValueForInspect(args[0])), nil
+}
+
 func dump() (string, lang.Function) {
 	name := "dump"
 	fn := func(args []lang.Value) (lang.Value, error) {
-		var parts []string
-		for _, arg := range args {
-			parts = append(parts, formatValueForInspect(arg))
-		}
-
-		return lang.StringValue(strings.Join(parts, "\n")), nil
+		return dumpContext(context.Background(), args)
 	}
 	return name, fn
 }
 
+// dumpContext describes values, failing before it formats values larger
+// than the limits of ctx allow.
+func dumpContext(ctx context.Context, args []lang.Value) (lang.Value, error) {
+	name := "dump"
+	if err := lib.CheckCollection(ctx, name, lib.Elements(ctx, args...)); err != nil {
+		return nil, err
+	}
+	var parts []string
+	for _, arg := range args {
+		parts = append(parts, formatValueForInspect(arg))
+	}
+
+	return lang.StringValue(strings.Join(parts, "\n")), nil
+}
+
 // Identity and Pass-through
 func identity() (string, lang.Function) {
 	name := "identity"
@@ -566,9 +597,8 @@ func randomString() (string, lang.Function) {
 
 		chars := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 		result := make([]byte, length)
-		rand.Seed(time.Now().UnixNano())
 		for i := range result {
-			result[i] = chars[rand.Intn(len(chars))]
+			result[i] = chars[rand.IntN(len(chars))]
 		}
 
 		return lang.StringValue(string(result)), nil
@@ -739,11 +769,229 @@ var utilityFunctions = []func() (string, lang.Function){
 	benchmark,
 }
 
+var utilitySignatures = map[string]lib.Descriptor{
+	"if": {
+		Params: []lib.Param{lib.Arg("condition", lang.BoolType), lib.Arg("trueValue", lang.AnyType), lib.OptionalArg("falseValue", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns a value based on a condition (ternary operator).",
+	},
+	"unless": {
+		Params: []lib.Param{lib.Arg("condition", lang.BoolType), lib.Arg("falseValue", lang.AnyType), lib.OptionalArg("trueValue", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Opposite of `if` - returns a value when condition is false.",
+	},
+	"switch": {
+		Params:   []lib.Param{lib.Arg("value", lang.AnyType), lib.Arg("case", lang.AnyType), lib.Arg("result", lang.AnyType), lib.Arg("default", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Multi-way conditional based on value matching.",
+	},
+	"coalesce": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Returns the first non-null value from a list.",
+	},
+	"default": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType), lib.Arg("defaultValue", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns a default value if the first value is null.",
+	},
+	"firstNonNull": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Same as coalesce.",
+	},
+	"firstNonEmpty": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Returns the first non-empty value (not null and not empty string/collection).",
+	},
+	"greatest": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Returns the largest value from the arguments.",
+	},
+	"least": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Returns the smallest value from the arguments.",
+	},
+	"choose": {
+		Params:   []lib.Param{lib.Arg("index", lang.NumberType), lib.Arg("options", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Selects a value by 1-based index from options.",
+	},
+	"debug": {
+		Params:          []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic:        true,
+		Result:          lang.AnyType,
+		Pure:            false,
+		Doc:             "Prints debug information to console and returns first value.",
+		ContextFunction: debugContext,
+	},
+	"inspect": {
+		Params:          []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Returns a detailed string description of a value's type and content.",
+		ContextFunction: inspectContext,
+	},
+	"dump": {
+		Params:          []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic:        true,
+		Result:          lang.StringType,
+		Pure:            true,
+		Doc:             "Returns detailed string descriptions of multiple values, separated by newlines.",
+		ContextFunction: dumpContext,
+	},
+	"identity": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns the input value unchanged (identity function).",
+	},
+	"noop": {
+		Params:   []lib.Param{lib.OptionalArg("args", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "No-operation function that ignores all arguments.",
+	},
+	"constant": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns the input value (alias for `identity`).",
+	},
+	"tryOr": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType), lib.Arg("fallback", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns value if not null, otherwise returns fallback.",
+	},
+	"safe": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns value if not null, otherwise returns null (null-safe wrapper).",
+	},
+	"tostring": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.StringType,
+		Pure:   true,
+		Doc:    "Converts a value to its string representation.",
+	},
+	"tonumber": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Converts a value to a number.",
+	},
+	"tobool": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Converts a value to a boolean.",
+	},
+	"tolist": {
+		Params:   []lib.Param{lib.OptionalArg("values", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.ListType,
+		Pure:     true,
+		Doc:      "Converts arguments to a list.",
+	},
+	"assert": {
+		Params: []lib.Param{lib.Arg("condition", lang.BoolType), lib.OptionalArg("message", lang.StringType)},
+		Result: lang.BoolType,
+		Pure:   true,
+		Doc:    "Throws an error if condition is false.",
+	},
+	"validate": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType), lib.Arg("condition", lang.BoolType), lib.OptionalArg("fallback", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Returns value if condition is true, otherwise returns fallback.",
+	},
+	"require": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType), lib.OptionalArg("message", lang.StringType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Throws an error if value is null or undefined.",
+	},
+	"apply": {
+		Params:   []lib.Param{lib.Arg("args", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Identity function for function application patterns.",
+	},
+	"pipe": {
+		Params:   []lib.Param{lib.Arg("args", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Identity function for piping patterns.",
+	},
+	"compose": {
+		Params:   []lib.Param{lib.Arg("args", lang.AnyType)},
+		Variadic: true,
+		Result:   lang.AnyType,
+		Pure:     true,
+		Doc:      "Identity function for composition patterns.",
+	},
+	"uuid": {
+		Params: []lib.Param{},
+		Result: lang.StringType,
+		Pure:   false,
+		Doc:    "Generates a new UUID (Universally Unique Identifier).",
+	},
+	"timestamp": {
+		Params: []lib.Param{},
+		Result: lang.NumberType,
+		Pure:   false,
+		Doc:    "Returns the current Unix timestamp in seconds.",
+	},
+	"randomString": {
+		Params: []lib.Param{lib.OptionalArg("length", lang.NumberType)},
+		Result: lang.StringType,
+		Pure:   false,
+		Doc:    "Generates a random alphanumeric string.",
+	},
+	"memoize": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.AnyType,
+		Pure:   true,
+		Doc:    "Placeholder for memoization (currently returns input unchanged).",
+	},
+	"benchmark": {
+		Params: []lib.Param{lib.Arg("value", lang.AnyType)},
+		Result: lang.NumberType,
+		Pure:   true,
+		Doc:    "Placeholder for benchmarking (returns mock timing).",
+	},
+}
+
+// Descriptors describes every function in the package, sorted by name.
+func Descriptors() []*lib.Descriptor {
+	return lib.Describe(utilityFunctions, utilitySignatures)
+}
+
 func Export() map[string]lang.Function {
-	out := make(map[string]lang.Function)
-	for _, value := range utilityFunctions {
-		name, fn := value()
-		out[name] = fn
-	}
-	return out
+	return lib.Export(Descriptors())
 }
@@ -16,6 +16,8 @@
 package exql
 
 import (
+	"context"
+
 	"github.com/vedadiyan/exql/lang"
 )
 
@@ -24,10 +26,36 @@ func Parse(expr string) (lang.ExprNode, error) {
 	return lang.ParseExpression(expr)
 }
 
-func Eval(expr string, context lang.Context) (lang.Value, error) {
-	result, err := Parse(expr)
+// ParsePartial parses expr, recovering from syntax errors, and returns all
+// of them together with a partial AST. It is meant for tooling such as
+// editors that need every error in one pass.
+func ParsePartial(expr string) (lang.ExprNode, []*lang.SyntaxError) {
+	return lang.ParsePartial(expr)
+}
+
+// Format parses expr and returns its canonical source, laid out according
+// to options. It is meant for normalizing expressions before they are
+// stored, so that equivalent expressions are saved as the same text.
+func Format(expr string, options lang.FormatOptions) (string, error) {
+	ast, err := Parse(expr)
+	if err != nil {
+		return "", err
+	}
+	return lang.FormatWith(ast, options), nil
+}
+
+func Eval(expr string, ctx lang.Context) (lang.Value, error) {
+	return EvalContext(context.Background(), expr, ctx)
+}
+
+// EvalContext is Eval for an evaluation that can be cancelled through goctx.
+// Context functions, such as time.sleep, receive goctx, and the evaluation
+// stops with goctx's error once it is done. Limits set on goctx with
+// lang.WithLimits are enforced, including MaxDepth while expr is parsed.
+func EvalContext(goctx context.Context, expr string, ctx lang.Context) (lang.Value, error) {
+	result, err := lang.ParseLimited(expr, lang.LimitsFrom(goctx))
 	if err != nil {
 		return nil, err
 	}
-	return result.Evaluate(context)
+	return lang.EvaluateContext(goctx, result, ctx)
 }
@@ -16,7 +16,12 @@
 package exql
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/vedadiyan/exql/lang"
 )
@@ -41,14 +46,57 @@ func TestParse(t *testing.T) {
 		{"complex expression", "(x + 5) * 2 > threshold and active", false},
 		{"list literal", "[1, 2, 3]", false},
 		{"nested access", "users[0].name", false},
+		{"ternary", "x > 1 ? 'big' : 'small'", false},
+		{"nested ternary", "a ? 1 : b ? 2 : 3", false},
+		{"case when", "case when x > 1 then 'big' else 'small' end", false},
+		{"simple case", "case x when 1 then 'one' when 2 then 'two' end", false},
+		{"keyword field", "range.end", false},
+		{"optional keyword field", "range?.end", false},
+		{"keyword map keys", "{case: 1, when: 2, then: 3, else: 4, end: 5}", false},
+		{"keyword map key in case", "case when x then {end: 1} else {end: 2} end", false},
+		{"null literal", "null", false},
+		{"optional chaining", "a?.b?.[0]?.c", false},
+		{"optional slice", "a?.[1:2]", false},
+		{"optional call", "a?.b(1)", false},
+		{"coalesce", "a ?? b ?? 'default'", false},
+		{"modulo", "x % 2 == 0", false},
+		{"empty map literal", "{}", false},
+		{"escaped string", "'it\\'s'", false},
+		{"template string", "`hello ${user.name}`", false},
+		{"map literal", "{'key': 1, name: x, \"other\": [1], [k]: v, ...rest}", false},
+		{"nested map literal", "{a: {b: {c: 1}}}", false},
+		{"integer division", "3 // 5", false},
+		{"power", "2 ** 3 ** 2", false},
+		{"scientific notation", "1.5e3 + 2E-2", false},
+		{"prefixed numbers", "0xFF + 0o17 + 0b101", false},
+		{"digit separators", "1_000_000 > x", false},
+		{"is null", "user.email is null", false},
+		{"is not null", "user.email is not null and active", false},
+		{"lambda", "x => x.age > 18", false},
+		{"lambda with parameters", "(a, b) => a + b", false},
+		{"lambda with parenthesized parameter", "(x) => x * 2", false},
+		{"lambda without parameters", "() => 42", false},
+		{"lambda argument", "list.filter(items, x => x > 1)", false},
 
 		// Invalid expressions
 		{"syntax error", "3 + +", true},
 		{"unclosed paren", "(3 + 5", true},
 		{"unclosed string", "'hello", true},
-		{"invalid operator", "3 // 5", true},
+		{"invalid operator", "3 <> 5", true},
 		{"empty expression", "", true},
 		{"invalid identifier", "123abc", true},
+		{"invalid lambda parameter", "(1) => 2", true},
+		{"ternary without else", "x ? 1", true},
+		{"case without when", "case else 1 end", true},
+		{"is without null", "x is 1", true},
+		{"map literal missing value", "{a: }", true},
+		{"invalid escape", "'\\q'", true},
+		{"unclosed template", "`hello ${name}", true},
+		{"invalid template placeholder", "`${1 +}`", true},
+		{"map literal missing colon", "{a 1}", true},
+		{"malformed number", "1.2.3", true},
+		{"malformed exponent", "1e + 2", true},
+		{"malformed hex", "0xZZ", true},
 	}
 
 	for _, tt := range tests {
@@ -169,6 +217,360 @@ func TestEval(t *testing.T) {
 			lang.StringValue("second"),
 			false,
 		},
+		{
+			"slice access",
+			"items[1:3]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("items", lang.ListValue{
+					lang.StringValue("first"),
+					lang.StringValue("second"),
+					lang.StringValue("third"),
+				})
+			},
+			lang.ListValue{lang.StringValue("second"), lang.StringValue("third")},
+			false,
+		},
+		{
+			"slice with variables",
+			"items[i:i+n]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("items", lang.ListValue{
+					lang.NumberValue(1),
+					lang.NumberValue(2),
+					lang.NumberValue(3),
+					lang.NumberValue(4),
+				})
+				ctx.SetVariable("i", lang.NumberValue(1))
+				ctx.SetVariable("n", lang.NumberValue(2))
+			},
+			lang.ListValue{lang.NumberValue(2), lang.NumberValue(3)},
+			false,
+		},
+		{
+			"slice with step",
+			"items[::2]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("items", lang.ListValue{
+					lang.NumberValue(1),
+					lang.NumberValue(2),
+					lang.NumberValue(3),
+				})
+			},
+			lang.ListValue{lang.NumberValue(1), lang.NumberValue(3)},
+			false,
+		},
+		{
+			"slice open ends",
+			"[items[:2], items[2:]]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("items", lang.ListValue{
+					lang.NumberValue(1),
+					lang.NumberValue(2),
+					lang.NumberValue(3),
+				})
+			},
+			lang.ListValue{
+				lang.ListValue{lang.NumberValue(1), lang.NumberValue(2)},
+				lang.ListValue{lang.NumberValue(3)},
+			},
+			false,
+		},
+		{
+			"string slice",
+			"'hello'[-3:]",
+			nil,
+			lang.StringValue("llo"),
+			false,
+		},
+		{
+			"slice out of range",
+			"items[0:5]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("items", lang.ListValue{lang.NumberValue(1)})
+			},
+			nil,
+			true,
+		},
+		{
+			"ternary guards nil",
+			"user != undefined_var ? user.name : 'n/a'",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{"name": lang.StringValue("John")})
+			},
+			lang.StringValue("John"),
+			false,
+		},
+		{
+			"ternary with list branches",
+			"flag ?[1] : [2]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("flag", lang.BoolValue(false))
+			},
+			lang.ListValue{lang.NumberValue(2)},
+			false,
+		},
+		{
+			"ternary is lazy",
+			"true ? 'ok' : fail()",
+			func(ctx *DefaultContext) {
+				ctx.SetFunction("fail", func(args []lang.Value) (lang.Value, error) {
+					return nil, fmt.Errorf("should not be called")
+				})
+			},
+			lang.StringValue("ok"),
+			false,
+		},
+		{
+			"case when",
+			"case when x < 10 then 'small' when x < 100 then 'medium' else 'large' end",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("x", lang.NumberValue(42))
+			},
+			lang.StringValue("medium"),
+			false,
+		},
+		{
+			"simple case",
+			"case status when 'A' then 'active' when 'I' then 'inactive' end",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("status", lang.StringValue("I"))
+			},
+			lang.StringValue("inactive"),
+			false,
+		},
+		{
+			"keyword map key and field",
+			"{start: 1, end: 5}.end",
+			nil,
+			lang.NumberValue(5),
+			false,
+		},
+		{
+			"null literal",
+			"null",
+			nil,
+			nil,
+			false,
+		},
+		{
+			"missing field is null",
+			"user.email is null",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{"name": lang.StringValue("John")})
+			},
+			lang.BoolValue(true),
+			false,
+		},
+		{
+			"present field is not null",
+			"user.name is not null",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{"name": lang.StringValue("John")})
+			},
+			lang.BoolValue(true),
+			false,
+		},
+		{
+			"null comparison is unknown",
+			"user.age > 18",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{})
+			},
+			nil,
+			false,
+		},
+		{
+			"null guard",
+			"user.age is not null and user.age > 18",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{})
+			},
+			lang.BoolValue(false),
+			false,
+		},
+		{
+			"optional chaining on sparse payload",
+			"payload.items?.[0]?.id ?? 'none'",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("payload", lang.MapValue{})
+			},
+			lang.StringValue("none"),
+			false,
+		},
+		{
+			"optional chaining on full payload",
+			"payload.items?.[0]?.id ?? 'none'",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("payload", lang.MapValue{
+					"items": lang.ListValue{lang.MapValue{"id": lang.StringValue("a1")}},
+				})
+			},
+			lang.StringValue("a1"),
+			false,
+		},
+		{
+			"index on missing field",
+			"payload.items[0]",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("payload", lang.MapValue{})
+			},
+			nil,
+			true,
+		},
+		{
+			"coalesce binds looser than or",
+			"false or null ?? 'default'",
+			nil,
+			lang.StringValue("default"),
+			false,
+		},
+		{
+			"modulo precedence",
+			"1 + 10 % 4 * 2",
+			nil,
+			lang.NumberValue(5),
+			false,
+		},
+		{
+			"integer division",
+			"17 // 5",
+			nil,
+			lang.NumberValue(3),
+			false,
+		},
+		{
+			"power is right associative",
+			"2 ** 3 ** 2",
+			nil,
+			lang.NumberValue(512),
+			false,
+		},
+		{
+			"power binds tighter than unary minus",
+			"-2 ** 2",
+			nil,
+			lang.NumberValue(-4),
+			false,
+		},
+		{
+			"power with negative exponent",
+			"2 ** -1",
+			nil,
+			lang.NumberValue(0.5),
+			false,
+		},
+		{
+			"string concatenation",
+			"'a' + 'b'",
+			nil,
+			lang.StringValue("ab"),
+			false,
+		},
+		{
+			"cache key",
+			"'user:' + user.id + ':' + user.name",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{
+					"id":   lang.NumberValue(7),
+					"name": lang.StringValue("john"),
+				})
+			},
+			lang.StringValue("user:7:john"),
+			false,
+		},
+		{
+			"list concatenation",
+			"[1, 2] + [3]",
+			nil,
+			lang.ListValue{lang.NumberValue(1), lang.NumberValue(2), lang.NumberValue(3)},
+			false,
+		},
+		{
+			"map literal",
+			"{name: user.name, 'adult': user.age >= 18, [user.role]: true}",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{
+					"name": lang.StringValue("John"),
+					"age":  lang.NumberValue(25),
+					"role": lang.StringValue("admin"),
+				})
+			},
+			lang.MapValue{
+				"name":  lang.StringValue("John"),
+				"adult": lang.BoolValue(true),
+				"admin": lang.BoolValue(true),
+			},
+			false,
+		},
+		{
+			"map literal spread",
+			"{...user, role: 'guest'}",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{
+					"name": lang.StringValue("John"),
+					"role": lang.StringValue("admin"),
+				})
+			},
+			lang.MapValue{
+				"name": lang.StringValue("John"),
+				"role": lang.StringValue("guest"),
+			},
+			false,
+		},
+		{
+			"map literal field access",
+			"{a: {b: 42}}.a.b",
+			nil,
+			lang.NumberValue(42),
+			false,
+		},
+		{
+			"escaped quote",
+			"'it\\'s'",
+			nil,
+			lang.StringValue("it's"),
+			false,
+		},
+		{
+			"escape sequences",
+			"'a\\tb\\n\\u00e9'",
+			nil,
+			lang.StringValue("a\tb\né"),
+			false,
+		},
+		{
+			"number literal forms",
+			"1e3 + 0xFF + 0o17 + 0b101 + 1_000",
+			nil,
+			lang.NumberValue(1000 + 255 + 15 + 5 + 1000),
+			false,
+		},
+		{
+			"template string",
+			"`user:${user.id}:${string.lower(user.name)}`",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("user", lang.MapValue{
+					"id":   lang.NumberValue(7),
+					"name": lang.StringValue("John"),
+				})
+				ctx.SetVariable("string", NewDefaultContext(WithFunctions(map[string]lang.Function{
+					"lower": func(args []lang.Value) (lang.Value, error) {
+						return lang.StringValue(strings.ToLower(string(args[0].(lang.StringValue)))), nil
+					},
+				})))
+			},
+			lang.StringValue("user:7:john"),
+			false,
+		},
+		{
+			"template with expression",
+			"`${n} item${n == 1 ? '' : 's'}`",
+			func(ctx *DefaultContext) {
+				ctx.SetVariable("n", lang.NumberValue(3))
+			},
+			lang.StringValue("3 items"),
+			false,
+		},
 		{
 			"function call",
 			"add(10, 20)",
@@ -292,6 +694,82 @@ func TestEval(t *testing.T) {
 	}
 }
 
+func TestNullWithBuiltInLibrary(t *testing.T) {
+	ctx := NewDefaultContext(WithBuiltInLibrary())
+
+	result, err := Eval("util.coalesce(null, 'default')", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !valueEqual(result, lang.StringValue("default")) {
+		t.Errorf("expected 'default', got %v", result)
+	}
+}
+
+func TestMapLiteralWithBuiltInLibrary(t *testing.T) {
+	ctx := NewDefaultContext(WithBuiltInLibrary())
+	ctx.SetVariable("user", lang.MapValue{
+		"name": lang.StringValue("John"),
+		"role": lang.StringValue("admin"),
+	})
+
+	result, err := Eval("map.merge(user, {'role': 'guest'})", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := lang.MapValue{
+		"name": lang.StringValue("John"),
+		"role": lang.StringValue("guest"),
+	}
+	if !valueEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestLambdaWithBuiltInLibrary(t *testing.T) {
+	ctx := NewDefaultContext(WithBuiltInLibrary())
+	ctx.SetVariable("order", lang.MapValue{
+		"items": lang.ListValue{
+			lang.MapValue{"sku": lang.StringValue("a"), "price": lang.NumberValue(40)},
+			lang.MapValue{"sku": lang.StringValue("b"), "price": lang.NumberValue(120)},
+			lang.MapValue{"sku": lang.StringValue("c"), "price": lang.NumberValue(80)},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   lang.Value
+	}{
+		{"any", "list.any(order.items, item => item.price > 100)", lang.BoolValue(true)},
+		{"all", "list.all(order.items, item => item.price > 100)", lang.BoolValue(false)},
+		{"filter", "list.filter(order.items, item => item.price < 100).sku", lang.ListValue{lang.StringValue("a"), lang.StringValue("c")}},
+		{"map", "list.map(order.items, item => item.price * 2)", lang.ListValue{lang.NumberValue(80), lang.NumberValue(240), lang.NumberValue(160)}},
+		{"reduce", "list.reduce(order.items, (total, item) => total + item.price, 0)", lang.NumberValue(240)},
+		{"find", "list.find(order.items, item => item.sku == 'c').price", lang.NumberValue(80)},
+		{"sortBy", "list.sortBy(order.items, item => item.price).sku", lang.ListValue{lang.StringValue("a"), lang.StringValue("c"), lang.StringValue("b")}},
+		{"closure", "list.map([1, 2], x => list.map([10, 20], y => x + y))", lang.ListValue{
+			lang.ListValue{lang.NumberValue(11), lang.NumberValue(21)},
+			lang.ListValue{lang.NumberValue(12), lang.NumberValue(22)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Eval(tt.expression, ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !valueEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestParseAndEvalSeparately(t *testing.T) {
 	ctx := NewDefaultContext()
 	ctx.SetVariable("x", lang.NumberValue(10))
@@ -324,6 +802,160 @@ func TestParseAndEvalSeparately(t *testing.T) {
 	}
 }
 
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		width      int
+		expected   string
+	}{
+		{"normalized", "(( user.age>18 ))", 0, "user.age > 18"},
+		{"equivalent", "x = 1 and (y = 2)", 0, "x == 1 and y == 2"},
+		{"wrapped", "list.contains(user.roles, 'admin') or user.age > 18", 40, "list.contains(user.roles, 'admin')\n    or user.age > 18"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Format(tt.expression, lang.FormatOptions{Width: tt.width})
+			if err != nil {
+				t.Fatalf("format error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if _, err := Format("1 +", lang.FormatOptions{}); err == nil {
+		t.Error("expected syntax error")
+	}
+	deep := "1" + strings.Repeat(" + 1", lang.DefaultMaxDepth)
+	if _, err := Format(deep, lang.FormatOptions{}); !errors.Is(err, lang.ErrLimitExceeded) {
+		t.Errorf("expected the depth to be exceeded, got %v", err)
+	}
+	if ast, errs := ParsePartial(deep); len(errs) != 1 || lang.Format(ast) != "<invalid>" {
+		t.Errorf("expected only the depth to be exceeded, got %v (%v)", ast, errs)
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	type key struct{}
+	base := NewDefaultContext(
+		WithBuiltInLibrary(),
+		WithContextFunctions(map[string]lang.ContextFunction{
+			"user": func(ctx context.Context, args []lang.Value) (lang.Value, error) {
+				user, _ := ctx.Value(key{}).(string)
+				return lang.StringValue(user), nil
+			},
+		}),
+	)
+	goctx := context.WithValue(context.Background(), key{}, "john")
+
+	t.Run("context functions", func(t *testing.T) {
+		ctx := NewChildContext(base)
+		result, err := EvalContext(goctx, "string.upper(user())", ctx)
+		if err != nil {
+			t.Fatalf("evaluation error: %v", err)
+		}
+		if !valueEqual(result, lang.StringValue("JOHN")) {
+			t.Errorf("expected 'JOHN', got %v", result)
+		}
+
+		// Without a context.Context
+		result, err = Eval("user()", ctx)
+		if err != nil || !valueEqual(result, lang.StringValue("")) {
+			t.Errorf("expected '', got %v (%v)", result, err)
+		}
+
+		// A plain function shadows a context function of the parent
+		ctx.SetFunction("user", func(args []lang.Value) (lang.Value, error) {
+			return lang.StringValue("jane"), nil
+		})
+		result, err = EvalContext(goctx, "user()", ctx)
+		if err != nil || !valueEqual(result, lang.StringValue("jane")) {
+			t.Errorf("expected 'jane', got %v (%v)", result, err)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		goctx, cancel := context.WithTimeout(goctx, 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := EvalContext(goctx, "list.map([1, 2, 3], i => time.sleep(10))", base)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the sleep to be cancelled, took %v", elapsed)
+		}
+	})
+
+	t.Run("deadline in a builtin", func(t *testing.T) {
+		goctx, cancel := context.WithTimeout(goctx, 30*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		_, err := EvalContext(goctx, "list.map(list.range(0, 1e8), x => x)", base)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the deadline to be exceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the range to be cancelled, took %v", elapsed)
+		}
+	})
+}
+
+func TestEvalLimits(t *testing.T) {
+	ctx := NewDefaultContext(WithBuiltInLibrary())
+	goctx := lang.WithLimits(context.Background(), lang.Limits{
+		MaxDepth:          20,
+		MaxSteps:          1000,
+		MaxCollectionSize: 1000,
+		MaxStringLength:   1000,
+		MaxDuration:       100 * time.Millisecond,
+	})
+
+	tests := []struct {
+		expr     string
+		expected lang.Value
+	}{
+		{"string.repeat('x', 1e9)", nil},
+		{"string.padLeft('x', 1e9)", nil},
+		{"list.range(0, 1e9)", nil},
+		{"list.repeat(1, 1e9)", nil},
+		{"list.reduce(list.range(0, 30), (acc, i) => acc + acc, 'x')", nil},
+		{"list.map(list.range(0, 900), i => i * 2)", nil},
+		{"1" + strings.Repeat(" + 1", 30), nil},
+		{"list.flatten(list.repeat(list.repeat(list.repeat(1, 300), 300), 300), 2)", nil},
+		{"list.flatten(list.repeat([list.repeat(1, 300)], 300))", nil},
+		{"'' + list.repeat(list.repeat(list.repeat(1, 200), 200), 200)", nil},
+		{"'' + list.repeat(string.repeat('x', 100), 20)", nil},
+		{"`${list.repeat(string.repeat('x', 100), 20)}`", nil},
+		{"list.length(list.flatten(list.repeat(list.repeat(1, 30), 30)))", lang.NumberValue(900)},
+		{"time.sleep(10)", nil},
+		{"list.length(list.range(0, 100))", lang.NumberValue(100)},
+		{"string.len(string.repeat('ab', 500))", lang.NumberValue(1000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := EvalContext(goctx, tt.expr, ctx)
+			if tt.expected == nil {
+				if !errors.Is(err, lang.ErrLimitExceeded) {
+					t.Errorf("expected the limit to be exceeded, got %v (%v)", result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("evaluation error: %v", err)
+			}
+			if !valueEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestBuiltInLibraries(t *testing.T) {
 	ctx := NewDefaultContext(WithBuiltInLibrary())
 
@@ -0,0 +1,497 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package exql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+// Env declares the variables, functions and namespaces an expression may
+// reference. Expressions compiled against an Env are checked up front, so
+// unknown names, wrong argument counts and type mismatches are reported
+// before evaluation.
+type Env struct {
+	variables  map[string]lang.Type
+	functions  map[string]FunctionDecl
+	namespaces map[string]map[string]FunctionDecl
+	limits     lang.Limits
+}
+
+// FunctionDecl declares a function and the number of arguments it accepts.
+// A MaxArgs of -1 means the function accepts any number of arguments. Params
+// and Result are optional; arguments beyond Params are not type checked and
+// the last param repeats for variadic functions. Calls to Pure functions with
+// constant arguments are computed once, when the expression is compiled.
+// ContextFunction, if set, is called instead of Function when the program
+// runs, with the context.Context of Program.EvaluateContext or
+// context.Background().
+type FunctionDecl struct {
+	Function        lang.Function
+	ContextFunction lang.ContextFunction
+	MinArgs         int
+	MaxArgs         int
+	Params          []lang.Type
+	Result          lang.Type
+	Pure            bool
+}
+
+type EnvOption func(*Env)
+
+// Program is an expression that has been parsed and validated against an
+// Env. It is safe to evaluate many times with different variable bindings.
+type Program struct {
+	ast      lang.ExprNode
+	bytecode *lang.Bytecode
+	globals  *DefaultContext
+	limits   lang.Limits
+}
+
+// Issue is a single problem found while compiling an expression. Pos is the
+// byte offset in the expression where the problem was found.
+type Issue struct {
+	Pos     int
+	Message string
+}
+
+// CompileError lists every issue found while validating an expression.
+type CompileError struct {
+	Issues []Issue
+}
+
+type envTypes struct {
+	env *Env
+}
+
+type checker struct {
+	env    *Env
+	issues []Issue
+}
+
+type programContext struct {
+	globals *DefaultContext
+	vars    map[string]lang.Value
+}
+
+// DeclareVariables declares variables whose type is only known at runtime.
+func DeclareVariables(names ...string) EnvOption {
+	return func(e *Env) {
+		for _, name := range names {
+			e.variables[name] = lang.AnyType
+		}
+	}
+}
+
+func DeclareVariable(name string, typ lang.Type) EnvOption {
+	return func(e *Env) {
+		e.variables[name] = typ
+	}
+}
+
+func DeclareFunction(name string, fn lang.Function, minArgs, maxArgs int) EnvOption {
+	return func(e *Env) {
+		e.functions[name] = FunctionDecl{Function: fn, MinArgs: minArgs, MaxArgs: maxArgs}
+	}
+}
+
+// DeclareContextFunction declares a function that receives the
+// context.Context of the evaluation, or context.Background() when the
+// program is evaluated without one.
+func DeclareContextFunction(name string, fn lang.ContextFunction, minArgs, maxArgs int) EnvOption {
+	return func(e *Env) {
+		e.functions[name] = FunctionDecl{
+			Function: func(args []lang.Value) (lang.Value, error) {
+				return fn(context.Background(), args)
+			},
+			ContextFunction: fn,
+			MinArgs:         minArgs,
+			MaxArgs:         maxArgs,
+		}
+	}
+}
+
+// DeclareLimits bounds the resources of the programs compiled against the
+// Env. Expressions nested deeper than limits.MaxDepth fail to compile, and
+// the other limits apply to every evaluation, unless the context.Context
+// passed to Program.EvaluateContext carries limits of its own.
+func DeclareLimits(limits lang.Limits) EnvOption {
+	return func(e *Env) {
+		e.limits = limits
+	}
+}
+
+func DeclareNamespace(name string, funcs map[string]FunctionDecl) EnvOption {
+	return func(e *Env) {
+		e.namespaces[name] = funcs
+	}
+}
+
+// DeclareBuiltInLibrary declares every namespace of the built-in library
+// with the argument counts and types from its function descriptors.
+func DeclareBuiltInLibrary() EnvOption {
+	return func(e *Env) {
+		for name, descriptors := range descriptors() {
+			decls := make(map[string]FunctionDecl)
+			for _, descriptor := range descriptors {
+				sig := descriptor.Signature()
+				decls[descriptor.Name] = FunctionDecl{
+					Function:        descriptor.Validated(),
+					ContextFunction: descriptor.ValidatedContext(),
+					MinArgs:         descriptor.MinArgs(),
+					MaxArgs:         descriptor.MaxArgs(),
+					Params:          sig.Params,
+					Result:          sig.Result,
+					Pure:            descriptor.Pure,
+				}
+			}
+			e.namespaces[name] = decls
+		}
+	}
+}
+
+func NewEnv(opts ...EnvOption) *Env {
+	out := &Env{
+		variables:  make(map[string]lang.Type),
+		functions:  make(map[string]FunctionDecl),
+		namespaces: make(map[string]map[string]FunctionDecl),
+	}
+
+	for _, opt := range opts {
+		opt(out)
+	}
+	return out
+}
+
+func (e *Env) declared(name string) bool {
+	_, ok := e.variables[name]
+	return ok
+}
+
+func (e *Env) context() *DefaultContext {
+	out := functions(e.functions)
+	for name, decls := range e.namespaces {
+		out.SetVariable(name, functions(decls))
+	}
+	return out
+}
+
+// functions returns a context holding the declared functions.
+func functions(decls map[string]FunctionDecl) *DefaultContext {
+	out := NewDefaultContext()
+	for name, decl := range decls {
+		if decl.ContextFunction != nil {
+			out.SetContextFunction(name, decl.ContextFunction)
+			continue
+		}
+		out.SetFunction(name, decl.Function)
+	}
+	return out
+}
+
+// Compile parses expr, resolves every variable, namespace and function it
+// references against env and type checks it. All problems are reported
+// together as a *CompileError, ordered by position.
+func Compile(expr string, env *Env) (*Program, error) {
+	if env == nil {
+		env = NewEnv()
+	}
+	ast, err := lang.ParseLimited(expr, env.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checker{env: env}
+	c.check(ast, nil)
+	_, typeErrors := lang.Check(ast, envTypes{env})
+	for _, err := range typeErrors {
+		c.issues = append(c.issues, Issue{Pos: err.Pos, Message: err.Message})
+	}
+	if len(c.issues) > 0 {
+		sort.SliceStable(c.issues, func(i, j int) bool {
+			return c.issues[i].Pos < c.issues[j].Pos
+		})
+		return nil, &CompileError{Issues: c.issues}
+	}
+	bytecode := lang.Compile(lang.Optimize(ast, envTypes{env}))
+	return &Program{ast: ast, bytecode: bytecode, globals: env.context(), limits: env.limits}, nil
+}
+
+// AST returns the expression as parsed, before optimization.
+func (p *Program) AST() lang.ExprNode {
+	return p.ast
+}
+
+// Evaluate runs the program with the given variable bindings. Declared
+// variables that are not bound evaluate to null. It runs the program as
+// bytecode, which is faster than walking the tree on arithmetic and lambdas
+// and on par elsewhere but for expressions that only build values, see
+// lang.Compile.
+func (p *Program) Evaluate(vars map[string]lang.Value) (lang.Value, error) {
+	if p.limits != (lang.Limits{}) {
+		return p.EvaluateContext(context.Background(), vars)
+	}
+	return p.bytecode.Evaluate(&programContext{globals: p.globals, vars: vars})
+}
+
+// EvaluateContext is Evaluate for an evaluation that can be cancelled
+// through goctx. Context functions receive goctx, and the evaluation stops
+// with goctx's error once it is done. The declared limits apply unless goctx
+// carries limits of its own.
+func (p *Program) EvaluateContext(goctx context.Context, vars map[string]lang.Value) (lang.Value, error) {
+	if lang.LimitsFrom(goctx) == (lang.Limits{}) {
+		goctx = lang.WithLimits(goctx, p.limits)
+	}
+	return lang.EvaluateContext(goctx, p.bytecode, &programContext{globals: p.globals, vars: vars})
+}
+
+func (e *CompileError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = fmt.Sprintf("%s at position %d", issue.Message, issue.Pos)
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (c *programContext) GetVariable(name string) lang.Value {
+	if value, ok := c.vars[name]; ok {
+		return value
+	}
+	return c.globals.GetVariable(name)
+}
+
+func (c *programContext) GetFunction(name string) lang.Function {
+	return c.globals.GetFunction(name)
+}
+
+func (c *programContext) GetContextFunction(name string) lang.ContextFunction {
+	return c.globals.GetContextFunction(name)
+}
+
+func (t envTypes) VariableType(name string) lang.Type {
+	return t.env.variables[name]
+}
+
+func (t envTypes) FunctionSignature(namespace, name string) *lang.Signature {
+	decls := t.env.functions
+	if namespace != "" {
+		decls = t.env.namespaces[namespace]
+	}
+	decl, ok := decls[name]
+	if !ok || (decl.Params == nil && decl.Result == lang.AnyType) {
+		return nil
+	}
+	return &lang.Signature{Params: decl.Params, Variadic: decl.MaxArgs < 0, Result: decl.Result}
+}
+
+// foldLimit bounds the strings and collections that calls folded at compile
+// time may build, unless smaller limits are declared. Calls that would build
+// larger values are left to run at evaluation time, under the limits of the
+// evaluation, so that compiling never allocates more than this.
+const foldLimit = 1 << 16
+
+// PureFunction returns the declared function if it is pure. Namespaces that
+// are also declared as variables may be rebound at evaluation time, so their
+// functions are never considered pure. Context functions run with the
+// declared limits, capped at foldLimit, so that folding a call cannot exceed
+// them.
+func (t envTypes) PureFunction(namespace, name string) lang.Function {
+	decls := t.env.functions
+	if namespace != "" {
+		if t.env.declared(namespace) {
+			return nil
+		}
+		decls = t.env.namespaces[namespace]
+	}
+	decl, ok := decls[name]
+	if !ok || !decl.Pure {
+		return nil
+	}
+	if decl.ContextFunction != nil {
+		limits := t.env.limits
+		limits.MaxStringLength = foldSize(limits.MaxStringLength)
+		limits.MaxCollectionSize = foldSize(limits.MaxCollectionSize)
+		goctx := lang.WithLimits(context.Background(), limits)
+		return func(args []lang.Value) (lang.Value, error) {
+			return decl.ContextFunction(goctx, args)
+		}
+	}
+	return decl.Function
+}
+
+// foldSize returns the size limit for folding under the declared limit.
+func foldSize(limit int) int {
+	if limit <= 0 || limit > foldLimit {
+		return foldLimit
+	}
+	return limit
+}
+
+// OperandResults reports false, since programs always evaluate `and` and
+// `or` to a boolean.
+func (t envTypes) OperandResults() bool {
+	return false
+}
+
+func (c *checker) errorf(pos int, format string, args ...any) {
+	c.issues = append(c.issues, Issue{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// check walks node and records an issue for every name it cannot resolve.
+// params holds the parameters of the enclosing lambdas.
+func (c *checker) check(node lang.ExprNode, params map[string]bool) {
+	switch n := node.(type) {
+	case *lang.BinaryOpNode:
+		{
+			c.check(n.Left, params)
+			c.check(n.Right, params)
+		}
+	case *lang.UnaryOpNode:
+		{
+			c.check(n.Operand, params)
+		}
+	case *lang.VariableNode:
+		{
+			if !params[n.Name] && !c.env.declared(n.Name) && c.env.namespaces[n.Name] == nil {
+				c.errorf(n.Pos, "unknown variable '%s'", n.Name)
+			}
+		}
+	case *lang.FieldAccessNode:
+		{
+			c.check(n.Object, params)
+		}
+	case *lang.IndexAccessNode:
+		{
+			c.check(n.Object, params)
+			c.check(n.Index, params)
+		}
+	case *lang.FunctionCallNode:
+		{
+			c.checkCall(n, params)
+		}
+	case *lang.ListNode:
+		{
+			for _, elem := range n.Elements {
+				c.check(elem, params)
+			}
+		}
+	case *lang.TemplateNode:
+		{
+			for _, part := range n.Parts {
+				c.check(part, params)
+			}
+		}
+	case *lang.MapNode:
+		{
+			for _, entry := range n.Entries {
+				c.check(entry.Key, params)
+				c.check(entry.Value, params)
+			}
+		}
+	case *lang.RangeNode:
+		{
+			c.check(n.Begin, params)
+			c.check(n.End, params)
+			c.check(n.Step, params)
+		}
+	case *lang.ConditionalNode:
+		{
+			c.check(n.Condition, params)
+			c.check(n.Then, params)
+			c.check(n.Else, params)
+		}
+	case *lang.CaseNode:
+		{
+			c.check(n.Subject, params)
+			for _, when := range n.Whens {
+				c.check(when.Condition, params)
+				c.check(when.Result, params)
+			}
+			c.check(n.Else, params)
+		}
+	case *lang.LambdaNode:
+		{
+			scope := make(map[string]bool)
+			for name := range params {
+				scope[name] = true
+			}
+			for _, name := range n.Params {
+				scope[name] = true
+			}
+			c.check(n.Body, scope)
+		}
+	}
+}
+
+func (c *checker) checkCall(n *lang.FunctionCallNode, params map[string]bool) {
+	for _, arg := range n.Args {
+		c.check(arg, params)
+	}
+
+	if n.Namespace == nil {
+		// Variables may hold lambdas, whose arity is only known at runtime
+		if params[n.Name] || c.env.declared(n.Name) {
+			return
+		}
+		decl, ok := c.env.functions[n.Name]
+		if !ok {
+			c.errorf(n.Pos, "unknown function '%s'", n.Name)
+			return
+		}
+		c.checkArity(n, n.Name, decl)
+		return
+	}
+
+	namespace, ok := n.Namespace.(*lang.VariableNode)
+	if !ok || params[namespace.Name] || c.env.declared(namespace.Name) {
+		c.check(n.Namespace, params)
+		c.errorf(n.Pos, "cannot call '%s' on a value that is not a namespace", n.Name)
+		return
+	}
+	decls, ok := c.env.namespaces[namespace.Name]
+	if !ok {
+		c.errorf(namespace.Pos, "unknown namespace '%s'", namespace.Name)
+		return
+	}
+	name := namespace.Name + "." + n.Name
+	decl, ok := decls[n.Name]
+	if !ok {
+		c.errorf(n.Pos, "unknown function '%s'", name)
+		return
+	}
+	c.checkArity(n, name, decl)
+}
+
+func (c *checker) checkArity(n *lang.FunctionCallNode, name string, decl FunctionDecl) {
+	count := len(n.Args)
+	if count >= decl.MinArgs && (decl.MaxArgs < 0 || count <= decl.MaxArgs) {
+		return
+	}
+
+	var expected string
+	switch {
+	case decl.MaxArgs < 0:
+		expected = fmt.Sprintf("at least %d", decl.MinArgs)
+	case decl.MinArgs == decl.MaxArgs:
+		expected = fmt.Sprintf("%d", decl.MinArgs)
+	default:
+		expected = fmt.Sprintf("%d to %d", decl.MinArgs, decl.MaxArgs)
+	}
+	c.errorf(n.Pos, "function '%s' expects %s argument(s), got %d", name, expected, count)
+}
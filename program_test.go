@@ -0,0 +1,424 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package exql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+func testEnv() *Env {
+	add := func(args []lang.Value) (lang.Value, error) {
+		return lang.NumberValue(toNumber(args[0]) + toNumber(args[1])), nil
+	}
+	return NewEnv(
+		DeclareVariables("user", "items", "threshold"),
+		DeclareFunction("add", add, 2, 2),
+		DeclareNamespace("math", map[string]FunctionDecl{
+			"sum": {Function: add, MinArgs: 1, MaxArgs: -1},
+			"abs": {Function: add, MinArgs: 1, MaxArgs: 1},
+		}),
+		DeclareBuiltInLibrary(),
+	)
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		issues     []Issue
+	}{
+		{"declared variable", "user.age > threshold", nil},
+		{"declared function", "add(1, 2)", nil},
+		{"namespace function", "math.sum(1, 2, 3)", nil},
+		{"built-in library", "string.upper(user.name)", nil},
+		{"namespace as value", "util", nil},
+		{"lambda parameters", "list.filter(items, x => x.price > threshold)", nil},
+		{"lambda parameter call", "list.map(items, f => f(1, 2, 3))", nil},
+		{"template placeholder", "`${user.name}`", nil},
+		{"unknown variable", "user.age > limit", []Issue{{11, "unknown variable 'limit'"}}},
+		{"unknown variable in template", "`hello ${nme}`", []Issue{{9, "unknown variable 'nme'"}}},
+		{"lambda parameter out of scope", "list.map(items, x => x) + x", []Issue{{26, "unknown variable 'x'"}}},
+		{"unknown function", "sub(1, 2)", []Issue{{0, "unknown function 'sub'"}}},
+		{"unknown namespace", "strings.upper('a')", []Issue{{0, "unknown namespace 'strings'"}}},
+		{"unknown namespace function", "string.uper('a')", []Issue{{7, "unknown function 'string.uper'"}}},
+		{"too many arguments", "add(1, 2, 3)", []Issue{{0, "function 'add' expects 2 argument(s), got 3"}}},
+		{"too few variadic arguments", "math.sum()", []Issue{{5, "function 'math.sum' expects at least 1 argument(s), got 0"}}},
+		{"built-in arity", "string.upper()", []Issue{{7, "function 'string.upper' expects 1 argument(s), got 0"}}},
+		{"built-in optional arguments", "string.trimLeft(user.name, 'x', 'y')", []Issue{{7, "function 'string.trimLeft' expects 1 to 2 argument(s), got 3"}}},
+		{"built-in argument type", "list.first(threshold > 1)", []Issue{{11, "argument 1 of 'first' must be list, got bool"}}},
+		{"built-in argument conversions", "util.if(1, 'a', 'b') + string.repeat('x', '3')", nil},
+		{"call on variable", "user.name()", []Issue{{5, "cannot call 'name' on a value that is not a namespace"}}},
+		{
+			"multiple issues",
+			"foo + add(bar)",
+			[]Issue{{0, "unknown variable 'foo'"}, {6, "function 'add' expects 2 argument(s), got 1"}, {10, "unknown variable 'bar'"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Compile(tt.expression, testEnv())
+
+			if tt.issues == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if program == nil {
+					t.Fatal("expected program")
+				}
+				return
+			}
+
+			var compileErr *CompileError
+			if !errors.As(err, &compileErr) {
+				t.Fatalf("expected CompileError, got %v", err)
+			}
+			if !reflect.DeepEqual(compileErr.Issues, tt.issues) {
+				t.Errorf("expected issues %v, got %v", tt.issues, compileErr.Issues)
+			}
+		})
+	}
+}
+
+func TestCompileSyntaxError(t *testing.T) {
+	program, err := Compile("1 +", NewEnv())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if program != nil {
+		t.Error("expected nil program")
+	}
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		t.Error("syntax errors should not be compile errors")
+	}
+}
+
+func TestProgramEvaluate(t *testing.T) {
+	program, err := Compile("add(user.age, threshold) > 30 and string.upper(user.name) == 'JOHN'", testEnv())
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		vars     map[string]lang.Value
+		expected lang.Value
+	}{
+		{
+			"matching",
+			map[string]lang.Value{
+				"user":      lang.MapValue{"age": lang.NumberValue(25), "name": lang.StringValue("john")},
+				"threshold": lang.NumberValue(10),
+			},
+			lang.BoolValue(true),
+		},
+		{
+			"not matching",
+			map[string]lang.Value{
+				"user":      lang.MapValue{"age": lang.NumberValue(15), "name": lang.StringValue("john")},
+				"threshold": lang.NumberValue(10),
+			},
+			lang.BoolValue(false),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := program.Evaluate(tt.vars)
+			if err != nil {
+				t.Fatalf("evaluation error: %v", err)
+			}
+			if !valueEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestProgramUnboundVariable(t *testing.T) {
+	program, err := Compile("user ?? 'anonymous'", NewEnv(DeclareVariables("user")))
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+
+	result, err := program.Evaluate(nil)
+	if err != nil {
+		t.Fatalf("evaluation error: %v", err)
+	}
+	if !valueEqual(result, lang.StringValue("anonymous")) {
+		t.Errorf("expected anonymous, got %v", result)
+	}
+}
+
+func TestProgramOptimize(t *testing.T) {
+	pure, impure := 0, 0
+	env := NewEnv(
+		DeclareVariables("role"),
+		DeclareNamespace("rules", map[string]FunctionDecl{
+			"prefix": {Function: func(args []lang.Value) (lang.Value, error) {
+				pure++
+				return lang.StringValue("role:"), nil
+			}, MinArgs: 0, MaxArgs: 0, Pure: true},
+			"suffix": {Function: func(args []lang.Value) (lang.Value, error) {
+				impure++
+				return lang.StringValue(""), nil
+			}, MinArgs: 0, MaxArgs: 0},
+		}),
+		DeclareBuiltInLibrary(),
+	)
+	expr := "rules.prefix() + role + rules.suffix() in [string.upper('role:') + 'ADMIN', 'role:admin']"
+	program, err := Compile(expr, env)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if actual := lang.Format(program.AST()); actual != expr {
+		t.Errorf("expected AST to be the parsed expression, got %s", actual)
+	}
+
+	for _, role := range []string{"admin", "user"} {
+		result, err := program.Evaluate(map[string]lang.Value{"role": lang.StringValue(role)})
+		if err != nil {
+			t.Fatalf("evaluation error: %v", err)
+		}
+		if !valueEqual(result, lang.BoolValue(role == "admin")) {
+			t.Errorf("expected %v for %s, got %v", role == "admin", role, result)
+		}
+	}
+	if pure != 1 || impure != 2 {
+		t.Errorf("expected the pure function to be called once and the impure one twice, got %d and %d", pure, impure)
+	}
+}
+
+func TestProgramEvaluateContext(t *testing.T) {
+	type key struct{}
+	tag := func(ctx context.Context, args []lang.Value) (lang.Value, error) {
+		value, _ := ctx.Value(key{}).(string)
+		return lang.StringValue(value), nil
+	}
+	env := NewEnv(DeclareBuiltInLibrary(), DeclareContextFunction("tag", tag, 0, 0))
+
+	program, err := Compile("tag() + string.upper(tag())", env)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	result, err := program.EvaluateContext(context.WithValue(context.Background(), key{}, "a"), nil)
+	if err != nil || !valueEqual(result, lang.StringValue("aA")) {
+		t.Errorf("expected 'aA', got %v (%v)", result, err)
+	}
+	result, err = program.Evaluate(nil)
+	if err != nil || !valueEqual(result, lang.StringValue("")) {
+		t.Errorf("expected '', got %v (%v)", result, err)
+	}
+
+	program, err = Compile("time.sleep(10)", env)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	goctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := program.EvaluateContext(goctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the sleep to be cancelled, got %v", err)
+	}
+}
+
+func TestProgramLimits(t *testing.T) {
+	env := NewEnv(
+		DeclareBuiltInLibrary(),
+		DeclareVariables("n"),
+		DeclareLimits(lang.Limits{MaxDepth: 10, MaxStringLength: 100}),
+	)
+
+	if _, err := Compile("n"+strings.Repeat(" + n", 10), env); !errors.Is(err, lang.ErrLimitExceeded) {
+		t.Errorf("expected the depth to be exceeded, got %v", err)
+	}
+
+	// Folding a pure call is limited too, so the call is left to fail when
+	// the program runs.
+	program, err := Compile("string.repeat('x', 1e9)", env)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if _, err := program.Evaluate(nil); !errors.Is(err, lang.ErrLimitExceeded) {
+		t.Errorf("expected the string length to be exceeded, got %v", err)
+	}
+
+	program, err = Compile("string.repeat('x', n)", env)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	result, err := program.Evaluate(map[string]lang.Value{"n": lang.NumberValue(10)})
+	if err != nil || !valueEqual(result, lang.StringValue("xxxxxxxxxx")) {
+		t.Errorf("expected 'xxxxxxxxxx', got %v (%v)", result, err)
+	}
+	if _, err := program.Evaluate(map[string]lang.Value{"n": lang.NumberValue(1000)}); !errors.Is(err, lang.ErrLimitExceeded) {
+		t.Errorf("expected the string length to be exceeded, got %v", err)
+	}
+
+	// Limits of the context.Context replace the declared ones
+	goctx := lang.WithLimits(context.Background(), lang.Limits{MaxStringLength: 1000})
+	result, err = program.EvaluateContext(goctx, map[string]lang.Value{"n": lang.NumberValue(1000)})
+	if err != nil || !valueEqual(result, lang.StringValue(strings.Repeat("x", 1000))) {
+		t.Errorf("expected 1000 x's, got %v", err)
+	}
+}
+
+func TestProgramFoldingLimits(t *testing.T) {
+	// Constants folded at compile time are checked when they are used
+	tests := []struct {
+		expression string
+		limits     lang.Limits
+	}{
+		{"[1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12]", lang.Limits{MaxCollectionSize: 5}},
+		{"list.range(1, 13)", lang.Limits{MaxCollectionSize: 5}},
+		{"{a: 1, b: 2, c: 3}", lang.Limits{MaxCollectionSize: 2}},
+		{"'xxxxxxxx' + 'xxxxxxxx'", lang.Limits{MaxStringLength: 10}},
+		{"string.repeat('x', 100)", lang.Limits{MaxStringLength: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			program, err := Compile(tt.expression, NewEnv(DeclareBuiltInLibrary(), DeclareLimits(tt.limits)))
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+			if result, err := program.Evaluate(nil); !errors.Is(err, lang.ErrLimitExceeded) {
+				t.Errorf("expected the declared limits to be exceeded, got %v (%v)", result, err)
+			}
+
+			program, err = Compile(tt.expression, NewEnv(DeclareBuiltInLibrary()))
+			if err != nil {
+				t.Fatalf("compile error: %v", err)
+			}
+			if _, err := program.Evaluate(nil); err != nil {
+				t.Errorf("evaluation error: %v", err)
+			}
+			goctx := lang.WithLimits(context.Background(), tt.limits)
+			if result, err := program.EvaluateContext(goctx, nil); !errors.Is(err, lang.ErrLimitExceeded) {
+				t.Errorf("expected the limits of the context to be exceeded, got %v (%v)", result, err)
+			}
+		})
+	}
+
+	// Calls are only folded while their results are small, so compiling
+	// does not build huge values
+	program, err := Compile("list.range(0, 3e7)", NewEnv(DeclareBuiltInLibrary()))
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if !strings.Contains(program.bytecode.String(), "range") {
+		t.Errorf("expected the call to be left in place, got\n%s", program.bytecode)
+	}
+	program, err = Compile("list.length(list.range(0, 10))", NewEnv(DeclareBuiltInLibrary()))
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	if strings.Contains(program.bytecode.String(), "range") {
+		t.Errorf("expected the call to be folded, got\n%s", program.bytecode)
+	}
+}
+
+func TestCompileTypes(t *testing.T) {
+	upper := func(args []lang.Value) (lang.Value, error) {
+		return args[0], nil
+	}
+	env := NewEnv(
+		DeclareVariable("age", lang.NumberType),
+		DeclareVariable("name", lang.StringType),
+		DeclareVariable("tags", lang.ListType),
+		DeclareVariables("payload"),
+		DeclareFunction("upper", upper, 1, 1),
+		DeclareNamespace("text", map[string]FunctionDecl{
+			"upper": {Function: upper, MinArgs: 1, MaxArgs: 1, Params: []lang.Type{lang.StringType}, Result: lang.StringType},
+		}),
+	)
+
+	tests := []struct {
+		name       string
+		expression string
+		issues     []Issue
+	}{
+		{"valid", "age > 18 and text.upper(name) == 'JOHN'", nil},
+		{"untyped variable", "payload * 2", nil},
+		{"untyped function", "upper(age) * 2", nil},
+		{"string times number", "'abc' * 2", []Issue{{6, "operator '*' expects numbers, got string and number"}}},
+		{"argument type", "text.upper(tags)", []Issue{{11, "argument 1 of 'upper' must be string, got list"}}},
+		{"argument conversion", "text.upper(age)", nil},
+		{"result type", "text.upper(name) > 1", []Issue{{17, "operator '>' expects numbers, got string and number"}}},
+		{
+			"names and types",
+			"name * 2 + missing",
+			[]Issue{{5, "operator '*' expects numbers, got string and number"}, {11, "unknown variable 'missing'"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.expression, env)
+
+			if tt.issues == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var compileErr *CompileError
+			if !errors.As(err, &compileErr) {
+				t.Fatalf("expected CompileError, got %v", err)
+			}
+			if !reflect.DeepEqual(compileErr.Issues, tt.issues) {
+				t.Errorf("expected issues %v, got %v", tt.issues, compileErr.Issues)
+			}
+		})
+	}
+}
+
+func BenchmarkProgram(b *testing.B) {
+	expr := "user.age > threshold and string.lower(user.name) in ['john', 'jane'] and math.sum(user.age, 1) < 100"
+	program, err := Compile(expr, testEnv())
+	if err != nil {
+		b.Fatalf("compile error: %v", err)
+	}
+	vars := map[string]lang.Value{
+		"user":      lang.MapValue{"age": lang.NumberValue(25), "name": lang.StringValue("John")},
+		"threshold": lang.NumberValue(18),
+	}
+
+	b.Run("tree", func(b *testing.B) {
+		b.ReportAllocs()
+		ctx := &programContext{globals: program.globals, vars: vars}
+		for i := 0; i < b.N; i++ {
+			if _, err := program.AST().Evaluate(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("program", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := program.Evaluate(vars); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package exql
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+type (
+	// Resolver loads variables when an expression first reads them, so only
+	// the data an expression touches is loaded. path is the name of the
+	// variable followed by the fields accessed on it, e.g. ["user", "orders"]
+	// for `user.orders`. A resolver that does not load a path that deep
+	// returns ok false, and the value is then taken from the shorter path.
+	// An error fails the evaluation that read the path.
+	Resolver func(path []string) (value lang.Value, ok bool, err error)
+
+	// resolution memoizes the paths a Resolver has loaded for a variable
+	// during one evaluation.
+	resolution struct {
+		resolver Resolver
+		mu       sync.Mutex
+		results  map[string]resolved
+	}
+	resolved struct {
+		value lang.Value
+		err   error
+	}
+	// lazy is a variable, or a path within one, that has not been loaded.
+	lazy struct {
+		resolution *resolution
+		path       []string
+	}
+)
+
+func (l *lazy) Field(name string) lang.Value {
+	path := make([]string, len(l.path)+1)
+	copy(path, l.path)
+	path[len(l.path)] = name
+	return &lazy{resolution: l.resolution, path: path}
+}
+
+func (l *lazy) Resolve() (lang.Value, error) {
+	return l.resolution.resolve(l.path)
+}
+
+// resolve loads path once. Concurrent first reads of a path may both call
+// the resolver, but all of them see the result stored first.
+func (r *resolution) resolve(path []string) (lang.Value, error) {
+	key := strings.Join(path, "\x00")
+	r.mu.Lock()
+	result, ok := r.results[key]
+	r.mu.Unlock()
+	if ok {
+		return result.value, result.err
+	}
+
+	value, ok, err := r.resolver(path)
+	if err == nil && !ok {
+		value = nil
+		if len(path) > 1 {
+			var parent lang.Value
+			parent, err = r.resolve(path[:len(path)-1])
+			value = lang.Field(parent, path[len(path)-1])
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if result, ok := r.results[key]; ok {
+		return result.value, result.err
+	}
+	if r.results == nil {
+		r.results = make(map[string]resolved)
+	}
+	r.results[key] = resolved{value: value, err: err}
+	return value, err
+}
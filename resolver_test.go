@@ -0,0 +1,169 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package exql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+// testResolver loads users and orders separately and records every call.
+type testResolver struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+var errUnavailable = errors.New("flags are unavailable")
+
+func (r *testResolver) resolve(path []string) (lang.Value, bool, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, strings.Join(path, "."))
+	r.mu.Unlock()
+	switch strings.Join(path, ".") {
+	case "user":
+		{
+			return ValueOf(map[string]any{"name": "john", "active": true}), true, nil
+		}
+	case "user.orders":
+		{
+			return ValueOf([]map[string]any{{"total": 10}, {"total": 32}}), true, nil
+		}
+	case "flags":
+		{
+			return nil, false, errUnavailable
+		}
+	default:
+		{
+			return nil, false, nil
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	base := NewDefaultContext(WithBuiltInLibrary())
+	base.SetVariable("tenant", lang.StringValue("acme"))
+
+	tests := []struct {
+		expr     string
+		expected lang.Value
+		calls    []string
+	}{
+		{"user.name", lang.StringValue("john"), []string{"user.name", "user"}},
+		{"user.orders[1].total", lang.NumberValue(32), []string{"user.orders"}},
+		{"list.length(user.orders) + user.orders[0].total", lang.NumberValue(12), []string{"user.orders"}},
+		{"user.active or flags.beta", lang.BoolValue(true), []string{"user.active", "user"}},
+		{"user.orders.total", lang.ListValue{lang.NumberValue(10), lang.NumberValue(32)}, []string{"user.orders.total", "user.orders"}},
+		{"user.address.city", nil, []string{"user.address.city", "user.address", "user"}},
+		{"tenant + '/' + user.name", lang.StringValue("acme/john"), []string{"user.name", "user"}},
+		{"missing", nil, []string{"missing"}},
+		{"string.upper('a')", lang.StringValue("A"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			resolver := &testResolver{}
+			ctx := NewChildContext(base, WithResolver(resolver.resolve))
+			// Paths are loaded once per evaluation
+			for i := 0; i < 2; i++ {
+				resolver.calls = nil
+				result, err := Eval(tt.expr, ctx)
+				if err != nil {
+					t.Fatalf("evaluation error: %v", err)
+				}
+				if !reflect.DeepEqual(result, tt.expected) {
+					t.Errorf("expected %#v, got %#v", tt.expected, result)
+				}
+				if !reflect.DeepEqual(resolver.calls, tt.calls) {
+					t.Errorf("expected calls %v, got %v", tt.calls, resolver.calls)
+				}
+			}
+		})
+	}
+
+	t.Run("fresh", func(t *testing.T) {
+		loads := 0
+		ctx := NewDefaultContext(WithResolver(func(path []string) (lang.Value, bool, error) {
+			loads++
+			return lang.NumberValue(loads), true, nil
+		}))
+		for i, expected := range []lang.Value{lang.NumberValue(2), lang.NumberValue(4)} {
+			result, err := Eval("version + version", ctx)
+			if err != nil || !valueEqual(result, expected) {
+				t.Errorf("evaluation %d: expected %v, got %v (%v)", i, expected, result, err)
+			}
+		}
+	})
+
+	t.Run("direct", func(t *testing.T) {
+		// Evaluating the tree or the bytecode directly shares loaded paths
+		// the same way
+		expr := "user.name + user.active + list.length(user.orders) + (user?.name ?? '')"
+		ast, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		evaluators := map[string]func(ctx lang.Context) (lang.Value, error){
+			"eval":     func(ctx lang.Context) (lang.Value, error) { return Eval(expr, ctx) },
+			"tree":     ast.Evaluate,
+			"bytecode": lang.Compile(ast).Evaluate,
+		}
+		expected := []string{"user.name", "user", "user.active", "user.orders"}
+		for name, evaluate := range evaluators {
+			resolver := &testResolver{}
+			ctx := NewChildContext(base, WithResolver(resolver.resolve))
+			for i := 0; i < 2; i++ {
+				resolver.calls = nil
+				result, err := evaluate(ctx)
+				if err != nil || !valueEqual(result, lang.StringValue("johntrue2john")) {
+					t.Errorf("%s: expected 'johntrue2john', got %v (%v)", name, result, err)
+				}
+				if !reflect.DeepEqual(resolver.calls, expected) {
+					t.Errorf("%s: expected calls %v, got %v", name, expected, resolver.calls)
+				}
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ctx := NewDefaultContext(WithResolver((&testResolver{}).resolve))
+		_, err := Eval("user.active and flags.beta", ctx)
+		var evalErr *lang.EvalError
+		if !errors.Is(err, errUnavailable) || !errors.As(err, &evalErr) || evalErr.Span.Start != 16 {
+			t.Errorf("expected the resolver error at position 16, got %v", err)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		resolver := &testResolver{}
+		ctx := NewDefaultContext(WithBuiltInLibrary(), WithResolver(resolver.resolve))
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result, err := Eval("list.length(user.orders)", ctx)
+				if err != nil || !valueEqual(result, lang.NumberValue(2)) {
+					t.Errorf("expected 2, got %v (%v)", result, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
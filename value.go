@@ -0,0 +1,719 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package exql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+type (
+	// object exposes a Go struct to expressions. Its fields are converted
+	// with ValueOf when they are accessed.
+	object struct {
+		value reflect.Value
+	}
+	// mapObject exposes a Go map that contains itself, where it repeats.
+	// Its elements are converted with ValueOf when they are accessed.
+	mapObject struct {
+		value reflect.Value
+	}
+	// wrapper is a lang value that holds a Go value without converting it.
+	wrapper interface {
+		lang.MapObject
+		native() reflect.Value
+	}
+	// visit identifies a map, slice or pointer a conversion is inside of.
+	// Slices are also told apart by length, as a shorter slice of the same
+	// array is a different value.
+	visit struct {
+		kind    reflect.Kind
+		pointer uintptr
+		length  int
+	}
+	// visits holds the values a conversion is inside of, to find the ones
+	// that contain themselves.
+	visits map[visit]bool
+	// DecodeError reports a value that cannot be stored in the Go value
+	// passed to Decode. Path locates the value, as in `.items[2].name`.
+	DecodeError struct {
+		Path  string
+		Value lang.Value
+		Type  reflect.Type
+	}
+)
+
+var (
+	valueType = reflect.TypeOf((*lang.Value)(nil)).Elem()
+	timeType  = reflect.TypeOf(time.Time{})
+	// fieldNames caches the fields of each struct type by name.
+	fieldNames sync.Map
+)
+
+// ValueOf converts a Go value to a value expressions can use:
+//
+//   - booleans, numbers and strings become BoolValue, NumberValue and
+//     StringValue, and []byte becomes a StringValue
+//   - time.Time becomes the number of seconds since the Unix epoch, as in
+//     the time library
+//   - slices and arrays become a ListValue and maps a MapValue, with their
+//     elements converted
+//   - structs are not converted: their exported fields are converted when
+//     an expression accesses them, named after their `exql` or `json` tag.
+//     They are lang.MapObjects, so expressions can use them as maps
+//   - pointers and interfaces are followed, and nil becomes null. Structs
+//     behind a pointer are not copied, so expressions see later changes
+//     to them
+//   - a map that contains itself is converted up to where it repeats,
+//     where it is a lang.MapObject converted when accessed, like a struct.
+//     A slice or pointer that contains itself becomes null where it repeats
+//
+// Values that already are lang values are returned as they are, and any
+// other value, such as a channel, becomes null.
+func ValueOf(v any) lang.Value {
+	return convert(v, nil)
+}
+
+// convert converts v, inside of the values in seen.
+func convert(v any, seen visits) lang.Value {
+	switch v := v.(type) {
+	case nil:
+		{
+			return nil
+		}
+	case lang.BoolValue, lang.NumberValue, lang.StringValue, lang.ListValue, lang.MapValue,
+		lang.FunctionValue, lang.RangeValue, lang.Context, lang.Object:
+		{
+			return v
+		}
+	case func(args []lang.Value) (lang.Value, error):
+		{
+			return lang.FunctionValue(v)
+		}
+	case lang.Function:
+		{
+			return lang.FunctionValue(v)
+		}
+	case time.Time:
+		{
+			return lang.NumberValue(float64(v.UnixNano()) / 1e9)
+		}
+	case []byte:
+		{
+			return lang.StringValue(v)
+		}
+	}
+	return valueOf(reflect.ValueOf(v), seen)
+}
+
+func valueOf(v reflect.Value, seen visits) lang.Value {
+	switch v.Kind() {
+	case reflect.Bool:
+		{
+			return lang.BoolValue(v.Bool())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		{
+			return lang.NumberValue(v.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		{
+			return lang.NumberValue(v.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		{
+			return lang.NumberValue(v.Float())
+		}
+	case reflect.String:
+		{
+			return lang.StringValue(v.String())
+		}
+	case reflect.Slice:
+		{
+			if v.IsNil() {
+				return nil
+			}
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return lang.StringValue(v.Bytes())
+			}
+			key := visit{kind: reflect.Slice, pointer: v.Pointer(), length: v.Len()}
+			if seen[key] {
+				return nil
+			}
+			seen = seen.enter(key)
+			defer delete(seen, key)
+			return listOf(v, seen)
+		}
+	case reflect.Array:
+		{
+			return listOf(v, seen)
+		}
+	case reflect.Map:
+		{
+			if v.IsNil() {
+				return nil
+			}
+			key := visit{kind: reflect.Map, pointer: v.Pointer()}
+			if seen[key] {
+				return mapObject{value: v}
+			}
+			seen = seen.enter(key)
+			defer delete(seen, key)
+			return mapOf(v, seen)
+		}
+	case reflect.Struct:
+		{
+			if v.Type() == timeType {
+				return ValueOf(v.Interface())
+			}
+			return object{value: v}
+		}
+	case reflect.Pointer, reflect.Interface:
+		{
+			if v.IsNil() {
+				return nil
+			}
+			// A struct behind a pointer is not copied, so that expressions
+			// see changes made to it after the call
+			if elem := v.Elem(); elem.Kind() == reflect.Struct && elem.Type() != timeType && elem.CanAddr() {
+				return object{value: elem}
+			}
+			if v.Kind() == reflect.Pointer {
+				key := visit{kind: reflect.Pointer, pointer: v.Pointer()}
+				if seen[key] {
+					return nil
+				}
+				seen = seen.enter(key)
+				defer delete(seen, key)
+			}
+			return convert(v.Elem().Interface(), seen)
+		}
+	default:
+		{
+			return nil
+		}
+	}
+}
+
+func listOf(v reflect.Value, seen visits) lang.ListValue {
+	out := make(lang.ListValue, v.Len())
+	for i := range out {
+		out[i] = valueOf(v.Index(i), seen)
+	}
+	return out
+}
+
+func mapOf(v reflect.Value, seen visits) lang.MapValue {
+	out := make(lang.MapValue, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		out[mapKey(iter.Key())] = valueOf(iter.Value(), seen)
+	}
+	return out
+}
+
+// enter adds key to seen, allocating it on the first map, slice or pointer
+// a conversion enters.
+func (seen visits) enter(key visit) visits {
+	if seen == nil {
+		seen = make(visits)
+	}
+	seen[key] = true
+	return seen
+}
+
+// printable returns value with the maps that repeat in it replaced by
+// map[...], so that it can be formatted without repeating them forever.
+func printable(value lang.Value) any {
+	switch value := value.(type) {
+	case lang.ListValue:
+		{
+			out := make([]any, len(value))
+			for i, item := range value {
+				out[i] = printable(item)
+			}
+			return out
+		}
+	case lang.MapValue:
+		{
+			out := make(map[string]any, len(value))
+			for key, item := range value {
+				out[key] = printable(item)
+			}
+			return out
+		}
+	case mapObject:
+		{
+			return "map[...]"
+		}
+	default:
+		{
+			return value
+		}
+	}
+}
+
+func mapKey(key reflect.Value) string {
+	switch key.Kind() {
+	case reflect.String:
+		{
+			return key.String()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		{
+			return strconv.FormatInt(key.Int(), 10)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		{
+			return strconv.FormatUint(key.Uint(), 10)
+		}
+	default:
+		{
+			return fmt.Sprint(key.Interface())
+		}
+	}
+}
+
+// fields returns the index of each exported field of a struct type by the
+// name expressions use for it. Fields of embedded structs are promoted
+// unless the embedded struct is tagged, and shallower fields win.
+func fields(t reflect.Type) map[string][]int {
+	if cached, ok := fieldNames.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+	out := make(map[string][]int)
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+		name, tagged := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && !tagged && indirect(field.Type).Kind() == reflect.Struct {
+			continue
+		}
+		if existing, ok := out[name]; ok && len(existing) <= len(field.Index) {
+			continue
+		}
+		out[name] = field.Index
+	}
+	fieldNames.Store(t, out)
+	return out
+}
+
+// fieldName returns the name of a field from its `exql` tag, or else its
+// `json` tag, or else its Go name, and whether a tag named it.
+func fieldName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"exql", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			return name, true
+		}
+	}
+	return field.Name, false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Field returns the named field, or null when the struct has no such field
+// or it is promoted through a nil embedded pointer.
+func (o object) Field(name string) lang.Value {
+	index, ok := fields(o.value.Type())[name]
+	if !ok {
+		return nil
+	}
+	field, err := o.value.FieldByIndexErr(index)
+	if err != nil {
+		return nil
+	}
+	return valueOf(field, nil)
+}
+
+// Map converts the struct to a MapValue.
+func (o object) Map() lang.MapValue {
+	names := fields(o.value.Type())
+	out := make(lang.MapValue, len(names))
+	for name := range names {
+		out[name] = o.Field(name)
+	}
+	return out
+}
+
+func (o object) String() string {
+	return fmt.Sprint(o.Map())
+}
+
+func (o object) native() reflect.Value {
+	return o.value
+}
+
+// Field returns the element of the map under name, or null when it has
+// none.
+func (o mapObject) Field(name string) lang.Value {
+	iter := o.value.MapRange()
+	for iter.Next() {
+		if mapKey(iter.Key()) == name {
+			return valueOf(iter.Value(), nil)
+		}
+	}
+	return nil
+}
+
+// Map converts the map to a MapValue, up to where it repeats.
+func (o mapObject) Map() lang.MapValue {
+	return mapOf(o.value, visits{{kind: reflect.Map, pointer: o.value.Pointer()}: true})
+}
+
+// String formats the map as fmt does, with the maps it contains itself
+// through as map[...].
+func (o mapObject) String() string {
+	return fmt.Sprint(printable(o.Map()))
+}
+
+// MarshalJSON fails, as JSON cannot hold a map that contains itself.
+func (o mapObject) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("%s contains itself", o.value.Type())
+}
+
+func (o mapObject) native() reflect.Value {
+	return o.value
+}
+
+// Decode stores value in the Go value target points to, converting lang
+// values the opposite way to ValueOf. Numbers only decode into integers
+// when they are whole and in range, maps decode into structs by the same
+// field names ValueOf uses and any value decodes into an empty interface
+// as bool, float64, string, []any or map[string]any. Null leaves target at
+// its zero value.
+func Decode(value lang.Value, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("decode target must be a non-nil pointer, got %T", target)
+	}
+	return decode(value, v.Elem(), "")
+}
+
+func (e *DecodeError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "."
+	}
+	return fmt.Sprintf("cannot decode %s into %s at %s", typeName(e.Value), e.Type, path)
+}
+
+func typeName(value lang.Value) string {
+	switch value.(type) {
+	case nil:
+		{
+			return "null"
+		}
+	case lang.BoolValue:
+		{
+			return "bool"
+		}
+	case lang.NumberValue:
+		{
+			return "number"
+		}
+	case lang.StringValue:
+		{
+			return "string"
+		}
+	case lang.ListValue:
+		{
+			return "list"
+		}
+	case lang.MapValue, lang.Object:
+		{
+			return "map"
+		}
+	default:
+		{
+			return fmt.Sprintf("%T", value)
+		}
+	}
+}
+
+func decode(value lang.Value, v reflect.Value, path string) error {
+	if value == nil {
+		v.SetZero()
+		return nil
+	}
+	if obj, ok := value.(wrapper); ok {
+		// Empty interfaces get the map, as for any other map value
+		if (v.Kind() != reflect.Interface || v.NumMethod() > 0) && obj.native().Type().AssignableTo(v.Type()) {
+			v.Set(obj.native())
+			return nil
+		}
+		value = obj.Map()
+	}
+	if v.Type() == timeType {
+		return decodeTime(value, v, path)
+	}
+	fail := &DecodeError{Path: path, Value: value, Type: v.Type()}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		{
+			if v.NumMethod() > 0 {
+				if reflect.TypeOf(value).AssignableTo(v.Type()) {
+					v.Set(reflect.ValueOf(value))
+					return nil
+				}
+				return fail
+			}
+			plain := native(value)
+			if v.Type() == valueType {
+				plain = value
+			}
+			v.Set(reflect.ValueOf(&plain).Elem())
+			return nil
+		}
+	case reflect.Pointer:
+		{
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return decode(value, v.Elem(), path)
+		}
+	case reflect.Bool:
+		{
+			b, ok := value.(lang.BoolValue)
+			if !ok {
+				return fail
+			}
+			v.SetBool(bool(b))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		{
+			n, ok := value.(lang.NumberValue)
+			if !ok || !whole(n) || n < -(1<<63) || n >= 1<<63 || v.OverflowInt(int64(n)) {
+				return fail
+			}
+			v.SetInt(int64(n))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		{
+			n, ok := value.(lang.NumberValue)
+			if !ok || !whole(n) || n < 0 || n >= 1<<64 || v.OverflowUint(uint64(n)) {
+				return fail
+			}
+			v.SetUint(uint64(n))
+		}
+	case reflect.Float32, reflect.Float64:
+		{
+			n, ok := value.(lang.NumberValue)
+			if !ok {
+				return fail
+			}
+			v.SetFloat(float64(n))
+		}
+	case reflect.String:
+		{
+			s, ok := value.(lang.StringValue)
+			if !ok {
+				return fail
+			}
+			v.SetString(string(s))
+		}
+	case reflect.Slice:
+		{
+			if s, ok := value.(lang.StringValue); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+				v.SetBytes([]byte(s))
+				return nil
+			}
+			list, ok := value.(lang.ListValue)
+			if !ok {
+				return fail
+			}
+			out := reflect.MakeSlice(v.Type(), len(list), len(list))
+			for i, item := range list {
+				if err := decode(item, out.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			v.Set(out)
+		}
+	case reflect.Array:
+		{
+			list, ok := value.(lang.ListValue)
+			if !ok || len(list) != v.Len() {
+				return fail
+			}
+			for i, item := range list {
+				if err := decode(item, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		{
+			m, ok := value.(lang.MapValue)
+			if !ok || v.Type().Key().Kind() != reflect.String {
+				return fail
+			}
+			out := reflect.MakeMapWithSize(v.Type(), len(m))
+			for key, item := range m {
+				elem := reflect.New(v.Type().Elem()).Elem()
+				if err := decode(item, elem, path+"."+key); err != nil {
+					return err
+				}
+				out.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+			}
+			v.Set(out)
+		}
+	case reflect.Struct:
+		{
+			m, ok := value.(lang.MapValue)
+			if !ok {
+				return fail
+			}
+			for name, index := range fields(v.Type()) {
+				item, ok := m[name]
+				if !ok {
+					continue
+				}
+				field, err := v.FieldByIndexErr(index)
+				if err != nil {
+					// A nil embedded pointer on the way to the field
+					if field, ok = allocate(v, index); !ok {
+						return &DecodeError{Path: path + "." + name, Value: item, Type: v.Type()}
+					}
+				}
+				if err := decode(item, field, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		{
+			return fail
+		}
+	}
+	return nil
+}
+
+// whole reports whether n is a finite number without a fraction.
+func whole(n lang.NumberValue) bool {
+	return !math.IsInf(float64(n), 0) && n == lang.NumberValue(math.Trunc(float64(n)))
+}
+
+// allocate allocates the nil embedded pointers on the way to the field of v
+// at index and returns the field. It fails on pointers to unexported
+// structs, which cannot be set.
+func allocate(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func decodeTime(value lang.Value, v reflect.Value, path string) error {
+	switch value := value.(type) {
+	case lang.NumberValue:
+		{
+			seconds, fraction := math.Modf(float64(value))
+			v.Set(reflect.ValueOf(time.Unix(int64(seconds), int64(fraction*1e9))))
+			return nil
+		}
+	case lang.StringValue:
+		{
+			t, err := time.Parse(time.RFC3339Nano, string(value))
+			if err != nil {
+				return &DecodeError{Path: path, Value: value, Type: v.Type()}
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	default:
+		{
+			return &DecodeError{Path: path, Value: value, Type: v.Type()}
+		}
+	}
+}
+
+// native converts a lang value to the plain Go value encoding/json would
+// decode the same JSON into.
+func native(value lang.Value) any {
+	switch value := value.(type) {
+	case lang.BoolValue:
+		{
+			return bool(value)
+		}
+	case lang.NumberValue:
+		{
+			return float64(value)
+		}
+	case lang.StringValue:
+		{
+			return string(value)
+		}
+	case lang.ListValue:
+		{
+			out := make([]any, len(value))
+			for i, item := range value {
+				out[i] = native(item)
+			}
+			return out
+		}
+	case lang.MapValue:
+		{
+			out := make(map[string]any, len(value))
+			for key, item := range value {
+				out[key] = native(item)
+			}
+			return out
+		}
+	case mapObject:
+		{
+			// The map contains itself, so it is its own plain value
+			return value.value.Interface()
+		}
+	case wrapper:
+		{
+			return native(value.Map())
+		}
+	default:
+		{
+			return value
+		}
+	}
+}
@@ -0,0 +1,370 @@
+/*
+ * Copyright 2025 Pouya Vedadiyan
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package exql
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vedadiyan/exql/lang"
+)
+
+type (
+	Audit struct {
+		CreatedBy string `json:"created_by"`
+	}
+	testAddress struct {
+		City    string `json:"city"`
+		Country string `json:"country,omitempty"`
+	}
+	testOrder struct {
+		ID    int     `json:"id"`
+		Total float64 `json:"total"`
+	}
+	testUser struct {
+		*Audit
+		Name     string            `exql:"name" json:"full_name"`
+		Age      int               `json:"age"`
+		Email    string            // no tag
+		Password string            `json:"-"`
+		Address  *testAddress      `json:"address"`
+		Orders   []testOrder       `json:"orders"`
+		Labels   map[string]string `json:"labels"`
+		Scores   [2]uint8          `json:"scores"`
+		Joined   time.Time         `json:"joined"`
+		Extra    any               `json:"extra"`
+		secret   string
+	}
+)
+
+func testUserValue() *testUser {
+	return &testUser{
+		Audit:    &Audit{CreatedBy: "admin"},
+		Name:     "John",
+		Age:      30,
+		Email:    "john@example.com",
+		Password: "hunter2",
+		Address:  &testAddress{City: "Berlin"},
+		Orders:   []testOrder{{ID: 1, Total: 9.5}, {ID: 2, Total: 20}},
+		Labels:   map[string]string{"tier": "gold"},
+		Scores:   [2]uint8{7, 9},
+		Joined:   time.Unix(1700000000, 0),
+		Extra:    []int{1},
+		secret:   "s3cret",
+	}
+}
+
+func TestValueOf(t *testing.T) {
+	ch := make(chan int)
+	var nilUser *testUser
+	tests := []struct {
+		name     string
+		value    any
+		expected lang.Value
+	}{
+		{"nil", nil, nil},
+		{"bool", true, lang.BoolValue(true)},
+		{"int", 42, lang.NumberValue(42)},
+		{"uint8", uint8(7), lang.NumberValue(7)},
+		{"float32", float32(1.5), lang.NumberValue(1.5)},
+		{"string", "hi", lang.StringValue("hi")},
+		{"named string", time.Month(3), lang.NumberValue(3)},
+		{"bytes", []byte("raw"), lang.StringValue("raw")},
+		{"time", time.Unix(1700000000, 500000000), lang.NumberValue(1700000000.5)},
+		{"slice", []any{1, "a", nil}, lang.ListValue{lang.NumberValue(1), lang.StringValue("a"), nil}},
+		{"nil slice", []int(nil), nil},
+		{"array", [2]bool{true, false}, lang.ListValue{lang.BoolValue(true), lang.BoolValue(false)}},
+		{"map", map[string]int{"a": 1}, lang.MapValue{"a": lang.NumberValue(1)}},
+		{"int keys", map[int]string{1: "one"}, lang.MapValue{"1": lang.StringValue("one")}},
+		{"pointer", func() *int { i := 5; return &i }(), lang.NumberValue(5)},
+		{"nil pointer", nilUser, nil},
+		{"lang value", lang.ListValue{lang.NumberValue(1)}, lang.ListValue{lang.NumberValue(1)}},
+		{"channel", ch, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ValueOf(tt.value); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestValueOfStruct(t *testing.T) {
+	user := testUserValue()
+	ctx := NewDefaultContext(WithBuiltInLibrary(), WithValues(map[string]any{"user": user}))
+
+	tests := []struct {
+		expr     string
+		expected lang.Value
+	}{
+		{"user.name", lang.StringValue("John")},
+		{"user.full_name", nil},
+		{"user.Name", nil},
+		{"user.age + 1", lang.NumberValue(31)},
+		{"user.Email", lang.StringValue("john@example.com")},
+		{"user['Email']", lang.StringValue("john@example.com")},
+		{"user.Password", nil},
+		{"user.secret", nil},
+		{"user.created_by", lang.StringValue("admin")},
+		{"user.address.city", lang.StringValue("Berlin")},
+		{"user.address?.country", lang.StringValue("")},
+		{"user.orders[1].total", lang.NumberValue(20)},
+		{"user.orders.id", lang.ListValue{lang.NumberValue(1), lang.NumberValue(2)}},
+		{"user.labels.tier", lang.StringValue("gold")},
+		{"user.scores[1]", lang.NumberValue(9)},
+		{"user.joined", lang.NumberValue(1700000000)},
+		{"user.extra[0]", lang.NumberValue(1)},
+		{"list.length(user.orders)", lang.NumberValue(2)},
+		{"{...user.address}", lang.MapValue{"city": lang.StringValue("Berlin"), "country": lang.StringValue("")}},
+		{"{...user, age: 31}.age", lang.NumberValue(31)},
+		{"'city' in map.keys(user.address)", lang.BoolValue(true)},
+		{"map.get(user, 'name')", lang.StringValue("John")},
+		{"map.merge(user.address, {country: 'DE'})", lang.MapValue{"city": lang.StringValue("Berlin"), "country": lang.StringValue("DE")}},
+		{"json.string(user.address)", lang.StringValue(`{"city":"Berlin","country":""}`)},
+		{"json.string(user.orders[0])", lang.StringValue(`{"id":1,"total":9.5}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := Eval(tt.expr, ctx)
+			if err != nil {
+				t.Fatalf("evaluation error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, result)
+			}
+		})
+	}
+
+	// Fields are read when they are accessed
+	user.Address.City = "Paris"
+	user.Audit = nil
+	for expr, expected := range map[string]lang.Value{"user.address.city": lang.StringValue("Paris"), "user.created_by": nil} {
+		result, err := Eval(expr, ctx)
+		if err != nil {
+			t.Fatalf("evaluation error: %v", err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %s to be %#v, got %#v", expr, expected, result)
+		}
+	}
+}
+
+func TestValueOfCycle(t *testing.T) {
+	m := map[string]any{"name": "root"}
+	m["self"] = m
+	list := []any{1, nil}
+	list[1] = list
+	shared := map[string]any{"id": 7}
+	ctx := NewDefaultContext(WithBuiltInLibrary(), WithValues(map[string]any{
+		"m":      m,
+		"list":   list,
+		"shared": []any{shared, shared},
+	}))
+
+	tests := []struct {
+		expr     string
+		expected lang.Value
+	}{
+		{"m.name", lang.StringValue("root")},
+		{"m.self.name", lang.StringValue("root")},
+		{"m.self.self.self.name", lang.StringValue("root")},
+		{"m['self']['name']", lang.StringValue("root")},
+		{"map.keys(m.self)", lang.ListValue{lang.StringValue("name"), lang.StringValue("self")}},
+		{"'' + m", lang.StringValue("map[name:root self:map[name:root self:map[...]]]")},
+		{"m.self == m.self", lang.BoolValue(true)},
+		{"list[0]", lang.NumberValue(1)},
+		{"list[1]", nil},
+		{"shared[1].id", lang.NumberValue(7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			result, err := Eval(tt.expr, ctx)
+			if err != nil {
+				t.Fatalf("evaluation error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, result)
+			}
+		})
+	}
+
+	if _, err := Eval("json.string(m)", ctx); err == nil || !strings.Contains(err.Error(), "contains itself") {
+		t.Errorf("expected json.string to fail on a map that contains itself, got %v", err)
+	}
+	var decoded map[string]any
+	if err := Decode(ValueOf(m).(lang.MapValue)["self"], &decoded); err != nil || decoded["name"] != "root" {
+		t.Errorf("expected the map to decode, got %v, %v", decoded, err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("struct", func(t *testing.T) {
+		value := lang.MapValue{
+			"name":       lang.StringValue("Jane"),
+			"age":        lang.NumberValue(28),
+			"created_by": lang.StringValue("root"),
+			"address":    lang.MapValue{"city": lang.StringValue("Rome")},
+			"orders":     lang.ListValue{lang.MapValue{"id": lang.NumberValue(3), "total": lang.NumberValue(1.25)}},
+			"labels":     lang.MapValue{"tier": lang.StringValue("silver")},
+			"scores":     lang.ListValue{lang.NumberValue(1), lang.NumberValue(2)},
+			"joined":     lang.StringValue("2024-01-02T03:04:05Z"),
+			"extra":      lang.MapValue{"a": lang.ListValue{lang.BoolValue(true), nil}},
+			"Password":   lang.StringValue("ignored"),
+		}
+		var user testUser
+		if err := Decode(value, &user); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		expected := testUser{
+			Audit:   &Audit{CreatedBy: "root"},
+			Name:    "Jane",
+			Age:     28,
+			Address: &testAddress{City: "Rome"},
+			Orders:  []testOrder{{ID: 3, Total: 1.25}},
+			Labels:  map[string]string{"tier": "silver"},
+			Scores:  [2]uint8{1, 2},
+			Joined:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Extra:   map[string]any{"a": []any{true, nil}},
+		}
+		if !reflect.DeepEqual(user, expected) {
+			t.Errorf("expected %+v, got %+v", expected, user)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		user := testUserValue()
+		user.Password, user.secret = "", ""
+		result, err := Eval("user", NewDefaultContext(WithValues(map[string]any{"user": user})))
+		if err != nil {
+			t.Fatalf("evaluation error: %v", err)
+		}
+		var decoded *testUser
+		if err := Decode(result, &decoded); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		// Times come back in the local time zone and numbers in interfaces
+		// as float64
+		if !decoded.Joined.Equal(user.Joined) || !reflect.DeepEqual(decoded.Extra, []any{float64(1)}) {
+			t.Errorf("unexpected joined %v or extra %v", decoded.Joined, decoded.Extra)
+		}
+		decoded.Joined, decoded.Extra = user.Joined, user.Extra
+		if !reflect.DeepEqual(decoded, user) {
+			t.Errorf("expected %+v, got %+v", user, decoded)
+		}
+
+		var generic map[string]any
+		if err := Decode(result, &generic); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if generic["name"] != "John" || generic["age"] != float64(30) {
+			t.Errorf("unexpected map %v", generic)
+		}
+
+		var untyped any
+		if err := Decode(result, &untyped); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if generic, ok := untyped.(map[string]any); !ok || generic["name"] != "John" {
+			t.Errorf("expected a map, got %#v", untyped)
+		}
+	})
+
+	t.Run("values", func(t *testing.T) {
+		var (
+			n     int
+			f     float32
+			s     string
+			b     []byte
+			p     *int
+			v     lang.Value
+			i     any
+			stamp time.Time
+		)
+		targets := []struct {
+			value    lang.Value
+			target   any
+			expected any
+		}{
+			{lang.NumberValue(3), &n, 3},
+			{nil, &n, 0},
+			{lang.NumberValue(0.5), &f, float32(0.5)},
+			{lang.StringValue("x"), &s, "x"},
+			{lang.StringValue("raw"), &b, []byte("raw")},
+			{lang.NumberValue(7), &p, func() *int { i := 7; return &i }()},
+			{lang.ListValue{lang.NumberValue(1)}, &v, lang.ListValue{lang.NumberValue(1)}},
+			{lang.ListValue{lang.NumberValue(1)}, &i, []any{float64(1)}},
+			{lang.NumberValue(1700000000.25), &stamp, time.Unix(1700000000, 250000000)},
+		}
+		for _, tt := range targets {
+			if err := Decode(tt.value, tt.target); err != nil {
+				t.Fatalf("decode error: %v", err)
+			}
+			if actual := reflect.ValueOf(tt.target).Elem().Interface(); !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, actual)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		var (
+			n     int8
+			i     int
+			u     uint
+			user  testUser
+			flags []bool
+		)
+		tests := []struct {
+			value    lang.Value
+			target   any
+			expected string
+		}{
+			{lang.StringValue("1"), &n, "cannot decode string into int8 at ."},
+			{lang.NumberValue(1.5), &n, "cannot decode number into int8 at ."},
+			{lang.NumberValue(300), &n, "cannot decode number into int8 at ."},
+			{lang.NumberValue(-1), &u, "cannot decode number into uint at ."},
+			{lang.NumberValue(math.Inf(1)), &u, "cannot decode number into uint at ."},
+			{lang.NumberValue(1 << 63), &i, "cannot decode number into int at ."},
+			{lang.NumberValue(math.NaN()), &i, "cannot decode number into int at ."},
+			{lang.MapValue{"orders": lang.ListValue{lang.MapValue{"id": lang.StringValue("x")}}}, &user, "cannot decode string into int at .orders[0].id"},
+			{lang.ListValue{lang.BoolValue(true), lang.NumberValue(1)}, &flags, "cannot decode number into bool at [1]"},
+		}
+		for _, tt := range tests {
+			err := Decode(tt.value, tt.target)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("expected a DecodeError, got %v", err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, err.Error())
+			}
+		}
+
+		var hidden struct{ *testAddress }
+		if err := Decode(lang.MapValue{"city": lang.StringValue("Rome")}, &hidden); err == nil || err.Error() != "cannot decode string into struct { *exql.testAddress } at .city" {
+			t.Errorf("expected an error for an unexported embedded pointer, got %v", err)
+		}
+
+		if err := Decode(lang.NumberValue(1), n); err == nil {
+			t.Error("expected an error for a non-pointer target")
+		}
+	})
+}